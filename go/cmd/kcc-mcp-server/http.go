@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// httpShutdownTimeout is how long in-flight requests get to finish after a
+// shutdown signal before their connections are closed
+const httpShutdownTimeout = 10 * time.Second
+
+// serveHTTP serves the MCP server over the streamable HTTP transport at /mcp
+// until ctx is cancelled, then shuts down gracefully. Every client gets its
+// own session on the same server, so they share the warm resource catalog.
+// Without a bearer token the server only listens on loopback.
+func serveHTTP(ctx context.Context, server *mcp.Server, addr, token string, allowedHosts []string) error {
+	if token == "" && !isLoopback(addr) {
+		return fmt.Errorf(`refusing to serve on %s without authentication
+
+Either set KCC_HTTP_BEARER_TOKEN (or "http": {"bearer_token": ...} in the config file),
+or listen on a loopback address such as localhost:8080`, addr)
+	}
+
+	var handler http.Handler = mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return server
+	}, nil)
+	if token != "" {
+		handler = auth.RequireBearerToken(bearerTokenVerifier(token), nil)(handler)
+	}
+	handler = checkHost(handler, addr, allowedHosts)
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", handler)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	httpServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "🚀 KCC MCP Server listening on http://%s/mcp\n", listener.Addr())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(os.Stderr, "🛑 Shutting down\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	httpServer.RegisterOnShutdown(func() {
+		// Ends the sessions' open event streams, which never go idle
+		for session := range server.Sessions() {
+			session.Close()
+		}
+	})
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		httpServer.Close()
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// checkHost rejects requests whose Host or Origin header names a host other
+// than a loopback name, the listen host or one of allowedHosts. A web page the
+// developer visits could otherwise reach the server through DNS rebinding.
+// Requests without an Origin, i.e. not from a browser, only need a valid Host.
+func checkHost(next http.Handler, addr string, allowedHosts []string) http.Handler {
+	allowed := map[string]bool{"localhost": true}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		allowed[strings.ToLower(host)] = true
+	}
+	for _, host := range allowedHosts {
+		allowed[strings.ToLower(host)] = true
+	}
+	permitted := func(host string) bool {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return true
+		}
+		return allowed[host]
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !permitted(strings.Trim(host, "[]")) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !permitted(u.Hostname()) {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerTokenVerifier accepts only the configured token
+func bearerTokenVerifier(token string) auth.TokenVerifier {
+	return func(ctx context.Context, presented string, req *http.Request) (*auth.TokenInfo, error) {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return nil, auth.ErrInvalidToken
+		}
+		// A static token does not expire, but the middleware requires an expiry
+		return &auth.TokenInfo{Expiration: time.Now().Add(time.Hour)}, nil
+	}
+}
+
+// isLoopback reports whether addr only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// okHandler stands in for the MCP handler behind the middleware
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		addr         string
+		allowedHosts []string
+		host         string
+		origin       string
+		want         int
+	}{
+		{name: "localhost", addr: "localhost:8080", host: "localhost:8080", want: http.StatusOK},
+		{name: "loopback IP", addr: "localhost:8080", host: "127.0.0.1:8080", want: http.StatusOK},
+		{name: "IPv6 loopback", addr: "[::1]:8080", host: "[::1]:8080", want: http.StatusOK},
+		{name: "trailing dot", addr: "localhost:8080", host: "LOCALHOST.:8080", want: http.StatusOK},
+		{name: "DNS rebinding", addr: "127.0.0.1:8080", host: "attacker.example:8080", want: http.StatusForbidden},
+		{name: "listen host", addr: "kcc.internal:8080", host: "kcc.internal:8080", want: http.StatusOK},
+		{name: "allowed host", addr: ":8080", allowedHosts: []string{"Dev-Box"}, host: "dev-box:8080", want: http.StatusOK},
+		{name: "other host", addr: ":8080", allowedHosts: []string{"dev-box"}, host: "other-box:8080", want: http.StatusForbidden},
+		{name: "local origin", addr: "localhost:8080", host: "localhost:8080", origin: "http://localhost:3000", want: http.StatusOK},
+		{name: "foreign origin", addr: "localhost:8080", host: "localhost:8080", origin: "https://attacker.example", want: http.StatusForbidden},
+		{name: "null origin", addr: "localhost:8080", host: "localhost:8080", origin: "null", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			checkHost(okHandler, tt.addr, tt.allowedHosts).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerTokenVerifier(t *testing.T) {
+	handler := auth.RequireBearerToken(bearerTokenVerifier("s3cret"), nil)(okHandler)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "missing token", want: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "token prefix", authorization: "Bearer s3cre", want: http.StatusUnauthorized},
+		{name: "correct token", authorization: "Bearer s3cret", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRequiresTokenOffLoopback(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	for _, addr := range []string{":8080", "0.0.0.0:8080", "192.0.2.1:8080"} {
+		t.Run(addr, func(t *testing.T) {
+			err := serveHTTP(context.Background(), server, addr, "", nil)
+			if err == nil || !strings.Contains(err.Error(), "refusing to serve") {
+				t.Errorf("serveHTTP(%q) without a token = %v, want a refusal", addr, err)
+			}
+		})
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"localhost:8080", true},
+		{"127.0.0.1:8080", true},
+		{"127.1.2.3:8080", true},
+		{"[::1]:8080", true},
+		{":8080", false},
+		{"0.0.0.0:8080", false},
+		{"[::]:8080", false},
+		{"example.com:8080", false},
+		{"localhost", false}, // no port
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.addr); got != tt.want {
+			t.Errorf("isLoopback(%q) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
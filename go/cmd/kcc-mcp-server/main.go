@@ -3,44 +3,103 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 
 	"github.com/fkc1e100/kcc-mcp-server/go/internal/config"
 	"github.com/fkc1e100/kcc-mcp-server/go/internal/gitvalidator"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/journal"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/oplog"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
 	"github.com/fkc1e100/kcc-mcp-server/go/internal/tools"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func main() {
+	readOnly := flag.Bool("read-only", false, "register only tools that do not modify the repository")
+	allowTools := flag.String("allow-tools", "", "comma-separated tools to register; all others are left out")
+	denyTools := flag.String("deny-tools", "", "comma-separated tools not to register")
+	transport := flag.String("transport", "stdio", "transport to serve MCP on: stdio or http")
+	listen := flag.String("listen", "", "address of the http transport, e.g. :8080 (default localhost:8080)")
+	flag.Parse()
+	if *transport != "stdio" && *transport != "http" {
+		log.Fatalf("❌ Unknown transport %q: expected stdio or http\n", *transport)
+	}
+
 	// Initialize config
 	cfg, err := config.NewConfigManager()
 	if err != nil {
 		log.Fatalf("❌ Failed to initialize KCC MCP Server:\n%v\n", err)
 	}
+	cfg.OverrideTools(*readOnly, config.SplitList(*allowTools), config.SplitList(*denyTools))
 
 	gitValidator := gitvalidator.NewGitValidator(cfg)
+	migrationJournal := journal.NewJournal(cfg.GetStateDir())
+	operations := oplog.New(cfg.GetStateDir(), tools.ResolveWritePath)
+
+	// SIGINT and SIGTERM stop the server gracefully
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Build the resource catalog shared by all tools
+	catalog := tools.NewCatalog(cfg.GetRepoPath())
+	stats, err := catalog.Refresh()
+	if err != nil {
+		log.Fatalf("❌ Failed to index KCC repository:\n%v\n", err)
+	}
+	go catalog.Watch(ctx, cfg.GetCatalogRefreshInterval())
 
 	authorName, authorEmail := cfg.GetGitAuthor()
 	fmt.Fprintf(os.Stderr, "✅ KCC MCP Server initialized\n")
 	fmt.Fprintf(os.Stderr, "📁 Repository: %s\n", cfg.GetRepoPath())
 	fmt.Fprintf(os.Stderr, "👤 Author: %s <%s>\n", authorName, authorEmail)
+	fmt.Fprintf(os.Stderr, "📓 Journal: %s\n", cfg.GetStateDir())
+	fmt.Fprintf(os.Stderr, "📚 Catalog: %d resources indexed in %s\n", stats.Kinds, stats.Duration)
 
 	// Create MCP server
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "kcc-contributor-server",
 		Version: "1.0.0",
 	}, nil)
+	registry := newToolRegistry(server, cfg)
+	registerTools(registry, cfg, catalog, gitValidator, migrationJournal, operations)
+	registry.report()
+
+	// Start server
+	if *transport == "http" {
+		addr := *listen
+		if addr == "" {
+			addr = cfg.GetHTTPListen()
+		}
+		err = serveHTTP(ctx, server, addr, cfg.GetHTTPBearerToken(), cfg.GetHTTPAllowedHosts())
+	} else {
+		fmt.Fprintf(os.Stderr, "🚀 KCC MCP Server running\n")
+		err = server.Run(ctx, &mcp.StdioTransport{})
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Fatalf("Fatal error: %v\n", err)
+	}
+}
 
+// registerTools registers every tool of the server with the registry, which
+// leaves out the ones the configuration excludes
+func registerTools(registry *toolRegistry, cfg *config.ConfigManager, catalog *tools.Catalog, gitValidator *gitvalidator.GitValidator, migrationJournal *journal.Journal, operations *oplog.Log) {
 	// Register kcc_find_resource tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_find_resource",
 		Description: "Locate files for a KCC resource (types, controller, mapper, test fixtures)",
+		Annotations: readOnlyTool(),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
 		Resource string `json:"resource"`
 	}) (*mcp.CallToolResult, any, error) {
-		location, err := tools.FindResource(cfg.GetRepoPath(), input.Resource)
+		location, err := tools.FindResource(catalog, input.Resource)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -54,13 +113,14 @@ func main() {
 	})
 
 	// Register kcc_detect_controller_type tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_detect_controller_type",
-		Description: "Detect if a resource uses direct controller or Terraform-based controller",
+		Description: "Detect whether a resource uses a direct, Terraform or DCL controller, and which is the default for hybrid resources",
+		Annotations: readOnlyTool(),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
 		Resource string `json:"resource"`
 	}) (*mcp.CallToolResult, any, error) {
-		info, err := tools.DetectControllerType(cfg.GetRepoPath(), input.Resource)
+		info, err := tools.DetectControllerType(catalog, input.Resource)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -73,31 +133,96 @@ func main() {
 		}, info, nil
 	})
 
+	// Register kcc_list_resources tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_list_resources",
+		Description: "List every KCC resource with its controller type, service, versions and migration phase, filterable by service, type and phase, 50 per page unless a limit is given",
+		Annotations: readOnlyTool(),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ListResourcesParams) (*mcp.CallToolResult, any, error) {
+		list, err := tools.ListResources(catalog, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		jsonData, _ := json.MarshalIndent(list, "", "  ")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: tools.FormatResourceTable(list)},
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, list, nil
+	})
+
+	// Register kcc_field_parity tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_field_parity",
+		Description: "Compare the Terraform/DCL schema of a resource (generated types or CRD) field by field with its proto message: matched, renamed, missing in proto and new in proto, with types",
+		Annotations: readOnlyTool(),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.FieldParityParams) (*mcp.CallToolResult, any, error) {
+		report, err := tools.FieldParity(catalog, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		jsonData, _ := json.MarshalIndent(report, "", "  ")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: tools.FormatFieldParity(report)},
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, report, nil
+	})
+
+	// Register kcc_describe_proto tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_describe_proto",
+		Description: "Describe a proto message from mockgcp/third_party/googleapis: fields with numbers, types, repeated/map/oneof, comments and field_behavior, its google.api.resource pattern, and the RPCs of the owning service",
+		Annotations: readOnlyTool(),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.DescribeProtoParams) (*mcp.CallToolResult, any, error) {
+		info, err := tools.DescribeProto(catalog, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		jsonData, _ := json.MarshalIndent(info, "", "  ")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: tools.FormatProtoMessage(info)},
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, info, nil
+	})
+
 	// Register kcc_generate_mapper tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_generate_mapper",
-		Description: "Regenerate KRM ↔ Proto mapper after adding fields",
+		Description: "Regenerate KRM ↔ Proto mapper after adding fields. Failures are returned as structured diagnostics (file, line, field, proto path and class: unknown_proto_field, unknown_proto_message, type_mismatch, missing_manual_mapping, undefined, compile_error, generator_error); proto fields left unmapped are reported as unmapped_field warnings",
+		Annotations: writeTool(true, true),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
 		Resource string `json:"resource"`
 	}) (*mcp.CallToolResult, any, error) {
-		result, err := tools.GenerateMapper(cfg.GetRepoPath(), input.Resource)
+		result, err := tools.GenerateMapper(ctx, catalog, input.Resource, newChangeSet(cfg, operations, req, false), mapperOptions(ctx, req, cfg))
 		if err != nil {
 			return nil, nil, err
 		}
 
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
 		return &mcp.CallToolResult{
+			IsError: !result.Success,
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
+				&mcp.TextContent{Text: tools.FormatMapperResult(result)},
+				&mcp.TextContent{Text: string(jsonData)},
 			},
-		}, map[string]string{"result": result}, nil
+		}, result, nil
 	})
 
 	// Register kcc_git_status tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_git_status",
 		Description: "Get current git status",
+		Annotations: readOnlyTool(),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
-		status, err := gitValidator.GetStatus(cfg.GetRepoPath())
+		status, err := gitValidator.GetStatus(ctx, cfg.GetRepoPath())
 		if err != nil {
 			return nil, nil, err
 		}
@@ -114,18 +239,34 @@ func main() {
 	})
 
 	// Register kcc_git_commit tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_git_commit",
 		Description: "Create git commit with enforced rules: blocks AI attribution, uses your git identity, validates message format",
+		Annotations: writeTool(false, false),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
-		Message string   `json:"message"`
-		Files   []string `json:"files,omitempty"`
+		Message  string   `json:"message"`
+		Files    []string `json:"files,omitempty"`
+		Resource string   `json:"resource,omitempty"`
+		Phase    int      `json:"phase,omitempty"`
 	}) (*mcp.CallToolResult, any, error) {
-		err := gitValidator.CreateCommit(cfg.GetRepoPath(), input.Message, input.Files)
+		err := gitValidator.CreateCommit(ctx, cfg.GetRepoPath(), input.Message, input.Files, streamOutput(ctx, req))
 		if err != nil {
 			return nil, nil, err
 		}
 
+		if input.Resource != "" {
+			commit, _ := gitValidator.GetHeadCommit(ctx, cfg.GetRepoPath())
+			recordJournal(migrationJournal, cfg, catalog, journal.Entry{
+				Resource: input.Resource,
+				Source:   "kcc_git_commit",
+				Phase:    input.Phase,
+				Status:   journal.StatusNote,
+				Message:  firstLine(input.Message),
+				Files:    input.Files,
+				Commit:   commit,
+			})
+		}
+
 		authorName, authorEmail := cfg.GetGitAuthor()
 		result := fmt.Sprintf("✅ Commit created successfully\n\nMessage: %s\n\nAuthor: %s <%s>",
 			input.Message, authorName, authorEmail)
@@ -138,13 +279,14 @@ func main() {
 	})
 
 	// Register kcc_migration_status tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_migration_status",
 		Description: "Check migration progress for a resource",
+		Annotations: readOnlyTool(),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
 		Resource string `json:"resource"`
 	}) (*mcp.CallToolResult, any, error) {
-		status, err := tools.GetMigrationStatus(cfg.GetRepoPath(), input.Resource)
+		status, err := tools.GetMigrationStatus(catalog, input.Resource)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -158,13 +300,14 @@ func main() {
 	})
 
 	// Register kcc_plan_migration tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_plan_migration",
 		Description: "Create detailed migration plan for a resource",
+		Annotations: readOnlyTool(),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
 		Resource string `json:"resource"`
 	}) (*mcp.CallToolResult, any, error) {
-		plan, err := tools.PlanMigration(cfg.GetRepoPath(), input.Resource)
+		plan, err := tools.PlanMigration(catalog, input.Resource)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -178,86 +321,267 @@ func main() {
 	})
 
 	// Register kcc_add_field tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_add_field",
-		Description: "Add a field to a KCC resource types file with proto annotations",
+		Description: "Add a field to a KCC resource types file with proto annotations. Pass just resource and proto_path to derive the name, type, JSON name, description, Spec/ObservedState placement and nested structs from the proto",
+		Annotations: writeTool(false, false),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
-		TypesFile string                 `json:"types_file"`
-		Params    tools.AddFieldParams   `json:"params"`
+		TypesFile string               `json:"types_file,omitempty"` // defaults to the resource's direct types file
+		Params    tools.AddFieldParams `json:"params"`
 	}) (*mcp.CallToolResult, any, error) {
-		result, err := tools.AddField(cfg.GetRepoPath(), input.TypesFile, input.Params)
+		changes := newChangeSet(cfg, operations, req, input.Params.DryRun)
+		result, err := tools.AddField(catalog, input.TypesFile, input.Params, changes)
 		if err != nil {
 			return nil, nil, err
 		}
 
+		return changeResult(changes, result)
+	})
+
+	// Register kcc_remove_field tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_remove_field",
+		Description: "Remove a field (by Go name or proto path) from a resource's types, report every reference to it in the mapper, controller, fixtures and golden files, and optionally regenerate the mapper",
+		Annotations: writeTool(true, false),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.RemoveFieldParams) (*mcp.CallToolResult, any, error) {
+		result, err := tools.RemoveField(ctx, catalog, input, newChangeSet(cfg, operations, req, input.DryRun), mapperOptions(ctx, req, cfg))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
+				&mcp.TextContent{Text: tools.FormatFieldChange(result)},
+				&mcp.TextContent{Text: string(jsonData)},
 			},
-		}, map[string]string{"result": result}, nil
+		}, result, nil
 	})
 
-	// Register kcc_scaffold_types tool
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "kcc_scaffold_types",
-		Description: "Generate API types file for a resource",
-	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ScaffoldTypesParams) (*mcp.CallToolResult, any, error) {
-		result, err := tools.ScaffoldTypes(cfg.GetRepoPath(), input)
+	// Register kcc_deprecate_field tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_deprecate_field",
+		Description: "Mark a field deprecated with KCC's DEPRECATED: doc comment convention, report every reference to it in the mapper, controller, fixtures and golden files, and optionally regenerate the mapper",
+		Annotations: writeTool(false, true),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.DeprecateFieldParams) (*mcp.CallToolResult, any, error) {
+		result, err := tools.DeprecateField(ctx, catalog, input, newChangeSet(cfg, operations, req, input.DryRun), mapperOptions(ctx, req, cfg))
 		if err != nil {
 			return nil, nil, err
 		}
 
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
+				&mcp.TextContent{Text: tools.FormatFieldChange(result)},
+				&mcp.TextContent{Text: string(jsonData)},
 			},
-		}, map[string]string{"result": result}, nil
+		}, result, nil
+	})
+
+	// Register kcc_scaffold_types tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_scaffold_types",
+		Description: "Generate API types file for a resource. With from_proto, Spec and ObservedState are populated from the proto message, including nested types and references",
+		Annotations: writeTool(false, false),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ScaffoldTypesParams) (*mcp.CallToolResult, any, error) {
+		changes := newChangeSet(cfg, operations, req, input.DryRun)
+		result, err := tools.ScaffoldTypes(catalog, input, changes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changes.DryRun() {
+			return changeResult(changes, result)
+		}
+		refreshCatalog(catalog)
+		recordJournal(migrationJournal, cfg, catalog, journal.Entry{
+			Resource: tools.ServiceTitle(input.Service) + input.Resource,
+			Source:   "kcc_scaffold_types",
+			Phase:    2,
+			Status:   journal.StatusInProgress,
+			Message:  firstLine(result),
+		})
+
+		return changeResult(changes, result)
 	})
 
 	// Register kcc_scaffold_identity tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_scaffold_identity",
 		Description: "Generate identity handler for a resource",
+		Annotations: writeTool(false, false),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ScaffoldIdentityParams) (*mcp.CallToolResult, any, error) {
-		result, err := tools.ScaffoldIdentity(cfg.GetRepoPath(), input)
+		changes := newChangeSet(cfg, operations, req, input.DryRun)
+		result, err := tools.ScaffoldIdentity(cfg.GetRepoPath(), input, changes)
 		if err != nil {
 			return nil, nil, err
 		}
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
-			},
-		}, map[string]string{"result": result}, nil
+		if changes.DryRun() {
+			return changeResult(changes, result)
+		}
+		refreshCatalog(catalog)
+		recordJournal(migrationJournal, cfg, catalog, journal.Entry{
+			Resource: tools.ServiceTitle(input.Service) + input.Resource,
+			Source:   "kcc_scaffold_identity",
+			Phase:    3,
+			Status:   journal.StatusInProgress,
+			Message:  firstLine(result),
+		})
+
+		return changeResult(changes, result)
 	})
 
 	// Register kcc_scaffold_controller tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_scaffold_controller",
 		Description: "Generate controller implementation for a resource",
+		Annotations: writeTool(false, false),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ScaffoldControllerParams) (*mcp.CallToolResult, any, error) {
-		result, err := tools.ScaffoldController(cfg.GetRepoPath(), input)
+		changes := newChangeSet(cfg, operations, req, input.DryRun)
+		result, err := tools.ScaffoldController(cfg.GetRepoPath(), input, changes)
 		if err != nil {
 			return nil, nil, err
 		}
-
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: result},
-			},
-		}, map[string]string{"result": result}, nil
+		if changes.DryRun() {
+			return changeResult(changes, result)
+		}
+		refreshCatalog(catalog)
+		recordJournal(migrationJournal, cfg, catalog, journal.Entry{
+			Resource: tools.ServiceTitle(input.Service) + input.Resource,
+			Source:   "kcc_scaffold_controller",
+			Phase:    5,
+			Status:   journal.StatusInProgress,
+			Message:  firstLine(result),
+		})
+
+		return changeResult(changes, result)
 	})
 
 	// Register kcc_scaffold_mockgcp tool
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(registry, &mcp.Tool{
 		Name:        "kcc_scaffold_mockgcp",
 		Description: "Generate MockGCP implementation for a resource",
+		Annotations: writeTool(false, false),
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input tools.ScaffoldMockGCPParams) (*mcp.CallToolResult, any, error) {
-		result, err := tools.ScaffoldMockGCP(cfg.GetRepoPath(), input)
+		changes := newChangeSet(cfg, operations, req, input.DryRun)
+		result, err := tools.ScaffoldMockGCP(cfg.GetRepoPath(), input, changes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changes.DryRun() {
+			return changeResult(changes, result)
+		}
+		refreshCatalog(catalog)
+		recordJournal(migrationJournal, cfg, catalog, journal.Entry{
+			Resource: tools.ServiceTitle(input.Service) + input.Resource,
+			Source:   "kcc_scaffold_mockgcp",
+			Phase:    6,
+			Status:   journal.StatusInProgress,
+			Message:  firstLine(result),
+		})
+
+		return changeResult(changes, result)
+	})
+
+	// Register kcc_undo tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_undo",
+		Description: "Revert the last N file-writing operations (add/remove/deprecate field, scaffolds, mapper generation) or a named one, restoring the files' previous content and deleting files they created. Only this session's operations are listed and undone by count; another session's operation can only be reverted by its ID. Refuses to overwrite files edited since, unless force is set. With list, show the recorded operations instead",
+		Annotations: writeTool(true, false),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+		Count     int    `json:"count,omitempty"`     // number of operations to revert, newest first; defaults to 1
+		Operation string `json:"operation,omitempty"` // revert only this operation ID
+		Force     bool   `json:"force,omitempty"`     // overwrite files changed since the operation
+		List      bool   `json:"list,omitempty"`      // list recorded operations without reverting
+	}) (*mcp.CallToolResult, any, error) {
+		if input.List {
+			ops, err := operations.List(req.Session.ID())
+			if err != nil {
+				return nil, nil, err
+			}
+			summaries := oplog.Summarise(ops)
+			text := "No recorded operations"
+			if len(summaries) > 0 {
+				text = fmt.Sprintf("%d recorded operation(s), newest first:\n\n%s", len(summaries), oplog.Format(summaries))
+			}
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+			}, map[string]any{"operations": summaries}, nil
+		}
+
+		ops, err := operations.Undo(cfg.GetRepoPath(), req.Session.ID(), input.Count, input.Operation, input.Force)
+		if err != nil {
+			return nil, nil, err
+		}
+		refreshCatalog(catalog)
+
+		summaries := oplog.Summarise(ops)
+		text := fmt.Sprintf("✅ Reverted %d operation(s):\n\n%s", len(summaries), oplog.Format(summaries))
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, map[string]any{"reverted": summaries}, nil
+	})
+
+	// Register kcc_journal_read tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_journal_read",
+		Description: "Read the migration journal of a resource: decisions, skipped or blocked phases, notes and commits recorded across sessions",
+		Annotations: readOnlyTool(),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+		Resource string `json:"resource"`
+	}) (*mcp.CallToolResult, any, error) {
+		result, err := migrationJournal.Read(journalResource(catalog, input.Resource))
 		if err != nil {
 			return nil, nil, err
 		}
 
+		jsonData, _ := json.MarshalIndent(result, "", "  ")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, result, nil
+	})
+
+	// Register kcc_journal_mark tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_journal_mark",
+		Description: "Record a migration phase as done, blocked, skipped or in_progress (or add a note) in the resource's journal",
+		Annotations: writeTool(false, false),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct {
+		Resource string `json:"resource"`
+		Phase    int    `json:"phase,omitempty"`
+		Status   string `json:"status"`
+		Note     string `json:"note,omitempty"`
+	}) (*mcp.CallToolResult, any, error) {
+		if input.Phase < 0 || input.Phase > 8 {
+			return nil, nil, fmt.Errorf("invalid phase: %d\n\nPhases are numbered 1-8 (see kcc_plan_migration)", input.Phase)
+		}
+		if input.Phase == 0 && input.Status != journal.StatusNote {
+			return nil, nil, fmt.Errorf("phase is required unless status is %q", journal.StatusNote)
+		}
+
+		authorName, authorEmail := cfg.GetGitAuthor()
+		entry := journal.Entry{
+			Resource: journalResource(catalog, input.Resource),
+			Author:   fmt.Sprintf("%s <%s>", authorName, authorEmail),
+			Source:   "manual",
+			Phase:    input.Phase,
+			Status:   input.Status,
+			Message:  input.Note,
+		}
+		if err := migrationJournal.Append(entry); err != nil {
+			return nil, nil, err
+		}
+
+		result := fmt.Sprintf("✅ Recorded %s for %s", input.Status, entry.Resource)
+		if input.Phase != 0 {
+			result = fmt.Sprintf("✅ Recorded phase %d as %s for %s", input.Phase, input.Status, entry.Resource)
+		}
+
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{Text: result},
@@ -265,10 +589,228 @@ func main() {
 		}, map[string]string{"result": result}, nil
 	})
 
-	// Start server
-	fmt.Fprintf(os.Stderr, "🚀 KCC MCP Server running\n")
+	// Register kcc_rebuild_catalog tool
+	addTool(registry, &mcp.Tool{
+		Name:        "kcc_rebuild_catalog",
+		Description: "Force a full rebuild of the in-memory KCC resource catalog",
+		Annotations: readOnlyTool(),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
+		stats, err := catalog.Rebuild()
+		if err != nil {
+			return nil, nil, err
+		}
 
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Fatalf("Fatal error: %v\n", err)
+		jsonData, _ := json.MarshalIndent(stats, "", "  ")
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, stats, nil
+	})
+}
+
+// readOnlyTool annotates a tool that does not modify the repository
+func readOnlyTool() *mcp.ToolAnnotations {
+	destructive := false
+	return &mcp.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: &destructive, IdempotentHint: true}
+}
+
+// writeTool annotates a tool that modifies the repository. A destructive tool
+// may overwrite or delete existing content; calling an idempotent tool again
+// with the same arguments changes nothing more.
+func writeTool(destructive, idempotent bool) *mcp.ToolAnnotations {
+	return &mcp.ToolAnnotations{DestructiveHint: &destructive, IdempotentHint: idempotent}
+}
+
+// toolRegistry registers tools on the server, leaving out the ones excluded
+// by read-only mode or the tool allow and deny lists
+type toolRegistry struct {
+	server     *mcp.Server
+	readOnly   bool
+	allow      []string
+	deny       []string
+	known      []string
+	registered []string
+}
+
+func newToolRegistry(server *mcp.Server, cfg *config.ConfigManager) *toolRegistry {
+	return &toolRegistry{
+		server:   server,
+		readOnly: cfg.IsReadOnly(),
+		allow:    cfg.GetToolAllowList(),
+		deny:     cfg.GetToolDenyList(),
+	}
+}
+
+// include reports whether the tool should be registered
+func (r *toolRegistry) include(tool *mcp.Tool) bool {
+	r.known = append(r.known, tool.Name)
+	if r.readOnly && !tool.Annotations.ReadOnlyHint {
+		return false
+	}
+	if len(r.allow) > 0 && !slices.Contains(r.allow, tool.Name) {
+		return false
 	}
+	return !slices.Contains(r.deny, tool.Name)
+}
+
+// report logs the registered tools and warns about allow and deny list
+// entries that name no tool
+func (r *toolRegistry) report() {
+	for _, name := range append(slices.Clone(r.allow), r.deny...) {
+		if !slices.Contains(r.known, name) {
+			fmt.Fprintf(os.Stderr, "Warning: unknown tool in allow or deny list: %s\n", name)
+		}
+	}
+	mode := ""
+	if r.readOnly {
+		mode = ", read-only"
+	}
+	fmt.Fprintf(os.Stderr, "🔧 Tools: %d of %d registered%s\n", len(r.registered), len(r.known), mode)
+}
+
+// addTool registers a tool unless the registry excludes it. Every tool must
+// carry annotations, which also decide whether it is read-only. A tool that
+// refuses a path outside the repository or its writable subtrees returns the
+// violation as a structured error result rather than plain text.
+func addTool[In any](registry *toolRegistry, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, any]) {
+	if tool.Annotations == nil {
+		log.Fatalf("tool %s has no annotations", tool.Name)
+	}
+	if !registry.include(tool) {
+		return
+	}
+	registry.registered = append(registry.registered, tool.Name)
+	mcp.AddTool(registry.server, tool, func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		result, out, err := handler(ctx, req, input)
+		var pathErr *tools.PathError
+		if !errors.As(err, &pathErr) {
+			return result, out, err
+		}
+		jsonData, _ := json.MarshalIndent(pathErr, "", "  ")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+				&mcp.TextContent{Text: string(jsonData)},
+			},
+		}, pathErr, nil
+	})
+}
+
+// newChangeSet returns the change set for a mutating tool call, logging its
+// writes under the tool's name and session so they can be undone
+func newChangeSet(cfg *config.ConfigManager, operations *oplog.Log, req *mcp.CallToolRequest, dryRun bool) *tools.ChangeSet {
+	changes := tools.NewChangeSet(cfg.GetRepoPath(), dryRun)
+	return changes.WithOperation(operations.Begin(req.Params.Name, cfg.GetRepoPath(), req.Session.ID()))
+}
+
+// changeResult returns the result of a mutating tool, or for a dry run the
+// files it would write and their diff
+func changeResult(changes *tools.ChangeSet, result string) (*mcp.CallToolResult, any, error) {
+	if !changes.DryRun() {
+		structured := map[string]string{"result": result}
+		if id := changes.OperationID(); id != "" {
+			result += fmt.Sprintf("\n\nRecorded as operation %s (revert with kcc_undo)", id)
+			structured["operation"] = id
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: result},
+			},
+		}, structured, nil
+	}
+
+	preview := changes.Preview()
+	preview.Summary = result
+	jsonData, _ := json.MarshalIndent(preview, "", "  ")
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: tools.FormatChangePreview(preview)},
+			&mcp.TextContent{Text: string(jsonData)},
+		},
+	}, preview, nil
+}
+
+// mapperOptions runs the mapper generator with the configured timeout,
+// streaming its output to the client
+func mapperOptions(ctx context.Context, req *mcp.CallToolRequest, cfg *config.ConfigManager) runner.Options {
+	return runner.Options{
+		Timeout: cfg.GetGenerateMapperTimeout(),
+		OnLine:  streamOutput(ctx, req),
+	}
+}
+
+// streamOutput forwards each line a subprocess writes to the client as a
+// log message and, if the request carries a progress token, as a progress
+// notification. Notification failures never fail the tool call, and are not
+// reported once the request is cancelled.
+func streamOutput(ctx context.Context, req *mcp.CallToolRequest) runner.LineFunc {
+	token := req.Params.GetProgressToken()
+	lines := 0
+	return func(stream, line string) {
+		if ctx.Err() != nil {
+			return
+		}
+		lines++
+		level := mcp.LoggingLevel("info")
+		if stream == runner.Stderr {
+			level = "warning"
+		}
+		if err := req.Session.Log(ctx, &mcp.LoggingMessageParams{
+			Level:  level,
+			Logger: req.Params.Name,
+			Data:   line,
+		}); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not send log message: %v\n", err)
+		}
+		if token == nil {
+			return
+		}
+		if err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(lines),
+			Message:       line,
+		}); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not send progress notification: %v\n", err)
+		}
+	}
+}
+
+// refreshCatalog picks up files created by a tool so later calls see them
+// without waiting for the next background refresh
+func refreshCatalog(catalog *tools.Catalog) {
+	if _, err := catalog.Refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: catalog refresh failed: %v\n", err)
+	}
+}
+
+// journalResource maps a resource name onto its catalog Kind so that journal
+// entries written with "urlmap", "URLMap" or "ComputeURLMap" end up together
+func journalResource(catalog *tools.Catalog, resource string) string {
+	if entry, err := catalog.Resolve(resource); err == nil && entry != nil {
+		return entry.Kind
+	}
+	return resource
+}
+
+// recordJournal appends an automatic journal entry. Journal failures are
+// logged but never fail the tool call that triggered them. A read-only
+// server records nothing.
+func recordJournal(j *journal.Journal, cfg *config.ConfigManager, catalog *tools.Catalog, entry journal.Entry) {
+	if cfg.IsReadOnly() {
+		return
+	}
+	authorName, authorEmail := cfg.GetGitAuthor()
+	entry.Resource = journalResource(catalog, entry.Resource)
+	entry.Author = fmt.Sprintf("%s <%s>", authorName, authorEmail)
+	if err := j.Append(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write journal entry: %v\n", err)
+	}
+}
+
+// firstLine returns the first line of s without a leading status emoji
+func firstLine(s string) string {
+	line := strings.SplitN(s, "\n", 2)[0]
+	return strings.TrimSpace(strings.TrimPrefix(line, "✅"))
 }
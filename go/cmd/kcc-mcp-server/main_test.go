@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/config"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/gitvalidator"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/journal"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/oplog"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/tools"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readOnlyTools are the tools that neither modify the repository nor write
+// state such as the journal or the operation log
+var readOnlyTools = []string{
+	"kcc_describe_proto",
+	"kcc_detect_controller_type",
+	"kcc_field_parity",
+	"kcc_find_resource",
+	"kcc_git_status",
+	"kcc_journal_read",
+	"kcc_list_resources",
+	"kcc_migration_status",
+	"kcc_plan_migration",
+	"kcc_rebuild_catalog",
+}
+
+// mutatingTools are the remaining tools
+var mutatingTools = []string{
+	"kcc_add_field",
+	"kcc_deprecate_field",
+	"kcc_generate_mapper",
+	"kcc_git_commit",
+	"kcc_journal_mark",
+	"kcc_remove_field",
+	"kcc_scaffold_controller",
+	"kcc_scaffold_identity",
+	"kcc_scaffold_mockgcp",
+	"kcc_scaffold_types",
+	"kcc_undo",
+}
+
+// listTools registers the tools under the given filtering and returns the
+// ones a client sees, sorted by name
+func listTools(t *testing.T, readOnly bool, allow, deny []string) []*mcp.Tool {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("KCC_AUTHOR_NAME", "Test")
+	t.Setenv("KCC_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("KCC_REPO_PATH", dir)
+	t.Setenv("KCC_STATE_DIR", dir)
+	for _, env := range []string{"KCC_READ_ONLY", "KCC_ALLOW_TOOLS", "KCC_DENY_TOOLS"} {
+		t.Setenv(env, "")
+	}
+	cfg, err := config.NewConfigManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.OverrideTools(readOnly, allow, deny)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	registry := newToolRegistry(server, cfg)
+	registerTools(registry, cfg, tools.NewCatalog(dir), gitvalidator.NewGitValidator(cfg),
+		journal.NewJournal(dir), oplog.New(dir, tools.ResolveWritePath))
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	result, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(registry.registered); len(result.Tools) != want {
+		t.Errorf("client sees %d tools, registry registered %d", len(result.Tools), want)
+	}
+	slices.SortFunc(result.Tools, func(a, b *mcp.Tool) int { return strings.Compare(a.Name, b.Name) })
+	return result.Tools
+}
+
+func toolNames(list []*mcp.Tool) []string {
+	names := make([]string, 0, len(list))
+	for _, tool := range list {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestToolAnnotations(t *testing.T) {
+	all := listTools(t, false, nil, nil)
+	want := slices.Sorted(slices.Values(append(slices.Clone(readOnlyTools), mutatingTools...)))
+	if names := toolNames(all); !reflect.DeepEqual(names, want) {
+		t.Fatalf("tools = %v\nwant %v", names, want)
+	}
+	for _, tool := range all {
+		a := tool.Annotations
+		if a == nil {
+			t.Errorf("%s has no annotations", tool.Name)
+			continue
+		}
+		readOnly := slices.Contains(readOnlyTools, tool.Name)
+		if a.ReadOnlyHint != readOnly {
+			t.Errorf("%s: readOnlyHint = %t, want %t", tool.Name, a.ReadOnlyHint, readOnly)
+		}
+		if readOnly && (a.DestructiveHint == nil || *a.DestructiveHint || !a.IdempotentHint) {
+			t.Errorf("%s: a read-only tool must be non-destructive and idempotent", tool.Name)
+		}
+		if !readOnly && a.DestructiveHint == nil {
+			t.Errorf("%s: a mutating tool must state whether it is destructive", tool.Name)
+		}
+	}
+}
+
+func TestToolRegistryFiltering(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		allow    []string
+		deny     []string
+		want     []string
+	}{
+		{
+			name:     "read-only hides every mutating tool",
+			readOnly: true,
+			want:     readOnlyTools,
+		},
+		{
+			name:  "allow list",
+			allow: []string{"kcc_add_field", "kcc_list_resources", "kcc_no_such_tool"},
+			want:  []string{"kcc_add_field", "kcc_list_resources"},
+		},
+		{
+			name: "deny list",
+			deny: mutatingTools[1:],
+			want: slices.Sorted(slices.Values(append(slices.Clone(readOnlyTools), mutatingTools[0]))),
+		},
+		{
+			name:  "deny wins over allow",
+			allow: []string{"kcc_add_field", "kcc_undo"},
+			deny:  []string{"kcc_undo"},
+			want:  []string{"kcc_add_field"},
+		},
+		{
+			name:     "read-only wins over allow",
+			readOnly: true,
+			allow:    []string{"kcc_add_field", "kcc_git_status"},
+			want:     []string{"kcc_git_status"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if names := toolNames(listTools(t, tt.readOnly, tt.allow, tt.deny)); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("tools = %v\nwant %v", names, tt.want)
+			}
+		})
+	}
+}
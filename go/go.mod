@@ -4,9 +4,15 @@ go 1.23.0
 
 toolchain go1.24.9
 
-require github.com/modelcontextprotocol/go-sdk v1.0.0
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/modelcontextprotocol/go-sdk v1.0.0
+	golang.org/x/text v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
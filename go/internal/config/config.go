@@ -6,9 +6,26 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// defaultCatalogRefreshSeconds is how often the resource catalog is refreshed
+// when the repository cannot be watched and no interval is configured
+const defaultCatalogRefreshSeconds = 30
+
+// Default subprocess timeouts. Mapper generation compiles the generator and
+// the controller package, so it gets much longer than git.
+const (
+	defaultGenerateMapperTimeoutSeconds = 600
+	defaultGitTimeoutSeconds            = 60
+)
+
+// defaultHTTPListen is the address the HTTP transport listens on when none is
+// configured: loopback only, as the server can write to the repository
+const defaultHTTPListen = "localhost:8080"
+
 // KCCConfig represents the configuration for the KCC MCP Server
 type KCCConfig struct {
 	Git struct {
@@ -16,7 +33,25 @@ type KCCConfig struct {
 		AuthorEmail string `json:"author_email"`
 	} `json:"git"`
 	KCCRepoPath string `json:"kcc_repo_path"`
-	Rules       struct {
+	StateDir    string `json:"state_dir"`
+	Catalog     struct {
+		RefreshIntervalSeconds int `json:"refresh_interval_seconds"`
+	} `json:"catalog"`
+	Timeouts struct {
+		GenerateMapperSeconds int `json:"generate_mapper_seconds"`
+		GitSeconds            int `json:"git_seconds"`
+	} `json:"timeouts"`
+	Tools struct {
+		ReadOnly bool     `json:"read_only"` // register only tools that do not modify the repository
+		Allow    []string `json:"allow"`     // if set, register only these tools
+		Deny     []string `json:"deny"`      // never register these tools
+	} `json:"tools"`
+	HTTP struct {
+		Listen       string   `json:"listen"`        // address of the HTTP transport, e.g. ":8080"
+		BearerToken  string   `json:"bearer_token"`  // if set, HTTP clients must present it as a bearer token
+		AllowedHosts []string `json:"allowed_hosts"` // host names clients may use besides loopback and the listen host
+	} `json:"http"`
+	Rules struct {
 		BlockAIAttribution         bool `json:"block_ai_attribution"`
 		RequireConventionalCommits bool `json:"require_conventional_commits"`
 	} `json:"rules"`
@@ -73,6 +108,75 @@ func (cm *ConfigManager) loadConfig() error {
 		kccRepoPath = fileConfig.KCCRepoPath
 	}
 
+	// Get state directory with priority: env > file > default
+	stateDir := os.Getenv("KCC_STATE_DIR")
+	if stateDir == "" {
+		stateDir = fileConfig.StateDir
+	}
+	if stateDir == "" {
+		stateDir = filepath.Join(os.Getenv("HOME"), ".local", "state", "kcc-mcp-server")
+	}
+
+	// Get catalog refresh interval with priority: env > file > default
+	refreshSeconds := fileConfig.Catalog.RefreshIntervalSeconds
+	if env := os.Getenv("KCC_CATALOG_REFRESH_SECONDS"); env != "" {
+		seconds, err := strconv.Atoi(env)
+		if err != nil {
+			return fmt.Errorf("invalid KCC_CATALOG_REFRESH_SECONDS %q: %w", env, err)
+		}
+		refreshSeconds = seconds
+	}
+	if refreshSeconds <= 0 {
+		refreshSeconds = defaultCatalogRefreshSeconds
+	}
+
+	// Get subprocess timeouts with priority: env > file > default
+	generateMapperSeconds, err := timeoutSeconds("KCC_GENERATE_MAPPER_TIMEOUT_SECONDS",
+		fileConfig.Timeouts.GenerateMapperSeconds, defaultGenerateMapperTimeoutSeconds)
+	if err != nil {
+		return err
+	}
+	gitSeconds, err := timeoutSeconds("KCC_GIT_TIMEOUT_SECONDS",
+		fileConfig.Timeouts.GitSeconds, defaultGitTimeoutSeconds)
+	if err != nil {
+		return err
+	}
+
+	// Get tool filtering with priority: env > file
+	readOnly := fileConfig.Tools.ReadOnly
+	if env := os.Getenv("KCC_READ_ONLY"); env != "" {
+		parsed, err := strconv.ParseBool(env)
+		if err != nil {
+			return fmt.Errorf("invalid KCC_READ_ONLY %q: %w", env, err)
+		}
+		readOnly = parsed
+	}
+	allowTools := fileConfig.Tools.Allow
+	if env := os.Getenv("KCC_ALLOW_TOOLS"); env != "" {
+		allowTools = SplitList(env)
+	}
+	denyTools := fileConfig.Tools.Deny
+	if env := os.Getenv("KCC_DENY_TOOLS"); env != "" {
+		denyTools = SplitList(env)
+	}
+
+	// Get HTTP transport settings with priority: env > file > default
+	httpListen := os.Getenv("KCC_HTTP_LISTEN")
+	if httpListen == "" {
+		httpListen = fileConfig.HTTP.Listen
+	}
+	if httpListen == "" {
+		httpListen = defaultHTTPListen
+	}
+	bearerToken := os.Getenv("KCC_HTTP_BEARER_TOKEN")
+	if bearerToken == "" {
+		bearerToken = fileConfig.HTTP.BearerToken
+	}
+	allowedHosts := fileConfig.HTTP.AllowedHosts
+	if env := os.Getenv("KCC_HTTP_ALLOWED_HOSTS"); env != "" {
+		allowedHosts = SplitList(env)
+	}
+
 	// Validate required fields
 	if authorEmail == "" || authorName == "" {
 		return fmt.Errorf(`Git author not configured. Set either:
@@ -101,12 +205,50 @@ Example config file:
 	cm.config.Git.AuthorName = authorName
 	cm.config.Git.AuthorEmail = authorEmail
 	cm.config.KCCRepoPath = kccRepoPath
+	cm.config.StateDir = stateDir
+	cm.config.Catalog.RefreshIntervalSeconds = refreshSeconds
+	cm.config.Timeouts.GenerateMapperSeconds = generateMapperSeconds
+	cm.config.Timeouts.GitSeconds = gitSeconds
+	cm.config.Tools.ReadOnly = readOnly
+	cm.config.Tools.Allow = allowTools
+	cm.config.Tools.Deny = denyTools
+	cm.config.HTTP.Listen = httpListen
+	cm.config.HTTP.BearerToken = bearerToken
+	cm.config.HTTP.AllowedHosts = allowedHosts
 	cm.config.Rules.BlockAIAttribution = true // Always enforced
 	cm.config.Rules.RequireConventionalCommits = fileConfig.Rules.RequireConventionalCommits || true
 
 	return nil
 }
 
+// timeoutSeconds reads a timeout from the environment, falling back to the
+// config file value and then the default
+func timeoutSeconds(env string, fileValue, defaultValue int) (int, error) {
+	seconds := fileValue
+	if value := os.Getenv(env); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q: %w", env, value, err)
+		}
+		seconds = parsed
+	}
+	if seconds <= 0 {
+		seconds = defaultValue
+	}
+	return seconds, nil
+}
+
+// SplitList parses a comma-separated list, such as tool or host names
+func SplitList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // getGitConfig retrieves a git config value
 func getGitConfig(key string) string {
 	cmd := exec.Command("git", "config", key)
@@ -132,6 +274,76 @@ func (cm *ConfigManager) GetRepoPath() string {
 	return cm.config.KCCRepoPath
 }
 
+// GetStateDir returns the directory holding persistent server state such as
+// migration journals
+func (cm *ConfigManager) GetStateDir() string {
+	return cm.config.StateDir
+}
+
+// GetCatalogRefreshInterval returns how often the resource catalog is refreshed
+// when the repository cannot be watched for changes
+func (cm *ConfigManager) GetCatalogRefreshInterval() time.Duration {
+	return time.Duration(cm.config.Catalog.RefreshIntervalSeconds) * time.Second
+}
+
+// GetGenerateMapperTimeout returns how long the mapper generator may run
+func (cm *ConfigManager) GetGenerateMapperTimeout() time.Duration {
+	return time.Duration(cm.config.Timeouts.GenerateMapperSeconds) * time.Second
+}
+
+// GetGitTimeout returns how long a git command may run
+func (cm *ConfigManager) GetGitTimeout() time.Duration {
+	return time.Duration(cm.config.Timeouts.GitSeconds) * time.Second
+}
+
+// IsReadOnly returns whether only tools that do not modify the repository
+// are registered
+func (cm *ConfigManager) IsReadOnly() bool {
+	return cm.config.Tools.ReadOnly
+}
+
+// GetToolAllowList returns the tools to register, or nil for all of them
+func (cm *ConfigManager) GetToolAllowList() []string {
+	return cm.config.Tools.Allow
+}
+
+// GetToolDenyList returns the tools never to register
+func (cm *ConfigManager) GetToolDenyList() []string {
+	return cm.config.Tools.Deny
+}
+
+// OverrideTools applies tool filtering given on the command line, which takes
+// precedence over the environment and config file. readOnly can only turn
+// read-only mode on; empty lists leave the configured ones in place.
+func (cm *ConfigManager) OverrideTools(readOnly bool, allow, deny []string) {
+	if readOnly {
+		cm.config.Tools.ReadOnly = true
+	}
+	if len(allow) > 0 {
+		cm.config.Tools.Allow = allow
+	}
+	if len(deny) > 0 {
+		cm.config.Tools.Deny = deny
+	}
+}
+
+// GetHTTPListen returns the address the HTTP transport listens on
+func (cm *ConfigManager) GetHTTPListen() string {
+	return cm.config.HTTP.Listen
+}
+
+// GetHTTPBearerToken returns the bearer token HTTP clients must present, or ""
+// if the HTTP transport is unauthenticated
+func (cm *ConfigManager) GetHTTPBearerToken() string {
+	return cm.config.HTTP.BearerToken
+}
+
+// GetHTTPAllowedHosts returns the host names HTTP clients may address the
+// server by, in addition to loopback names and the listen host
+func (cm *ConfigManager) GetHTTPAllowedHosts() []string {
+	return cm.config.HTTP.AllowedHosts
+}
+
 // IsBlockAIAttribution returns whether AI attribution blocking is enabled
 func (cm *ConfigManager) IsBlockAIAttribution() bool {
 	return cm.config.Rules.BlockAIAttribution
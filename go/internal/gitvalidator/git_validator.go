@@ -1,13 +1,15 @@
 package gitvalidator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 
 	"github.com/fkc1e100/kcc-mcp-server/go/internal/config"
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
 )
 
 // GitValidator handles git validation and operations
@@ -85,26 +87,22 @@ Your message: "%s"`, firstLine)
 }
 
 // ValidateGitConfig validates git config matches expected author
-func (gv *GitValidator) ValidateGitConfig(repoPath string) error {
+func (gv *GitValidator) ValidateGitConfig(ctx context.Context, repoPath string) error {
 	authorName, authorEmail := gv.config.GetGitAuthor()
 
 	// Get current email
-	cmd := exec.Command("git", "config", "user.email")
-	cmd.Dir = repoPath
-	currentEmailBytes, err := cmd.Output()
+	currentEmailBytes, err := gv.git(ctx, repoPath, nil, nil, "config", "user.email")
 	if err != nil {
 		return fmt.Errorf("failed to check git config: %w", err)
 	}
-	currentEmail := strings.TrimSpace(string(currentEmailBytes))
+	currentEmail := strings.TrimSpace(currentEmailBytes)
 
 	// Get current name
-	cmd = exec.Command("git", "config", "user.name")
-	cmd.Dir = repoPath
-	currentNameBytes, err := cmd.Output()
+	currentNameBytes, err := gv.git(ctx, repoPath, nil, nil, "config", "user.name")
 	if err != nil {
 		return fmt.Errorf("failed to check git config: %w", err)
 	}
-	currentName := strings.TrimSpace(string(currentNameBytes))
+	currentName := strings.TrimSpace(currentNameBytes)
 
 	if currentEmail != authorEmail || currentName != authorName {
 		return fmt.Errorf(`⚠️  Git config mismatch!
@@ -125,8 +123,9 @@ Run in %s:
 	return nil
 }
 
-// CreateCommit creates a commit with validated identity
-func (gv *GitValidator) CreateCommit(repoPath, message string, files []string) error {
+// CreateCommit creates a commit with validated identity. Output of git and
+// its hooks is passed to onLine as it is written.
+func (gv *GitValidator) CreateCommit(ctx context.Context, repoPath, message string, files []string, onLine runner.LineFunc) error {
 	// 1. Validate message (blocks AI attribution)
 	if err := gv.ValidateCommitMessage(message); err != nil {
 		return err
@@ -138,24 +137,20 @@ func (gv *GitValidator) CreateCommit(repoPath, message string, files []string) e
 	}
 
 	// 3. Ensure git config matches
-	if err := gv.ValidateGitConfig(repoPath); err != nil {
+	if err := gv.ValidateGitConfig(ctx, repoPath); err != nil {
 		return err
 	}
 
 	// 4. Stage files if provided
 	if len(files) > 0 {
 		for _, file := range files {
-			cmd := exec.Command("git", "add", file)
-			cmd.Dir = repoPath
-			if err := cmd.Run(); err != nil {
+			if _, err := gv.git(ctx, repoPath, onLine, nil, "add", file); err != nil {
 				return fmt.Errorf("failed to stage file %s: %w", file, err)
 			}
 		}
 	} else {
 		// Stage all changes
-		cmd := exec.Command("git", "add", "-A")
-		cmd.Dir = repoPath
-		if err := cmd.Run(); err != nil {
+		if _, err := gv.git(ctx, repoPath, onLine, nil, "add", "-A"); err != nil {
 			return fmt.Errorf("failed to stage changes: %w", err)
 		}
 	}
@@ -163,16 +158,14 @@ func (gv *GitValidator) CreateCommit(repoPath, message string, files []string) e
 	// 5. Create commit with validated identity
 	authorName, authorEmail := gv.config.GetGitAuthor()
 
-	cmd := exec.Command("git", "commit", "-m", message)
-	cmd.Dir = repoPath
-	cmd.Env = append(os.Environ(),
+	env := append(os.Environ(),
 		fmt.Sprintf("GIT_AUTHOR_NAME=%s", authorName),
 		fmt.Sprintf("GIT_AUTHOR_EMAIL=%s", authorEmail),
 		fmt.Sprintf("GIT_COMMITTER_NAME=%s", authorName),
 		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", authorEmail),
 	)
 
-	if err := cmd.Run(); err != nil {
+	if _, err := gv.git(ctx, repoPath, onLine, env, "commit", "-m", message); err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
 	}
 
@@ -180,12 +173,38 @@ func (gv *GitValidator) CreateCommit(repoPath, message string, files []string) e
 }
 
 // GetStatus gets current git status
-func (gv *GitValidator) GetStatus(repoPath string) (string, error) {
-	cmd := exec.Command("git", "status", "--short")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+func (gv *GitValidator) GetStatus(ctx context.Context, repoPath string) (string, error) {
+	output, err := gv.git(ctx, repoPath, nil, nil, "status", "--short")
 	if err != nil {
 		return "", fmt.Errorf("failed to get git status: %w", err)
 	}
-	return string(output), nil
+	return output, nil
+}
+
+// GetHeadCommit returns the abbreviated hash of the current HEAD commit
+func (gv *GitValidator) GetHeadCommit(ctx context.Context, repoPath string) (string, error) {
+	output, err := gv.git(ctx, repoPath, nil, nil, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// git runs a git command in repoPath with the configured timeout. A failed
+// command's error includes its output, which for commits holds the hook
+// messages explaining why.
+func (gv *GitValidator) git(ctx context.Context, repoPath string, onLine runner.LineFunc, env []string, args ...string) (string, error) {
+	output, err := runner.Run(ctx, runner.Command{
+		Name:    "git",
+		Args:    args,
+		Dir:     repoPath,
+		Env:     env,
+		Timeout: gv.config.GetGitTimeout(),
+		OnLine:  onLine,
+	})
+	var runErr *runner.Error
+	if errors.As(err, &runErr) && strings.TrimSpace(runErr.Output) != "" {
+		return output, fmt.Errorf("%w\n\n%s", err, strings.TrimSpace(runErr.Output))
+	}
+	return output, err
 }
@@ -0,0 +1,194 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry statuses
+const (
+	StatusDone       = "done"
+	StatusBlocked    = "blocked"
+	StatusSkipped    = "skipped"
+	StatusInProgress = "in_progress"
+	StatusNote       = "note"
+)
+
+// Entry is a single record in a resource's migration journal
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Resource string    `json:"resource"`
+	Author   string    `json:"author,omitempty"`
+	Source   string    `json:"source"` // tool that wrote the entry, or "manual"
+	Phase    int       `json:"phase,omitempty"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message"`
+	Files    []string  `json:"files,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+}
+
+// PhaseMark is the latest recorded status of a migration phase
+type PhaseMark struct {
+	Phase   int       `json:"phase"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	Author  string    `json:"author,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// ResourceJournal is the full journal of a resource with a per-phase summary
+type ResourceJournal struct {
+	Resource string      `json:"resource"`
+	Path     string      `json:"path"`
+	Phases   []PhaseMark `json:"phases"`
+	Entries  []Entry     `json:"entries"`
+}
+
+// resourcePattern restricts journal keys to identifiers so they are safe file names
+var resourcePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,127}$`)
+
+// Journal persists per-resource migration journals as JSON Lines files
+// under {stateDir}/journal/{Resource}.jsonl
+type Journal struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewJournal creates a journal rooted in stateDir
+func NewJournal(stateDir string) *Journal {
+	return &Journal{dir: filepath.Join(stateDir, "journal")}
+}
+
+// ValidStatus reports whether status may be recorded in a journal entry
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusDone, StatusBlocked, StatusSkipped, StatusInProgress, StatusNote:
+		return true
+	}
+	return false
+}
+
+func (j *Journal) path(resource string) (string, error) {
+	if !resourcePattern.MatchString(resource) {
+		return "", fmt.Errorf("invalid journal resource name: %q", resource)
+	}
+	return filepath.Join(j.dir, resource+".jsonl"), nil
+}
+
+// Append adds an entry to the resource's journal, filling in the time if unset
+func (j *Journal) Append(e Entry) error {
+	if !ValidStatus(e.Status) {
+		return fmt.Errorf("invalid journal status: %q\n\nUse one of: done, blocked, skipped, in_progress, note", e.Status)
+	}
+	path, err := j.path(e.Resource)
+	if err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Read returns every entry recorded for resource, oldest first, together with
+// the latest status of each phase. A resource without a journal yields an
+// empty result.
+func (j *Journal) Read(resource string) (*ResourceJournal, error) {
+	path, err := j.path(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResourceJournal{
+		Resource: resource,
+		Path:     path,
+		Phases:   []PhaseMark{},
+		Entries:  []Entry{},
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", path, lineNo, err)
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	result.Phases = summarisePhases(result.Entries)
+	return result, nil
+}
+
+// summarisePhases returns the latest non-note status recorded for each phase
+func summarisePhases(entries []Entry) []PhaseMark {
+	latest := make(map[int]PhaseMark)
+	for _, e := range entries {
+		if e.Phase == 0 || e.Status == StatusNote {
+			continue
+		}
+		latest[e.Phase] = PhaseMark{
+			Phase:   e.Phase,
+			Status:  e.Status,
+			Message: e.Message,
+			Author:  e.Author,
+			Time:    e.Time,
+		}
+	}
+
+	marks := make([]PhaseMark, 0, len(latest))
+	for _, m := range latest {
+		marks = append(marks, m)
+	}
+	sort.Slice(marks, func(a, b int) bool {
+		return marks[a].Phase < marks[b].Phase
+	})
+	return marks
+}
@@ -0,0 +1,134 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendRejectsInvalidEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{name: "path traversal", entry: Entry{Resource: "../x", Status: StatusDone}, want: `invalid journal resource name: "../x"`},
+		{name: "separator", entry: Entry{Resource: "compute/URLMap", Status: StatusDone}, want: "invalid journal resource name"},
+		{name: "empty resource", entry: Entry{Status: StatusDone}, want: "invalid journal resource name"},
+		{name: "leading digit", entry: Entry{Resource: "1Kind", Status: StatusDone}, want: "invalid journal resource name"},
+		{name: "invalid status", entry: Entry{Resource: "ComputeURLMap", Status: "finished"}, want: `invalid journal status: "finished"`},
+		{name: "missing status", entry: Entry{Resource: "ComputeURLMap"}, want: "invalid journal status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			err := NewJournal(stateDir).Append(tt.entry)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Append() = %v, want %q", err, tt.want)
+			}
+			if entries, _ := os.ReadDir(stateDir); len(entries) != 0 {
+				t.Errorf("Append() created %s despite the error", entries[0].Name())
+			}
+		})
+	}
+}
+
+func TestReadMissingJournal(t *testing.T) {
+	j := NewJournal(t.TempDir())
+	got, err := j.Read("ComputeURLMap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Resource != "ComputeURLMap" || len(got.Entries) != 0 || len(got.Phases) != 0 || got.Entries == nil || got.Phases == nil {
+		t.Errorf("Read() = %+v, want an empty journal", got)
+	}
+	if _, err := j.Read("../x"); err == nil {
+		t.Error("Read() accepted an invalid resource name")
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	stateDir := t.TempDir()
+	j := NewJournal(stateDir)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Resource: "ComputeURLMap", Phase: 1, Status: StatusInProgress, Message: "vendoring protos", Author: "alice"},
+		{Resource: "ComputeURLMap", Phase: 2, Status: StatusBlocked, Message: "waiting on the API review"},
+		{Resource: "ComputeURLMap", Phase: 1, Status: StatusDone, Message: "protos vendored", Author: "bob"},
+		{Resource: "ComputeURLMap", Phase: 1, Status: StatusNote, Message: "the v1 proto has no etag"},
+		{Resource: "ComputeURLMap", Status: StatusNote, Message: "no phase"},
+		{Resource: "ComputeRegionURLMap", Phase: 1, Status: StatusSkipped, Message: "another resource"},
+	}
+	for i, e := range entries {
+		e.Time = start.Add(time.Duration(i) * time.Minute)
+		if err := j.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Append(Entry{Resource: "ComputeURLMap", Phase: 3, Status: StatusDone, Message: "identity"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := j.Read("ComputeURLMap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(stateDir, "journal", "ComputeURLMap.jsonl"); got.Path != want {
+		t.Errorf("path = %s, want %s", got.Path, want)
+	}
+	if len(got.Entries) != 6 {
+		t.Fatalf("got %d entries, want 6", len(got.Entries))
+	}
+	if last := got.Entries[5]; last.Time.IsZero() || last.Message != "identity" {
+		t.Errorf("last entry = %+v, want the identity entry with its time filled in", last)
+	}
+
+	// The latest status that is not a note wins per phase
+	want := []PhaseMark{
+		{Phase: 1, Status: StatusDone, Message: "protos vendored", Author: "bob", Time: start.Add(2 * time.Minute)},
+		{Phase: 2, Status: StatusBlocked, Message: "waiting on the API review", Time: start.Add(time.Minute)},
+		{Phase: 3, Status: StatusDone, Message: "identity", Time: got.Entries[5].Time},
+	}
+	if !reflect.DeepEqual(got.Phases, want) {
+		t.Errorf("phases =\n%+v\nwant\n%+v", got.Phases, want)
+	}
+}
+
+func TestSummarisePhases(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []PhaseMark
+	}{
+		{name: "no entries", want: []PhaseMark{}},
+		{
+			name:    "only notes",
+			entries: []Entry{{Phase: 4, Status: StatusNote}, {Status: StatusDone}},
+			want:    []PhaseMark{},
+		},
+		{
+			name: "a later status overrides an earlier one",
+			entries: []Entry{
+				{Phase: 5, Status: StatusDone},
+				{Phase: 5, Status: StatusInProgress, Message: "reopened"},
+				{Phase: 5, Status: StatusNote, Message: "ignored"},
+			},
+			want: []PhaseMark{{Phase: 5, Status: StatusInProgress, Message: "reopened"}},
+		},
+		{
+			name:    "sorted by phase",
+			entries: []Entry{{Phase: 7, Status: StatusSkipped}, {Phase: 2, Status: StatusDone}},
+			want:    []PhaseMark{{Phase: 2, Status: StatusDone}, {Phase: 7, Status: StatusSkipped}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarisePhases(tt.entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summarisePhases() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package oplog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileImage is the state of a file before an operation changed it
+type FileImage struct {
+	Path    string `json:"path"`             // relative to the repository
+	Existed bool   `json:"existed"`          // false if the operation created the file
+	Before  []byte `json:"before,omitempty"` // content before the operation
+	After   string `json:"after_sha256"`     // hash of the content the operation left, "" if it deleted the file
+	Mode    uint32 `json:"mode,omitempty"`   // permission bits before the operation
+
+	abs     string // absolute path while the operation is running
+	pending bool   // captured but not yet compared with the result
+}
+
+// Operation is one tool call's worth of file writes
+type Operation struct {
+	ID       string      `json:"id"`
+	Tool     string      `json:"tool"`
+	Time     time.Time   `json:"time"`
+	RepoPath string      `json:"repo_path"`
+	Session  string      `json:"session,omitempty"` // MCP session that made the operation; "" over stdio
+	Files    []FileImage `json:"files"`
+
+	log *Log
+}
+
+// PathCheck confines a file an undo is about to write or delete. path is
+// relative to repoPath; the absolute path to use is returned.
+type PathCheck func(repoPath, path string) (string, error)
+
+// Log persists operations with the before-images of the files they changed,
+// one JSON file per operation under {stateDir}/oplog
+type Log struct {
+	dir   string
+	check PathCheck
+	mu    sync.Mutex
+}
+
+// New creates an operation log rooted in stateDir. Undo passes every file it
+// restores or deletes through check, if set.
+func New(stateDir string, check PathCheck) *Log {
+	return &Log{dir: filepath.Join(stateDir, "oplog"), check: check}
+}
+
+// Begin starts an operation for a tool call made in session. Nothing is
+// persisted until a captured file is saved.
+func (l *Log) Begin(tool, repoPath, session string) *Operation {
+	now := time.Now().UTC()
+	return &Operation{
+		ID:       now.Format("20060102-150405.000000") + "-" + tool,
+		Tool:     tool,
+		Time:     now,
+		RepoPath: repoPath,
+		Session:  session,
+		Files:    []FileImage{},
+		log:      l,
+	}
+}
+
+// Capture records the before-image of path (absolute) unless the operation
+// already holds one. Call it before the file is written.
+func (op *Operation) Capture(path string) error {
+	if op == nil {
+		return nil
+	}
+	for i := range op.Files {
+		if op.Files[i].abs == path {
+			// Written again: its result has to be compared afresh
+			op.Files[i].pending = true
+			return nil
+		}
+	}
+	rel, err := op.relPath(path)
+	if err != nil {
+		return err
+	}
+	image := FileImage{Path: rel, abs: path, pending: true}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read %s before writing it: %w", path, err)
+	default:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s before writing it: %w", path, err)
+		}
+		image.Existed = true
+		image.Before = content
+		image.Mode = uint32(info.Mode().Perm())
+	}
+	op.Files = append(op.Files, image)
+	return nil
+}
+
+// Save compares the captured files with what is on disk now, drops the ones
+// that did not change and persists the operation. An operation that changed
+// nothing is not written.
+func (op *Operation) Save() error {
+	if op == nil {
+		return nil
+	}
+	kept := op.Files[:0]
+	for _, f := range op.Files {
+		if f.pending {
+			content, err := os.ReadFile(f.abs)
+			exists := err == nil
+			if exists == f.Existed && (!exists || bytes.Equal(content, f.Before)) {
+				continue // untouched
+			}
+			f.After = ""
+			if exists {
+				f.After = hash(content)
+			}
+			f.pending = false
+		}
+		kept = append(kept, f)
+	}
+	op.Files = kept
+	if len(op.Files) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode operation: %w", err)
+	}
+	op.log.mu.Lock()
+	defer op.log.mu.Unlock()
+	if err := os.MkdirAll(op.log.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create operation log directory: %w", err)
+	}
+	return WriteFile(op.log.path(op.ID), data, 0644)
+}
+
+// Paths lists the files the operation changed
+func (op *Operation) Paths() []string {
+	paths := make([]string, 0, len(op.Files))
+	for _, f := range op.Files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// Summary is an operation without its before-images, as reported to clients
+type Summary struct {
+	ID    string    `json:"id"`
+	Tool  string    `json:"tool"`
+	Time  time.Time `json:"time"`
+	Files []string  `json:"files"`
+}
+
+// Summarise drops the before-images of ops
+func Summarise(ops []*Operation) []Summary {
+	summaries := make([]Summary, 0, len(ops))
+	for _, op := range ops {
+		summaries = append(summaries, Summary{ID: op.ID, Tool: op.Tool, Time: op.Time, Files: op.Paths()})
+	}
+	return summaries
+}
+
+// Format renders operation summaries as text, one block per operation
+func Format(summaries []Summary) string {
+	var b strings.Builder
+	for _, s := range summaries {
+		fmt.Fprintf(&b, "%s  %s  %s\n", s.ID, s.Tool, s.Time.Local().Format(time.DateTime))
+		for _, f := range s.Files {
+			fmt.Fprintf(&b, "    %s\n", f)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// List returns the operations recorded in session, newest first. Sessions
+// sharing one server over HTTP each see only their own operations.
+func (l *Log) List(session string) ([]*Operation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ops, err := l.list()
+	if err != nil {
+		return nil, err
+	}
+	return inSession(ops, session), nil
+}
+
+// inSession filters ops down to the ones made in session
+func inSession(ops []*Operation, session string) []*Operation {
+	filtered := []*Operation{}
+	for _, op := range ops {
+		if op.Session == session {
+			filtered = append(filtered, op)
+		}
+	}
+	return filtered
+}
+
+func (l *Log) list() ([]*Operation, error) {
+	entries, err := os.ReadDir(l.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Operation{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation log: %w", err)
+	}
+
+	ops := []*Operation{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read operation log: %w", err)
+		}
+		op := &Operation{log: l}
+		if err := json.Unmarshal(data, op); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", e.Name(), err)
+		}
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(a, b int) bool {
+		return ops[a].ID > ops[b].ID
+	})
+	return ops, nil
+}
+
+// Undo reverts the last count operations of session, or the operation with
+// the given ID, restoring every file to its before-image and deleting files
+// the operations created. Operations of other sessions are never reverted by
+// count, and an operation whose files another, later operation changed too
+// is refused, as is one recorded for another repository than repoPath or
+// with a file outside it. Files changed since the operation are not touched
+// unless force is set. Reverted operations are removed from the log.
+func (l *Log) Undo(repoPath, session string, count int, id string, force bool) ([]*Operation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	all, err := l.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*Operation
+	if id != "" {
+		i := slices.IndexFunc(all, func(op *Operation) bool { return op.ID == id })
+		if i < 0 {
+			return nil, fmt.Errorf("operation not found: %s\n\nCall kcc_undo with list to see the recorded operations", id)
+		}
+		targets = all[i : i+1]
+	} else {
+		ops := inSession(all, session)
+		if len(ops) == 0 {
+			return nil, fmt.Errorf("the operation log is empty: nothing to undo")
+		}
+		if count <= 0 {
+			count = 1
+		}
+		if count > len(ops) {
+			return nil, fmt.Errorf("cannot undo %d operations: only %d recorded", count, len(ops))
+		}
+		targets = ops[:count]
+	}
+
+	// Later operations that touched the same files would be clobbered
+	for _, target := range targets {
+		for _, later := range all {
+			if later.ID <= target.ID || slices.Contains(targets, later) {
+				continue
+			}
+			if shared := sharedPaths(later, target); len(shared) > 0 {
+				return nil, fmt.Errorf("cannot undo %s: the later operation %s (%s) also changed %s\n\nUndo the later operation first",
+					target.ID, later.ID, sessionName(later.Session), strings.Join(shared, ", "))
+			}
+		}
+	}
+
+	// Nothing is reverted unless every file of every target may be written
+	paths := make(map[*Operation][]string)
+	for _, op := range targets {
+		if filepath.Clean(op.RepoPath) != filepath.Clean(repoPath) {
+			return nil, fmt.Errorf("cannot undo %s: it was recorded in %s, not in %s", op.ID, op.RepoPath, repoPath)
+		}
+		for _, f := range op.Files {
+			path, err := l.resolve(op, f)
+			if err != nil {
+				return nil, fmt.Errorf("cannot undo %s: %w", op.ID, err)
+			}
+			paths[op] = append(paths[op], path)
+		}
+	}
+
+	if !force {
+		if err := checkUnchanged(targets, paths); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, op := range targets {
+		if err := op.revert(paths[op]); err != nil {
+			return nil, fmt.Errorf("failed to undo %s: %w", op.ID, err)
+		}
+		if err := os.Remove(l.path(op.ID)); err != nil {
+			return nil, fmt.Errorf("undid %s but could not remove it from the log: %w", op.ID, err)
+		}
+	}
+	return targets, nil
+}
+
+// checkUnchanged verifies that the files of the operations, at the resolved
+// paths, still hold what the operations wrote. For a file touched by several
+// of the operations only the newest one's result is on disk.
+func checkUnchanged(ops []*Operation, paths map[*Operation][]string) error {
+	seen := make(map[string]bool)
+	var changed []string
+	for _, op := range ops {
+		for i, f := range op.Files {
+			path := paths[op][i]
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			current := ""
+			if content, err := os.ReadFile(path); err == nil {
+				current = hash(content)
+			}
+			if current != f.After {
+				changed = append(changed, fmt.Sprintf("%s (by %s)", f.Path, op.ID))
+			}
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("files changed since the operation, refusing to overwrite them:\n  %s\n\nCommit or stash those edits, or pass force to discard them",
+			strings.Join(changed, "\n  "))
+	}
+	return nil
+}
+
+// revert restores the before-images of the operation's files, at the
+// resolved paths
+func (op *Operation) revert(paths []string) error {
+	for i, f := range op.Files {
+		path := paths[i]
+		if !f.Existed {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		mode := os.FileMode(f.Mode)
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := WriteFile(path, f.Before, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedPaths lists the files two operations both changed
+func sharedPaths(a, b *Operation) []string {
+	var shared []string
+	for _, fa := range a.Files {
+		for _, fb := range b.Files {
+			if fa.Path == fb.Path && a.RepoPath == b.RepoPath {
+				shared = append(shared, fa.Path)
+			}
+		}
+	}
+	return shared
+}
+
+// sessionName describes the session an operation was made in
+func sessionName(session string) string {
+	if session == "" {
+		return "stdio session"
+	}
+	return "session " + session
+}
+
+func (l *Log) path(id string) string {
+	return filepath.Join(l.dir, id+".json")
+}
+
+// resolve returns the absolute path of one of the operation's files. Paths
+// are recorded relative to the repository; absolute ones or ones leading
+// out of it are refused, and the rest must pass the log's PathCheck.
+func (l *Log) resolve(op *Operation, f FileImage) (string, error) {
+	path := filepath.FromSlash(f.Path)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("%s is not a path inside the repository", f.Path)
+	}
+	if l.check != nil {
+		return l.check(op.RepoPath, f.Path)
+	}
+	return filepath.Join(op.RepoPath, path), nil
+}
+
+// relPath returns path relative to the repository, and refuses paths outside
+// it: their before-images could not be restored
+func (op *Operation) relPath(path string) (string, error) {
+	roots := []string{op.RepoPath}
+	// Tools pass canonical paths, with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(op.RepoPath); err == nil {
+		roots = append(roots, resolved)
+	}
+	for _, root := range roots {
+		if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("cannot record %s: it is outside the repository %s", path, op.RepoPath)
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteFile writes data to a temporary file next to path and renames it into
+// place, so a crash never leaves a truncated file behind
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package oplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apisOnly is a PathCheck that allows writes under apis/ only
+func apisOnly(repoPath, path string) (string, error) {
+	if !strings.HasPrefix(path, "apis/") {
+		return "", fmt.Errorf("%s is not in a subtree tools may write to", path)
+	}
+	return filepath.Join(repoPath, filepath.FromSlash(path)), nil
+}
+
+// newTestLog returns an operation log and an empty repository
+func newTestLog(t *testing.T) (*Log, string) {
+	t.Helper()
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "apis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return New(t.TempDir(), apisOnly), repo
+}
+
+// record runs one operation that writes the given files, relative to repo
+func record(t *testing.T, l *Log, repo, session, tool string, writes map[string]string) *Operation {
+	t.Helper()
+	time.Sleep(time.Millisecond) // operation IDs are ordered by time
+	op := l.Begin(tool, repo, session)
+	for rel, content := range writes {
+		path := filepath.Join(repo, filepath.FromSlash(rel))
+		if err := op.Capture(path); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path, content)
+	}
+	if err := op.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return op
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of a repository file, or "<missing>"
+func readFile(t *testing.T, repo, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repo, filepath.FromSlash(rel)))
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func ids(ops []*Operation) []string {
+	var ids []string
+	for _, op := range ops {
+		ids = append(ids, op.ID)
+	}
+	return ids
+}
+
+func TestUndoByCount(t *testing.T) {
+	l, repo := newTestLog(t)
+	writeFile(t, filepath.Join(repo, "apis/b.go"), "b v1")
+
+	first := record(t, l, repo, "s1", "first", map[string]string{"apis/a.go": "a v1"})
+	second := record(t, l, repo, "s1", "second", map[string]string{"apis/b.go": "b v2"})
+	third := record(t, l, repo, "s1", "third", map[string]string{"apis/a.go": "a v2"})
+	other := record(t, l, repo, "s2", "other", map[string]string{"apis/c.go": "c v1"})
+
+	if _, err := l.Undo(repo, "s1", 4, "", false); err == nil || !strings.Contains(err.Error(), "only 3 recorded") {
+		t.Errorf("undoing more operations than the session recorded: %v", err)
+	}
+
+	undone, err := l.Undo(repo, "s1", 2, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(undone), []string{third.ID, second.ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("undone %v, want %v", got, want)
+	}
+	if got := readFile(t, repo, "apis/a.go"); got != "a v1" {
+		t.Errorf("apis/a.go = %q, want the content before the third operation", got)
+	}
+	if got := readFile(t, repo, "apis/b.go"); got != "b v1" {
+		t.Errorf("apis/b.go = %q, want the content before the second operation", got)
+	}
+
+	remaining, err := l.List("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(remaining); len(got) != 1 || got[0] != first.ID {
+		t.Errorf("remaining operations = %v, want only %s", got, first.ID)
+	}
+	if got := readFile(t, repo, "apis/c.go"); got != "c v1" {
+		t.Errorf("another session's operation was reverted: apis/c.go = %q", got)
+	}
+	if others, _ := l.List("s2"); len(others) != 1 || others[0].ID != other.ID {
+		t.Errorf("operations of s2 = %v", ids(others))
+	}
+}
+
+func TestUndoDeletesCreatedFiles(t *testing.T) {
+	l, repo := newTestLog(t)
+	record(t, l, repo, "", "scaffold", map[string]string{
+		"apis/widgets/v1alpha1/widget_types.go": "package v1alpha1",
+		"apis/widgets/v1alpha1/doc.go":          "package v1alpha1",
+	})
+
+	if _, err := l.Undo(repo, "", 1, "", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"apis/widgets/v1alpha1/widget_types.go", "apis/widgets/v1alpha1/doc.go"} {
+		if got := readFile(t, repo, rel); got != "<missing>" {
+			t.Errorf("%s was not deleted: %q", rel, got)
+		}
+	}
+	if _, err := l.Undo(repo, "", 1, "", false); err == nil || !strings.Contains(err.Error(), "nothing to undo") {
+		t.Errorf("undo with an empty log: %v", err)
+	}
+}
+
+func TestUndoByIDConflict(t *testing.T) {
+	l, repo := newTestLog(t)
+	writeFile(t, filepath.Join(repo, "apis/shared.go"), "v0")
+
+	first := record(t, l, repo, "s1", "first", map[string]string{"apis/shared.go": "v1", "apis/own.go": "own"})
+	second := record(t, l, repo, "s2", "second", map[string]string{"apis/shared.go": "v2"})
+
+	_, err := l.Undo(repo, "s1", 0, first.ID, false)
+	if err == nil || !strings.Contains(err.Error(), "the later operation "+second.ID+" (session s2) also changed apis/shared.go") {
+		t.Fatalf("undoing an operation a later one built on: %v", err)
+	}
+	if got := readFile(t, repo, "apis/own.go"); got != "own" {
+		t.Errorf("a refused undo changed apis/own.go: %q", got)
+	}
+
+	// Another session's operation can be reverted by ID, newest first
+	if _, err := l.Undo(repo, "s1", 0, second.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Undo(repo, "s1", 0, first.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, repo, "apis/shared.go"); got != "v0" {
+		t.Errorf("apis/shared.go = %q, want v0", got)
+	}
+	if got := readFile(t, repo, "apis/own.go"); got != "<missing>" {
+		t.Errorf("apis/own.go = %q, want it deleted", got)
+	}
+
+	if _, err := l.Undo(repo, "s1", 0, "no-such-operation", false); err == nil || !strings.Contains(err.Error(), "operation not found") {
+		t.Errorf("undo of an unknown ID: %v", err)
+	}
+}
+
+func TestUndoForce(t *testing.T) {
+	l, repo := newTestLog(t)
+	writeFile(t, filepath.Join(repo, "apis/a.go"), "before")
+	record(t, l, repo, "", "add_field", map[string]string{"apis/a.go": "tool edit"})
+	writeFile(t, filepath.Join(repo, "apis/a.go"), "user edit")
+
+	_, err := l.Undo(repo, "", 1, "", false)
+	if err == nil || !strings.Contains(err.Error(), "files changed since the operation") {
+		t.Fatalf("undo over a file edited since: %v", err)
+	}
+	if got := readFile(t, repo, "apis/a.go"); got != "user edit" {
+		t.Errorf("a refused undo overwrote the user's edit: %q", got)
+	}
+
+	if _, err := l.Undo(repo, "", 1, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, repo, "apis/a.go"); got != "before" {
+		t.Errorf("apis/a.go = %q after a forced undo, want before", got)
+	}
+}
+
+// writeEntry persists a hand-made operation, as a tampered or foreign log
+// entry would be
+func writeEntry(t *testing.T, l *Log, op *Operation) {
+	t.Helper()
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(l.path(op.ID), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndoRefusesPathsOutsideRepository(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+
+	tests := []struct {
+		name     string
+		path     string
+		repoPath string // recorded repository, the test repository if empty
+		want     string
+	}{
+		{name: "absolute path", path: outside, want: "is not a path inside the repository"},
+		{name: "parent directory", path: "../outside.txt", want: "is not a path inside the repository"},
+		{name: "parent directory inside a path", path: "apis/../../outside.txt", want: "is not a path inside the repository"},
+		{name: "outside the writable subtrees", path: "pkg/clients/generated/x.go", want: "not in a subtree tools may write to"},
+		{name: "another repository", path: "apis/a.go", repoPath: filepath.Dir(outside), want: "was recorded in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, repo := newTestLog(t)
+			writeFile(t, outside, "keep")
+			writeFile(t, filepath.Join(repo, "apis/ok.go"), "tool edit")
+
+			repoPath := tt.repoPath
+			if repoPath == "" {
+				repoPath = repo
+			}
+			writeEntry(t, l, &Operation{
+				ID:       "20260101-000000.000000-tampered",
+				Tool:     "tampered",
+				RepoPath: repoPath,
+				Files: []FileImage{
+					{Path: "apis/ok.go", Existed: true, Before: []byte("before"), After: hash([]byte("tool edit"))},
+					{Path: filepath.ToSlash(tt.path), Existed: true, Before: []byte("pwned")},
+				},
+			})
+
+			_, err := l.Undo(repo, "", 1, "", true)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.want)
+			}
+			if got := readFile(t, filepath.Dir(outside), "outside.txt"); got != "keep" {
+				t.Errorf("file outside the repository was written: %q", got)
+			}
+			if got := readFile(t, repo, "apis/ok.go"); got != "tool edit" {
+				t.Errorf("a refused undo reverted the operation's other files: %q", got)
+			}
+		})
+	}
+}
+
+func TestCaptureRefusesPathsOutsideRepository(t *testing.T) {
+	l, repo := newTestLog(t)
+	op := l.Begin("add_field", repo, "")
+	err := op.Capture(filepath.Join(filepath.Dir(repo), "elsewhere.go"))
+	if err == nil || !strings.Contains(err.Error(), "outside the repository") {
+		t.Errorf("capturing a file outside the repository: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package protoparser
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TypeKind classifies the type of a field
+type TypeKind string
+
+const (
+	KindScalar  TypeKind = "scalar"
+	KindMessage TypeKind = "message"
+	KindEnum    TypeKind = "enum"
+	KindUnknown TypeKind = "unknown" // a message or enum from a file that is not indexed
+)
+
+// scalarTypes are the protobuf scalar value types
+var scalarTypes = map[string]bool{
+	"double": true, "float": true,
+	"int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true,
+	"sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// IsScalar reports whether typ is a protobuf scalar type
+func IsScalar(typ string) bool {
+	return scalarTypes[typ]
+}
+
+// Index resolves messages, enums and services across a set of parsed files
+type Index struct {
+	files    []*File
+	messages map[string]*Message
+	enums    map[string]*Enum
+	services []*Service
+}
+
+// NewIndex indexes every message and enum, including nested ones, declared
+// in files
+func NewIndex(files ...*File) *Index {
+	ix := &Index{
+		files:    files,
+		messages: make(map[string]*Message),
+		enums:    make(map[string]*Enum),
+	}
+	for _, f := range files {
+		for _, e := range f.Enums {
+			ix.enums[e.FullName] = e
+		}
+		for _, m := range f.Messages {
+			ix.addMessage(m)
+		}
+		ix.services = append(ix.services, f.Services...)
+	}
+	return ix
+}
+
+func (ix *Index) addMessage(m *Message) {
+	ix.messages[m.FullName] = m
+	for _, e := range m.Enums {
+		ix.enums[e.FullName] = e
+	}
+	for _, nested := range m.Messages {
+		ix.addMessage(nested)
+	}
+}
+
+// Files returns the indexed files
+func (ix *Index) Files() []*File {
+	return ix.files
+}
+
+// Message returns the message with the given full name, or nil
+func (ix *Index) Message(fullName string) *Message {
+	return ix.messages[strings.TrimPrefix(fullName, ".")]
+}
+
+// Enum returns the enum with the given full name, or nil
+func (ix *Index) Enum(fullName string) *Enum {
+	return ix.enums[strings.TrimPrefix(fullName, ".")]
+}
+
+// FindMessages returns the messages matching name, which is either a full
+// name or a short name compared case-insensitively. Results are sorted by
+// full name.
+func (ix *Index) FindMessages(name string) []*Message {
+	name = strings.TrimPrefix(name, ".")
+	if m := ix.messages[name]; m != nil {
+		return []*Message{m}
+	}
+
+	var matches []*Message
+	for fullName, m := range ix.messages {
+		if strings.EqualFold(m.Name, name) || strings.EqualFold(fullName, name) {
+			matches = append(matches, m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].FullName < matches[j].FullName
+	})
+	return matches
+}
+
+// ResolveType resolves a type name as written in scope (the full name of the
+// enclosing message) following protobuf scoping rules, innermost scope first
+func (ix *Index) ResolveType(scope, name string) (TypeKind, string) {
+	if IsScalar(name) {
+		return KindScalar, name
+	}
+	if strings.HasPrefix(name, ".") {
+		return ix.lookup(name[1:])
+	}
+
+	for s := scope; ; {
+		candidate := qualify(s, name)
+		if kind, fullName := ix.lookup(candidate); kind != KindUnknown {
+			return kind, fullName
+		}
+		if s == "" {
+			break
+		}
+		if i := strings.LastIndex(s, "."); i >= 0 {
+			s = s[:i]
+		} else {
+			s = ""
+		}
+	}
+	return KindUnknown, name
+}
+
+func (ix *Index) lookup(fullName string) (TypeKind, string) {
+	if _, ok := ix.messages[fullName]; ok {
+		return KindMessage, fullName
+	}
+	if _, ok := ix.enums[fullName]; ok {
+		return KindEnum, fullName
+	}
+	return KindUnknown, fullName
+}
+
+// FieldType resolves the type of a field of m. For map fields the value type
+// is resolved.
+func (ix *Index) FieldType(m *Message, f *Field) (TypeKind, string) {
+	if f.IsMap() {
+		return ix.ResolveType(m.FullName, f.MapValue)
+	}
+	return ix.ResolveType(m.FullName, f.Type)
+}
+
+// ServicesFor returns the services that own a message: those with an RPC
+// that returns it (directly or as a long-running operation response), takes
+// it as input, or wraps it in its request or response, e.g.
+// CreateNetworkRequest.network
+func (ix *Index) ServicesFor(m *Message) []*Service {
+	var owners []*Service
+	for _, svc := range ix.services {
+		if svc.File.Package != m.File.Package {
+			continue
+		}
+		for _, method := range svc.Methods {
+			if ix.methodUses(svc, method, m) {
+				owners = append(owners, svc)
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// methodUses reports whether an RPC reads or returns message m
+func (ix *Index) methodUses(svc *Service, method *Method, m *Message) bool {
+	scope := svc.File.Package
+	for _, typ := range []string{method.InputType, method.OutputType, method.LROResponseType} {
+		if typ == "" {
+			continue
+		}
+		if _, fullName := ix.ResolveType(scope, typ); fullName == m.FullName {
+			return true
+		}
+	}
+
+	// Request and response wrappers, e.g. CreateNetworkRequest or
+	// ListNetworksResponse
+	for _, typ := range []string{method.InputType, method.OutputType} {
+		_, wrapperName := ix.ResolveType(scope, typ)
+		wrapper := ix.Message(wrapperName)
+		if wrapper == nil {
+			continue
+		}
+		for _, f := range wrapper.Fields {
+			if _, fullName := ix.FieldType(wrapper, f); fullName == m.FullName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Cache keeps parsed files in memory and re-parses a file only when its size
+// or modification time changes
+type Cache struct {
+	mu    sync.Mutex
+	files map[string]*cachedFile
+}
+
+type cachedFile struct {
+	modTime time.Time
+	size    int64
+	file    *File
+}
+
+// NewCache creates an empty parse cache
+func NewCache() *Cache {
+	return &Cache{files: make(map[string]*cachedFile)}
+}
+
+// Load parses the file at path, reusing the previous result if the file has
+// not changed. name is recorded as the File's Path.
+func (c *Cache) Load(path, name string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(name, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.files[path] = &cachedFile{modTime: info.ModTime(), size: info.Size(), file: f}
+	c.mu.Unlock()
+	return f, nil
+}
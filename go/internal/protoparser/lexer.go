@@ -0,0 +1,194 @@
+package protoparser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind classifies a lexical token of a .proto file
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+// token is a single lexical token
+type token struct {
+	kind     tokenKind
+	text     string // identifier, number or symbol text; unquoted string value
+	line     int
+	quote    bool   // string literal
+	leading  string // comment block directly above the token
+	trailing string // comment on the same line after the token
+}
+
+// lexer accumulates tokens and attaches comments to them: a comment that
+// starts on the line of the previous token trails it, and a comment block
+// that ends on the line above a token leads it
+type lexer struct {
+	tokens     []token
+	pending    []string
+	pendingEnd int
+}
+
+func (l *lexer) comment(text string, startLine, endLine int) {
+	if last := len(l.tokens) - 1; last >= 0 && l.tokens[last].line == startLine && l.pending == nil {
+		if l.tokens[last].trailing != "" {
+			l.tokens[last].trailing += "\n"
+		}
+		l.tokens[last].trailing += text
+		return
+	}
+	if l.pending != nil && l.pendingEnd < startLine-1 {
+		l.pending = nil // detached by a blank line
+	}
+	l.pending = append(l.pending, text)
+	l.pendingEnd = endLine
+}
+
+func (l *lexer) add(t token) {
+	if l.pending != nil && l.pendingEnd >= t.line-1 {
+		t.leading = strings.Join(l.pending, "\n")
+	}
+	l.pending = nil
+	l.tokens = append(l.tokens, t)
+}
+
+// lex splits a .proto source into tokens, dropping whitespace and attaching
+// comments to the neighbouring tokens. Adjacent string literals are
+// concatenated as in the protobuf language.
+func lex(src string) ([]token, error) {
+	l := &lexer{}
+	line := 1
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			start := i
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			l.comment(lineCommentText(src[start:i]), line, line)
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated block comment", line)
+			}
+			body := src[i+2 : i+2+end]
+			startLine := line
+			line += strings.Count(body, "\n")
+			l.comment(blockCommentText(body), startLine, line)
+			i += end + 4
+
+		case c == '"' || c == '\'':
+			value, n, err := unquote(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if last := len(l.tokens) - 1; last >= 0 && l.tokens[last].kind == tokenString {
+				l.tokens[last].text += value
+			} else {
+				l.add(token{kind: tokenString, text: value, line: line, quote: true})
+			}
+			i += n
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.') {
+				i++
+			}
+			l.add(token{kind: tokenIdent, text: src[start:i], line: line})
+
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			start := i
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			l.add(token{kind: tokenNumber, text: src[start:i], line: line})
+
+		case c == '.':
+			// Fully-qualified type reference, e.g. ".google.protobuf.Empty"
+			start := i
+			i++
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.') {
+				i++
+			}
+			l.add(token{kind: tokenIdent, text: src[start:i], line: line})
+
+		default:
+			l.add(token{kind: tokenSymbol, text: string(c), line: line})
+			i++
+		}
+	}
+	l.add(token{kind: tokenEOF, line: line})
+	return l.tokens, nil
+}
+
+// lineCommentText strips the // marker and one following space
+func lineCommentText(comment string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(comment, "//"), " ")
+}
+
+// blockCommentText strips the leading " * " decoration of block comment lines
+func blockCommentText(body string) string {
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		lines[i] = strings.TrimPrefix(strings.TrimPrefix(line, "*"), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unquote decodes the string literal at the start of s and returns its value
+// and length in bytes
+func unquote(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\n':
+			return "", 0, fmt.Errorf("unterminated string literal")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c))
+}
+
+func isIdentPart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -0,0 +1,846 @@
+// Package protoparser parses the subset of the protobuf language needed to
+// inspect the vendored googleapis definitions: packages, imports, messages,
+// enums, fields, oneofs, maps, services, options and comments. The
+// google.api annotations KCC relies on (field_behavior, resource,
+// resource_reference, http) are interpreted. It does not type-check.
+package protoparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// File is a parsed .proto file
+type File struct {
+	Path     string     `json:"path"`
+	Syntax   string     `json:"syntax"`
+	Package  string     `json:"package"`
+	Imports  []string   `json:"imports"`
+	Options  []Option   `json:"options,omitempty"`
+	Messages []*Message `json:"messages"`
+	Enums    []*Enum    `json:"enums"`
+	Services []*Service `json:"services"`
+
+	// ResourceDefinitions are file-level google.api.resource_definition options
+	ResourceDefinitions []Resource `json:"resource_definitions,omitempty"`
+}
+
+// Message is a message declaration, possibly nested
+type Message struct {
+	Name     string     `json:"name"`
+	FullName string     `json:"full_name"`
+	Comment  string     `json:"comment,omitempty"`
+	Resource *Resource  `json:"resource,omitempty"`
+	Fields   []*Field   `json:"fields"`
+	Oneofs   []string   `json:"oneofs,omitempty"`
+	Options  []Option   `json:"options,omitempty"`
+	Messages []*Message `json:"messages,omitempty"`
+	Enums    []*Enum    `json:"enums,omitempty"`
+	File     *File      `json:"-"`
+}
+
+// Field is a message field. Map fields have MapKey and MapValue set and Type
+// left empty.
+type Field struct {
+	Name     string   `json:"name"`
+	Number   int      `json:"number"`
+	Label    string   `json:"label,omitempty"` // "optional", "repeated", "required", or ""
+	Type     string   `json:"type,omitempty"`  // as written, e.g. "string" or "google.protobuf.Timestamp"
+	MapKey   string   `json:"map_key,omitempty"`
+	MapValue string   `json:"map_value,omitempty"`
+	Oneof    string   `json:"oneof,omitempty"`
+	Comment  string   `json:"comment,omitempty"`
+	Options  []Option `json:"options,omitempty"`
+
+	FieldBehavior     []string           `json:"field_behavior,omitempty"` // e.g. REQUIRED, OUTPUT_ONLY, IMMUTABLE
+	ResourceReference *ResourceReference `json:"resource_reference,omitempty"`
+	Deprecated        bool               `json:"deprecated,omitempty"`
+}
+
+// Enum is an enum declaration
+type Enum struct {
+	Name     string      `json:"name"`
+	FullName string      `json:"full_name"`
+	Comment  string      `json:"comment,omitempty"`
+	Values   []EnumValue `json:"values"`
+}
+
+// EnumValue is a single enum constant
+type EnumValue struct {
+	Name    string `json:"name"`
+	Number  int    `json:"number"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Service is a service declaration
+type Service struct {
+	Name     string    `json:"name"`
+	FullName string    `json:"full_name"`
+	Comment  string    `json:"comment,omitempty"`
+	Options  []Option  `json:"options,omitempty"`
+	Methods  []*Method `json:"methods"`
+	File     *File     `json:"-"`
+}
+
+// Method is an RPC of a service
+type Method struct {
+	Name            string   `json:"name"`
+	Comment         string   `json:"comment,omitempty"`
+	InputType       string   `json:"input_type"`
+	OutputType      string   `json:"output_type"`
+	ClientStreaming bool     `json:"client_streaming,omitempty"`
+	ServerStreaming bool     `json:"server_streaming,omitempty"`
+	HTTPMethod      string   `json:"http_method,omitempty"` // from google.api.http, e.g. "get"
+	HTTPPath        string   `json:"http_path,omitempty"`
+	HTTPBody        string   `json:"http_body,omitempty"`
+	LROResponseType string   `json:"lro_response_type,omitempty"` // from google.longrunning.operation_info
+	LROMetadataType string   `json:"lro_metadata_type,omitempty"`
+	Options         []Option `json:"options,omitempty"`
+}
+
+// Resource is a google.api.resource annotation
+type Resource struct {
+	Type     string   `json:"type"`
+	Patterns []string `json:"patterns"`
+	Plural   string   `json:"plural,omitempty"`
+	Singular string   `json:"singular,omitempty"`
+}
+
+// ResourceReference is a google.api.resource_reference annotation
+type ResourceReference struct {
+	Type      string `json:"type,omitempty"`
+	ChildType string `json:"child_type,omitempty"`
+}
+
+// Option is an option assignment. Aggregate values such as
+// `{ type: "x" pattern: "y" }` are flattened into Fields with dotted keys.
+type Option struct {
+	Name   string              `json:"name"` // e.g. "deprecated" or "(google.api.field_behavior)"
+	Value  string              `json:"value,omitempty"`
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+// IsMap reports whether the field is a map field
+func (f *Field) IsMap() bool {
+	return f.MapValue != ""
+}
+
+// IsRepeated reports whether the field is a repeated field
+func (f *Field) IsRepeated() bool {
+	return f.Label == "repeated"
+}
+
+// HasBehavior reports whether the field is annotated with the given
+// google.api.field_behavior, e.g. "OUTPUT_ONLY"
+func (f *Field) HasBehavior(behavior string) bool {
+	for _, b := range f.FieldBehavior {
+		if b == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// OptionValues returns the values of every option named name, in order.
+// List values such as `[A, B]` are expanded.
+func OptionValues(options []Option, name string) []string {
+	var values []string
+	for _, o := range options {
+		if o.Name == name {
+			values = append(values, strings.Split(o.Value, ",")...)
+		}
+	}
+	return values
+}
+
+// optionFields returns the aggregate fields of the first option named name
+func optionFields(options []Option, name string) map[string][]string {
+	for _, o := range options {
+		if o.Name == name && o.Fields != nil {
+			return o.Fields
+		}
+	}
+	return nil
+}
+
+// first returns the first value of an aggregate field, or ""
+func first(fields map[string][]string, key string) string {
+	if values := fields[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// resourceFromOption interprets a google.api.resource aggregate
+func resourceFromOption(fields map[string][]string) Resource {
+	return Resource{
+		Type:     first(fields, "type"),
+		Patterns: fields["pattern"],
+		Plural:   first(fields, "plural"),
+		Singular: first(fields, "singular"),
+	}
+}
+
+// Parse parses the contents of a .proto file
+func Parse(path, src string) (*File, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	p := &parser{tokens: tokens}
+	f := &File{Path: path, Syntax: "proto2", Imports: []string{}, Messages: []*Message{}, Enums: []*Enum{}, Services: []*Service{}}
+	if err := p.parseFile(f); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", path, p.peek().line, err)
+	}
+	return f, nil
+}
+
+// parser is a recursive-descent parser over the token stream
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// prev returns the most recently consumed token
+func (p *parser) prev() token {
+	if p.pos == 0 {
+		return token{}
+	}
+	return p.tokens[p.pos-1]
+}
+
+// commentOf returns the leading comment of a token, else its trailing one
+func commentOf(t token) string {
+	if t.leading != "" {
+		return t.leading
+	}
+	return t.trailing
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the symbol or keyword s
+func (p *parser) accept(s string) bool {
+	if t := p.peek(); t.kind != tokenString && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return fmt.Errorf("expected %q, found %q", s, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) expectIdent() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return "", fmt.Errorf("expected identifier, found %q", t.text)
+	}
+	return t.text, nil
+}
+
+func (p *parser) expectInt() (int, error) {
+	negative := p.accept("-")
+	t := p.next()
+	if t.kind != tokenNumber {
+		return 0, fmt.Errorf("expected number, found %q", t.text)
+	}
+	n, err := strconv.ParseInt(t.text, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", t.text)
+	}
+	if negative {
+		n = -n
+	}
+	return int(n), nil
+}
+
+func (p *parser) parseFile(f *File) error {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenEOF:
+			return nil
+
+		case p.accept(";"):
+
+		case p.accept("syntax"), p.accept("edition"):
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			f.Syntax = p.next().text
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+
+		case p.accept("package"):
+			name, err := p.expectIdent()
+			if err != nil {
+				return err
+			}
+			f.Package = name
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+
+		case p.accept("import"):
+			if !p.accept("public") {
+				p.accept("weak")
+			}
+			path := p.next()
+			if !path.quote {
+				return fmt.Errorf("expected import path, found %q", path.text)
+			}
+			f.Imports = append(f.Imports, path.text)
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+
+		case p.accept("option"):
+			opt, err := p.parseOptionStatement()
+			if err != nil {
+				return err
+			}
+			f.Options = append(f.Options, opt)
+			if opt.Name == "(google.api.resource_definition)" && opt.Fields != nil {
+				f.ResourceDefinitions = append(f.ResourceDefinitions, resourceFromOption(opt.Fields))
+			}
+
+		case p.accept("message"):
+			m, err := p.parseMessage(f, f.Package)
+			if err != nil {
+				return err
+			}
+			f.Messages = append(f.Messages, m)
+
+		case p.accept("enum"):
+			e, err := p.parseEnum(f.Package)
+			if err != nil {
+				return err
+			}
+			f.Enums = append(f.Enums, e)
+
+		case p.accept("service"):
+			svc, err := p.parseService(f)
+			if err != nil {
+				return err
+			}
+			f.Services = append(f.Services, svc)
+
+		case p.accept("extend"):
+			if _, err := p.expectIdent(); err != nil {
+				return err
+			}
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
+
+func (p *parser) parseMessage(f *File, scope string) (*Message, error) {
+	comment := commentOf(p.prev())
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Name: name, FullName: qualify(scope, name), Comment: comment, Fields: []*Field{}, File: f}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseMessageBody(f, m, ""); err != nil {
+		return nil, err
+	}
+	if fields := optionFields(m.Options, "(google.api.resource)"); fields != nil {
+		r := resourceFromOption(fields)
+		m.Resource = &r
+	}
+	return m, nil
+}
+
+// parseMessageBody parses declarations up to and including the closing brace.
+// Inside a oneof, oneof names the group the fields belong to.
+func (p *parser) parseMessageBody(f *File, m *Message, oneof string) error {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("unexpected end of file in message %s", m.Name)
+
+		case p.accept("}"):
+			return nil
+
+		case p.accept(";"):
+
+		case p.accept("option"):
+			opt, err := p.parseOptionStatement()
+			if err != nil {
+				return err
+			}
+			if oneof == "" {
+				m.Options = append(m.Options, opt)
+			}
+
+		case oneof == "" && p.accept("message"):
+			nested, err := p.parseMessage(f, m.FullName)
+			if err != nil {
+				return err
+			}
+			m.Messages = append(m.Messages, nested)
+
+		case oneof == "" && p.accept("enum"):
+			e, err := p.parseEnum(m.FullName)
+			if err != nil {
+				return err
+			}
+			m.Enums = append(m.Enums, e)
+
+		case oneof == "" && p.accept("oneof"):
+			name, err := p.expectIdent()
+			if err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			m.Oneofs = append(m.Oneofs, name)
+			if err := p.parseMessageBody(f, m, name); err != nil {
+				return err
+			}
+
+		case p.accept("reserved"), p.accept("extensions"):
+			p.skipStatement()
+
+		case p.accept("extend"):
+			if _, err := p.expectIdent(); err != nil {
+				return err
+			}
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+
+		default:
+			start := p.peek()
+			field, err := p.parseField()
+			if err != nil {
+				return err
+			}
+			field.Oneof = oneof
+			field.Comment = start.leading
+			if field.Comment == "" {
+				field.Comment = p.prev().trailing
+			}
+			m.Fields = append(m.Fields, field)
+		}
+	}
+}
+
+func (p *parser) parseField() (*Field, error) {
+	field := &Field{}
+
+	if p.accept("map") {
+		if err := p.expect("<"); err != nil {
+			return nil, err
+		}
+		key, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(">"); err != nil {
+			return nil, err
+		}
+		field.MapKey, field.MapValue = key, value
+	} else {
+		typ, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "optional", "repeated", "required":
+			field.Label = typ
+			if typ, err = p.expectIdent(); err != nil {
+				return nil, err
+			}
+		}
+		field.Type = typ
+	}
+
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	field.Name = name
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	if field.Number, err = p.expectInt(); err != nil {
+		return nil, err
+	}
+
+	if p.accept("[") {
+		for {
+			opt, err := p.parseOption()
+			if err != nil {
+				return nil, err
+			}
+			field.Options = append(field.Options, opt)
+			if p.accept("]") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	field.FieldBehavior = OptionValues(field.Options, "(google.api.field_behavior)")
+	if fields := optionFields(field.Options, "(google.api.resource_reference)"); fields != nil {
+		field.ResourceReference = &ResourceReference{Type: first(fields, "type"), ChildType: first(fields, "child_type")}
+	}
+	for _, v := range OptionValues(field.Options, "deprecated") {
+		field.Deprecated = v == "true"
+	}
+	return field, nil
+}
+
+func (p *parser) parseEnum(scope string) (*Enum, error) {
+	comment := commentOf(p.prev())
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	e := &Enum{Name: name, FullName: qualify(scope, name), Comment: comment, Values: []EnumValue{}}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().kind == tokenEOF:
+			return nil, fmt.Errorf("unexpected end of file in enum %s", name)
+		case p.accept("}"):
+			return e, nil
+		case p.accept(";"):
+		case p.accept("option"), p.accept("reserved"):
+			p.skipStatement()
+		default:
+			start := p.peek()
+			valueName, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			number, err := p.expectInt()
+			if err != nil {
+				return nil, err
+			}
+			if p.accept("[") {
+				p.skipUntil("]")
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			comment := start.leading
+			if comment == "" {
+				comment = p.prev().trailing
+			}
+			e.Values = append(e.Values, EnumValue{Name: valueName, Number: number, Comment: comment})
+		}
+	}
+}
+
+func (p *parser) parseService(f *File) (*Service, error) {
+	comment := commentOf(p.prev())
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, err
+	}
+	svc := &Service{Name: name, FullName: qualify(f.Package, name), Comment: comment, Methods: []*Method{}, File: f}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().kind == tokenEOF:
+			return nil, fmt.Errorf("unexpected end of file in service %s", name)
+		case p.accept("}"):
+			return svc, nil
+		case p.accept(";"):
+		case p.accept("option"):
+			opt, err := p.parseOptionStatement()
+			if err != nil {
+				return nil, err
+			}
+			svc.Options = append(svc.Options, opt)
+		case p.accept("rpc"):
+			m, err := p.parseMethod()
+			if err != nil {
+				return nil, err
+			}
+			svc.Methods = append(svc.Methods, m)
+		default:
+			return nil, fmt.Errorf("unexpected %q in service %s", p.peek().text, name)
+		}
+	}
+}
+
+// parseMethod parses `Name(Input) returns (Output)` followed by either a
+// semicolon or a block of options
+func (p *parser) parseMethod() (*Method, error) {
+	m := &Method{Comment: commentOf(p.prev())}
+	var err error
+	if m.Name, err = p.expectIdent(); err != nil {
+		return nil, err
+	}
+	if m.ClientStreaming, m.InputType, err = p.parseMethodType(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("returns"); err != nil {
+		return nil, err
+	}
+	if m.ServerStreaming, m.OutputType, err = p.parseMethodType(); err != nil {
+		return nil, err
+	}
+
+	if p.accept("{") {
+		for !p.accept("}") {
+			switch {
+			case p.peek().kind == tokenEOF:
+				return nil, fmt.Errorf("unexpected end of file in rpc %s", m.Name)
+			case p.accept(";"):
+			case p.accept("option"):
+				opt, err := p.parseOptionStatement()
+				if err != nil {
+					return nil, err
+				}
+				m.Options = append(m.Options, opt)
+			default:
+				return nil, fmt.Errorf("unexpected %q in rpc %s", p.peek().text, m.Name)
+			}
+		}
+	} else if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	if http := optionFields(m.Options, "(google.api.http)"); http != nil {
+		for _, verb := range []string{"get", "post", "put", "patch", "delete"} {
+			if path := first(http, verb); path != "" {
+				m.HTTPMethod, m.HTTPPath = verb, path
+				break
+			}
+		}
+		m.HTTPBody = first(http, "body")
+	}
+	if lro := optionFields(m.Options, "(google.longrunning.operation_info)"); lro != nil {
+		m.LROResponseType = first(lro, "response_type")
+		m.LROMetadataType = first(lro, "metadata_type")
+	}
+	return m, nil
+}
+
+// parseMethodType parses `([stream] Type)`
+func (p *parser) parseMethodType() (bool, string, error) {
+	if err := p.expect("("); err != nil {
+		return false, "", err
+	}
+	stream := false
+	if p.peek().text == "stream" && p.tokens[p.pos+1].text != ")" {
+		p.next()
+		stream = true
+	}
+	typ, err := p.expectIdent()
+	if err != nil {
+		return false, "", err
+	}
+	return stream, typ, p.expect(")")
+}
+
+// parseOptionStatement parses `name = value;` after the option keyword
+func (p *parser) parseOptionStatement() (Option, error) {
+	opt, err := p.parseOption()
+	if err != nil {
+		return opt, err
+	}
+	return opt, p.expect(";")
+}
+
+// parseOption parses `name = value`, where name may contain parenthesised
+// extension names such as `(google.api.http).get`
+func (p *parser) parseOption() (Option, error) {
+	var name strings.Builder
+	for !p.accept("=") {
+		t := p.next()
+		if t.kind == tokenEOF || t.text == ";" || t.text == "]" {
+			return Option{}, fmt.Errorf("malformed option %q", name.String())
+		}
+		name.WriteString(t.text)
+	}
+
+	opt := Option{Name: name.String()}
+	if p.peek().text == "{" && !p.peek().quote {
+		p.next()
+		opt.Fields = make(map[string][]string)
+		if err := p.parseAggregate("", opt.Fields); err != nil {
+			return opt, err
+		}
+		return opt, nil
+	}
+
+	if p.accept("[") {
+		var values []string
+		for !p.accept("]") {
+			value, err := p.parseScalar()
+			if err != nil {
+				return opt, err
+			}
+			values = append(values, value)
+			p.accept(",")
+		}
+		opt.Value = strings.Join(values, ",")
+		return opt, nil
+	}
+
+	value, err := p.parseScalar()
+	opt.Value = value
+	return opt, err
+}
+
+// parseAggregate parses a text-format message literal up to and including
+// its closing brace, recording scalar values under dotted keys
+func (p *parser) parseAggregate(prefix string, fields map[string][]string) error {
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("unterminated option value")
+		case !t.quote && t.text == "}":
+			return nil
+		case !t.quote && (t.text == "," || t.text == ";"):
+			continue
+		case t.kind != tokenIdent:
+			return fmt.Errorf("unexpected %q in option value", t.text)
+		}
+
+		key := t.text
+		if prefix != "" {
+			key = prefix + "." + t.text
+		}
+		p.accept(":")
+
+		switch {
+		case p.accept("{"):
+			if err := p.parseAggregate(key, fields); err != nil {
+				return err
+			}
+		case p.accept("["):
+			for !p.accept("]") {
+				if p.accept("{") {
+					if err := p.parseAggregate(key, fields); err != nil {
+						return err
+					}
+				} else {
+					value, err := p.parseScalar()
+					if err != nil {
+						return err
+					}
+					fields[key] = append(fields[key], value)
+				}
+				p.accept(",")
+			}
+		default:
+			value, err := p.parseScalar()
+			if err != nil {
+				return err
+			}
+			fields[key] = append(fields[key], value)
+		}
+	}
+}
+
+// parseScalar parses a constant: identifier, number, string or boolean
+func (p *parser) parseScalar() (string, error) {
+	sign := ""
+	if p.accept("-") {
+		sign = "-"
+	} else {
+		p.accept("+")
+	}
+	t := p.next()
+	if t.kind == tokenEOF || (t.kind == tokenSymbol && !t.quote) {
+		return "", fmt.Errorf("expected constant, found %q", t.text)
+	}
+	return sign + t.text, nil
+}
+
+// skipStatement skips tokens up to and including the next semicolon
+func (p *parser) skipStatement() {
+	p.skipUntil(";")
+}
+
+// skipUntil skips tokens up to and including the symbol s
+func (p *parser) skipUntil(s string) {
+	for {
+		t := p.next()
+		if t.kind == tokenEOF || (!t.quote && t.text == s) {
+			return
+		}
+	}
+}
+
+// skipBlock skips a brace-delimited block, including nested blocks
+func (p *parser) skipBlock() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		t := p.next()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("unterminated block")
+		case t.quote:
+		case t.text == "{":
+			depth++
+		case t.text == "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+// qualify joins a scope and a name into a full name
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
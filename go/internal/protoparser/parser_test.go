@@ -0,0 +1,483 @@
+package protoparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProto = `// Copyright header, detached from the syntax statement
+
+syntax = "proto3";
+
+package google.cloud.widgets.v1;
+
+import "google/api/annotations.proto";
+import public "google/api/field_behavior.proto";
+import "google/longrunning/operations.proto";
+
+option go_package = "cloud.google.com/go/widgets/apiv1/widgetspb;widgetspb";
+option (google.api.resource_definition) = {
+  type: "compute.googleapis.com/Network"
+  pattern: "projects/{project}/global/networks/{network}"
+};
+
+// Manages widgets.
+service WidgetService {
+  option (google.api.default_host) = "widgets.googleapis.com";
+
+  // Gets a widget.
+  rpc GetWidget(GetWidgetRequest) returns (Widget) {
+    option (google.api.http) = {
+      get: "/v1/{name=projects/*/locations/*/widgets/*}"
+    };
+    option (google.api.method_signature) = "name";
+  }
+
+  // Creates a widget.
+  rpc CreateWidget(CreateWidgetRequest) returns (google.longrunning.Operation) {
+    option (google.api.http) = {
+      post: "/v1/{parent=projects/*/locations/*}/widgets"
+      body: "widget"
+      additional_bindings { post: "/v1/{parent=projects/*}/widgets" body: "*" }
+    };
+    option (google.longrunning.operation_info) = {
+      response_type: "Widget"
+      metadata_type: "OperationMetadata"
+    };
+  }
+
+  rpc WatchWidgets(stream GetWidgetRequest) returns (stream Widget);
+}
+
+/*
+ * A widget.
+ * Second line.
+ */
+message Widget {
+  option (google.api.resource) = {
+    type: "widgets.googleapis.com/Widget"
+    pattern: "projects/{project}/locations/{location}/widgets/{widget}"
+    pattern: "organizations/{organization}/widgets/{widget}"
+    plural: "widgets"
+    singular: "widget"
+  };
+
+  // The state of a widget.
+  enum State {
+    STATE_UNSPECIFIED = 0;
+    ACTIVE = 1; // Ready for use.
+    DELETING = -1 [deprecated = true];
+  }
+
+  // Settings of a widget.
+  message Config {
+    message Inner {
+      int32 depth = 1;
+    }
+    bool enabled = 1;
+    Inner inner = 2;
+  }
+
+  // Identifier. The resource name.
+  string name = 1 [(google.api.field_behavior) = IDENTIFIER];
+
+  string display_name = 2 [
+    (google.api.field_behavior) = REQUIRED,
+    (google.api.field_behavior) = IMMUTABLE
+  ]; // Shown in the console.
+
+  State state = 3 [(google.api.field_behavior) = OUTPUT_ONLY];
+
+  string network = 4 [
+    (google.api.resource_reference) = { type: "compute.googleapis.com/Network" }
+  ];
+
+  string parent = 5 [(google.api.resource_reference).child_type = "widgets.googleapis.com/Widget"];
+
+  repeated string tags = 6 [(google.api.field_behavior) = OPTIONAL, deprecated = true];
+
+  map<string, string> labels = 7;
+
+  optional int64 size_gb = 8 [json_name = "sizeGb"];
+
+  Config config = 9;
+
+  oneof source {
+    string image = 10;
+    .google.cloud.widgets.v1.Widget.Config template = 11;
+  }
+
+  reserved 12, 13 to 15;
+  reserved "old_field";
+
+  repeated int32 flags = 16 [(google.api.field_behavior) = OPTIONAL, (google.api.field_behavior) = OUTPUT_ONLY];
+  string etag = 17 [(google.api.field_behavior) = {}];
+}
+
+enum Color {
+  option allow_alias = true;
+  COLOR_UNSPECIFIED = 0;
+  RED = 1;
+  CRIMSON = 1;
+}
+
+message GetWidgetRequest {
+  string name = 1 [
+    (google.api.field_behavior) = REQUIRED,
+    (google.api.resource_reference) = {
+      type: "widgets.googleapis.com/Widget"
+    }
+  ];
+}
+
+message CreateWidgetRequest {
+  string parent = 1;
+  string widget_id = 2 [(google.api.field_behavior) = REQUIRED];
+  Widget widget = 3 [(google.api.field_behavior) = REQUIRED];
+}
+
+message OperationMetadata {
+  string verb = 1;
+}
+`
+
+func parseTestProto(t *testing.T) *File {
+	t.Helper()
+	f, err := Parse("widgets.proto", testProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseFile(t *testing.T) {
+	f := parseTestProto(t)
+
+	if f.Syntax != "proto3" || f.Package != "google.cloud.widgets.v1" {
+		t.Errorf("syntax, package = %q, %q", f.Syntax, f.Package)
+	}
+	wantImports := []string{"google/api/annotations.proto", "google/api/field_behavior.proto", "google/longrunning/operations.proto"}
+	if !reflect.DeepEqual(f.Imports, wantImports) {
+		t.Errorf("imports = %q", f.Imports)
+	}
+	if got := OptionValues(f.Options, "go_package"); len(got) != 1 || got[0] != "cloud.google.com/go/widgets/apiv1/widgetspb;widgetspb" {
+		t.Errorf("go_package = %q", got)
+	}
+	wantDefs := []Resource{{
+		Type:     "compute.googleapis.com/Network",
+		Patterns: []string{"projects/{project}/global/networks/{network}"},
+	}}
+	if !reflect.DeepEqual(f.ResourceDefinitions, wantDefs) {
+		t.Errorf("resource definitions = %+v", f.ResourceDefinitions)
+	}
+
+	var names []string
+	for _, m := range f.Messages {
+		names = append(names, m.FullName)
+	}
+	want := []string{
+		"google.cloud.widgets.v1.Widget",
+		"google.cloud.widgets.v1.GetWidgetRequest",
+		"google.cloud.widgets.v1.CreateWidgetRequest",
+		"google.cloud.widgets.v1.OperationMetadata",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("messages = %q", names)
+	}
+}
+
+func TestParseNested(t *testing.T) {
+	w := parseTestProto(t).Messages[0]
+
+	if len(w.Messages) != 1 || w.Messages[0].FullName != "google.cloud.widgets.v1.Widget.Config" {
+		t.Fatalf("nested messages = %+v", w.Messages)
+	}
+	config := w.Messages[0]
+	if config.Comment != "Settings of a widget." {
+		t.Errorf("nested message comment = %q", config.Comment)
+	}
+	if len(config.Messages) != 1 || config.Messages[0].FullName != "google.cloud.widgets.v1.Widget.Config.Inner" {
+		t.Errorf("doubly nested messages = %+v", config.Messages)
+	}
+	if len(config.Fields) != 2 || config.Fields[1].Type != "Inner" {
+		t.Errorf("nested message fields = %+v", config.Fields)
+	}
+
+	if len(w.Enums) != 1 {
+		t.Fatalf("nested enums = %+v", w.Enums)
+	}
+	state := w.Enums[0]
+	if state.FullName != "google.cloud.widgets.v1.Widget.State" || state.Comment != "The state of a widget." {
+		t.Errorf("enum = %s, comment %q", state.FullName, state.Comment)
+	}
+	wantValues := []EnumValue{
+		{Name: "STATE_UNSPECIFIED", Number: 0},
+		{Name: "ACTIVE", Number: 1, Comment: "Ready for use."},
+		{Name: "DELETING", Number: -1},
+	}
+	if !reflect.DeepEqual(state.Values, wantValues) {
+		t.Errorf("enum values = %+v", state.Values)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	w := parseTestProto(t).Messages[0]
+	fields := make(map[string]*Field)
+	for _, f := range w.Fields {
+		fields[f.Name] = f
+	}
+
+	tests := []struct {
+		name      string
+		number    int
+		label     string
+		typ       string
+		oneof     string
+		behavior  []string
+		reference *ResourceReference
+		comment   string
+	}{
+		{name: "name", number: 1, typ: "string", behavior: []string{"IDENTIFIER"}, comment: "Identifier. The resource name."},
+		{name: "display_name", number: 2, typ: "string", behavior: []string{"REQUIRED", "IMMUTABLE"}, comment: "Shown in the console."},
+		{name: "state", number: 3, typ: "State", behavior: []string{"OUTPUT_ONLY"}},
+		{name: "network", number: 4, typ: "string", reference: &ResourceReference{Type: "compute.googleapis.com/Network"}},
+		{name: "parent", number: 5, typ: "string"},
+		{name: "tags", number: 6, label: "repeated", typ: "string", behavior: []string{"OPTIONAL"}},
+		{name: "size_gb", number: 8, label: "optional", typ: "int64"},
+		{name: "config", number: 9, typ: "Config"},
+		{name: "image", number: 10, typ: "string", oneof: "source"},
+		{name: "template", number: 11, typ: ".google.cloud.widgets.v1.Widget.Config", oneof: "source"},
+		{name: "flags", number: 16, label: "repeated", typ: "int32", behavior: []string{"OPTIONAL", "OUTPUT_ONLY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := fields[tt.name]
+			if !ok {
+				t.Fatalf("field %s not parsed", tt.name)
+			}
+			if f.Number != tt.number || f.Label != tt.label || f.Type != tt.typ || f.Oneof != tt.oneof {
+				t.Errorf("got number %d, label %q, type %q, oneof %q", f.Number, f.Label, f.Type, f.Oneof)
+			}
+			if !reflect.DeepEqual(f.FieldBehavior, tt.behavior) {
+				t.Errorf("field behavior = %q, want %q", f.FieldBehavior, tt.behavior)
+			}
+			if !reflect.DeepEqual(f.ResourceReference, tt.reference) {
+				t.Errorf("resource reference = %+v, want %+v", f.ResourceReference, tt.reference)
+			}
+			if f.Comment != tt.comment {
+				t.Errorf("comment = %q, want %q", f.Comment, tt.comment)
+			}
+		})
+	}
+
+	if labels := fields["labels"]; !labels.IsMap() || labels.MapKey != "string" || labels.MapValue != "string" || labels.IsRepeated() {
+		t.Errorf("map field = %+v", labels)
+	}
+	if !fields["tags"].Deprecated || fields["name"].Deprecated {
+		t.Error("deprecated option not interpreted")
+	}
+	if !fields["tags"].IsRepeated() {
+		t.Error("repeated field not reported as repeated")
+	}
+	if got := OptionValues(fields["size_gb"].Options, "json_name"); len(got) != 1 || got[0] != "sizeGb" {
+		t.Errorf("json_name = %q", got)
+	}
+	if _, ok := fields["etag"]; !ok {
+		t.Error("field with an empty aggregate option not parsed")
+	}
+	if len(w.Fields) != 13 {
+		t.Errorf("got %d fields, want 13: reserved statements must not become fields", len(w.Fields))
+	}
+	if !reflect.DeepEqual(w.Oneofs, []string{"source"}) {
+		t.Errorf("oneofs = %q", w.Oneofs)
+	}
+
+	// A reference set through a dotted option name is kept as a plain option
+	if parent := fields["parent"]; len(parent.Options) != 1 || parent.Options[0].Name != "(google.api.resource_reference).child_type" {
+		t.Errorf("parent options = %+v", parent.Options)
+	}
+
+	req := parseTestProto(t).Messages[1].Fields[0]
+	if req.ResourceReference == nil || req.ResourceReference.Type != "widgets.googleapis.com/Widget" || !req.HasBehavior("REQUIRED") {
+		t.Errorf("request name field = %+v", req)
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	w := parseTestProto(t).Messages[0]
+
+	if w.Comment != "A widget.\nSecond line." {
+		t.Errorf("block comment = %q", w.Comment)
+	}
+	want := &Resource{
+		Type: "widgets.googleapis.com/Widget",
+		Patterns: []string{
+			"projects/{project}/locations/{location}/widgets/{widget}",
+			"organizations/{organization}/widgets/{widget}",
+		},
+		Plural:   "widgets",
+		Singular: "widget",
+	}
+	if !reflect.DeepEqual(w.Resource, want) {
+		t.Errorf("resource = %+v", w.Resource)
+	}
+	if parseTestProto(t).Messages[1].Resource != nil {
+		t.Error("resource reported for a message without google.api.resource")
+	}
+}
+
+func TestParseService(t *testing.T) {
+	f := parseTestProto(t)
+	if len(f.Services) != 1 {
+		t.Fatalf("services = %+v", f.Services)
+	}
+	svc := f.Services[0]
+	if svc.FullName != "google.cloud.widgets.v1.WidgetService" || svc.Comment != "Manages widgets." {
+		t.Errorf("service = %s, comment %q", svc.FullName, svc.Comment)
+	}
+	if got := OptionValues(svc.Options, "(google.api.default_host)"); len(got) != 1 || got[0] != "widgets.googleapis.com" {
+		t.Errorf("default host = %q", got)
+	}
+
+	want := []Method{
+		{
+			Name:       "GetWidget",
+			Comment:    "Gets a widget.",
+			InputType:  "GetWidgetRequest",
+			OutputType: "Widget",
+			HTTPMethod: "get",
+			HTTPPath:   "/v1/{name=projects/*/locations/*/widgets/*}",
+		},
+		{
+			Name:            "CreateWidget",
+			Comment:         "Creates a widget.",
+			InputType:       "CreateWidgetRequest",
+			OutputType:      "google.longrunning.Operation",
+			HTTPMethod:      "post",
+			HTTPPath:        "/v1/{parent=projects/*/locations/*}/widgets",
+			HTTPBody:        "widget",
+			LROResponseType: "Widget",
+			LROMetadataType: "OperationMetadata",
+		},
+		{
+			Name:            "WatchWidgets",
+			InputType:       "GetWidgetRequest",
+			OutputType:      "Widget",
+			ClientStreaming: true,
+			ServerStreaming: true,
+		},
+	}
+	if len(svc.Methods) != len(want) {
+		t.Fatalf("got %d methods, want %d", len(svc.Methods), len(want))
+	}
+	for i, w := range want {
+		got := *svc.Methods[i]
+		got.Options = nil
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("method %d:\n got %+v\nwant %+v", i, got, w)
+		}
+	}
+	if got := OptionValues(svc.Methods[0].Options, "(google.api.method_signature)"); len(got) != 1 || got[0] != "name" {
+		t.Errorf("method signature = %q", got)
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	src := `syntax = "proto3";
+package c;
+
+// Detached by a blank line.
+
+// Leading one.
+// Leading two.
+message M {
+  int32 a = 1; // Trailing a.
+
+  // Leading b.
+  int32 b = 2; // Trailing b is ignored when b has a leading comment.
+
+  /* Block c. */
+  int32 c = 3;
+  int32 d = 4;
+}
+`
+	f, err := Parse("c.proto", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := f.Messages[0]
+	if m.Comment != "Leading one.\nLeading two." {
+		t.Errorf("message comment = %q", m.Comment)
+	}
+	want := []string{"Trailing a.", "Leading b.", "Block c.", ""}
+	for i, field := range m.Fields {
+		if field.Comment != want[i] {
+			t.Errorf("field %s comment = %q, want %q", field.Name, field.Comment, want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unterminated message", "message M {\n  int32 a = 1;\n", "unexpected end of file in message M"},
+		{"missing field number", "message M {\n  int32 a;\n}", `widgets.proto:2: expected "="`},
+		{"unterminated comment", "/* open", "unterminated block comment"},
+		{"unterminated string", "option x = \"open\n;", "unterminated string literal"},
+		{"bad top-level token", "messages M {}", `unexpected "messages"`},
+		{"malformed option", "message M {\n  int32 a = 1 [deprecated];\n}", "malformed option"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("widgets.proto", tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexResolveType(t *testing.T) {
+	f := parseTestProto(t)
+	ix := NewIndex(f)
+	w := ix.Message("google.cloud.widgets.v1.Widget")
+	if w == nil {
+		t.Fatal("Widget not indexed")
+	}
+	if ix.Message("google.cloud.widgets.v1.Widget.Config.Inner") == nil {
+		t.Error("nested message not indexed")
+	}
+
+	fields := make(map[string]*Field)
+	for _, field := range w.Fields {
+		fields[field.Name] = field
+	}
+	tests := []struct {
+		field string
+		kind  TypeKind
+		name  string
+	}{
+		{"display_name", KindScalar, "string"},
+		{"state", KindEnum, "google.cloud.widgets.v1.Widget.State"},
+		{"config", KindMessage, "google.cloud.widgets.v1.Widget.Config"},
+		{"template", KindMessage, "google.cloud.widgets.v1.Widget.Config"},
+		{"labels", KindScalar, "string"},
+	}
+	for _, tt := range tests {
+		kind, name := ix.FieldType(w, fields[tt.field])
+		if kind != tt.kind || name != tt.name {
+			t.Errorf("FieldType(%s) = %s %s, want %s %s", tt.field, kind, name, tt.kind, tt.name)
+		}
+	}
+
+	if kind, _ := ix.ResolveType("google.cloud.widgets.v1", "google.protobuf.Timestamp"); kind != KindUnknown {
+		t.Errorf("unindexed type resolved as %s", kind)
+	}
+	if got := ix.FindMessages("widget"); len(got) != 1 || got[0] != w {
+		t.Errorf("FindMessages(widget) = %v", got)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups are not available; the
+// command itself is still killed on cancellation
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// waitDelay is how long a killed command gets to close its output before
+// Run stops waiting for it
+const waitDelay = 5 * time.Second
+
+// Stream names passed to a LineFunc
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// LineFunc receives each line a command writes, as it is written
+type LineFunc func(stream, line string)
+
+// Command is a subprocess run on behalf of a tool
+type Command struct {
+	Name    string
+	Args    []string
+	Dir     string
+	Env     []string      // full environment; nil inherits the server's
+	Timeout time.Duration // zero means no timeout beyond the caller's context
+	OnLine  LineFunc      // optional
+}
+
+// Options are the per-call settings tools pass down to their subprocesses
+type Options struct {
+	Timeout time.Duration
+	OnLine  LineFunc
+}
+
+// Error is returned when a command fails, times out or is cancelled. Output
+// holds whatever the command wrote before it stopped.
+type Error struct {
+	Command  string
+	Output   string
+	TimedOut bool
+	Canceled bool
+	Timeout  time.Duration
+	Err      error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.TimedOut:
+		return fmt.Sprintf("%s timed out after %s", e.Command, e.Timeout)
+	case e.Canceled:
+		return fmt.Sprintf("%s was cancelled", e.Command)
+	default:
+		return fmt.Sprintf("%s failed: %v", e.Command, e.Err)
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Interrupted reports whether the command was stopped by a timeout or a
+// cancellation rather than failing on its own
+func (e *Error) Interrupted() bool {
+	return e.TimedOut || e.Canceled
+}
+
+// Run runs the command and returns its combined stdout and stderr. The
+// command is killed, along with any children it started, when ctx is done
+// or the timeout expires. Every line of output is passed to OnLine as it
+// arrives, until ctx is done or the timeout expires.
+func Run(ctx context.Context, c Command) (string, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Env
+	cmd.WaitDelay = waitDelay
+	killProcessGroup(cmd)
+
+	out := newLineWriter(ctx, c.OnLine)
+	cmd.Stdout = out.stream(Stdout)
+	cmd.Stderr = out.stream(Stderr)
+
+	err := cmd.Run()
+	out.close()
+	if err == nil {
+		return out.String(), nil
+	}
+
+	runErr := &Error{
+		Command: strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " ")),
+		Output:  out.String(),
+		Timeout: c.Timeout,
+		Err:     err,
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		runErr.TimedOut = true
+	case errors.Is(ctx.Err(), context.Canceled):
+		runErr.Canceled = true
+	}
+	return runErr.Output, runErr
+}
+
+// lineBuffer is how many lines may wait for the LineFunc before the
+// command's writes block
+const lineBuffer = 256
+
+// outputLine is one line of output on its way to the LineFunc
+type outputLine struct {
+	stream, text string
+}
+
+// lineWriter collects the output of both streams in order and splits it
+// into lines. Lines are handed to the LineFunc by a single goroutine, in
+// order and outside the lock, and dropped once ctx is done.
+type lineWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	lines   chan outputLine // nil without a LineFunc, or once closed
+	done    chan struct{}   // closed when every line has been handled
+	pending map[string][]byte
+}
+
+func newLineWriter(ctx context.Context, onLine LineFunc) *lineWriter {
+	w := &lineWriter{}
+	if onLine != nil {
+		w.lines = make(chan outputLine, lineBuffer)
+		w.done = make(chan struct{})
+		w.pending = make(map[string][]byte)
+		go w.deliver(ctx, w.lines, onLine)
+	}
+	return w
+}
+
+// deliver passes lines to onLine until the writer is closed. Lines arriving
+// after ctx is done are drained without notifying, so writes never block on
+// a client that has gone away.
+func (w *lineWriter) deliver(ctx context.Context, lines <-chan outputLine, onLine LineFunc) {
+	defer close(w.done)
+	for line := range lines {
+		if ctx.Err() == nil {
+			onLine(line.stream, line.text)
+		}
+	}
+}
+
+// stream returns the writer for one of the command's output streams
+func (w *lineWriter) stream(name string) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.buf.Write(p)
+		if w.lines == nil {
+			return len(p), nil
+		}
+		data := append(w.pending[name], p...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			w.lines <- outputLine{name, strings.TrimRight(string(data[:i]), "\r")}
+			data = data[i+1:]
+		}
+		w.pending[name] = append([]byte(nil), data...)
+		return len(p), nil
+	})
+}
+
+// close passes on any final lines that did not end in a newline and waits
+// until every line has been handled, so the LineFunc is never called after
+// Run returns
+func (w *lineWriter) close() {
+	w.mu.Lock()
+	if w.lines == nil {
+		w.mu.Unlock()
+		return
+	}
+	for _, name := range []string{Stdout, Stderr} {
+		if line := w.pending[name]; len(line) > 0 {
+			w.lines <- outputLine{name, string(line)}
+		}
+	}
+	// Writes arriving later, after a WaitDelay expired, are only collected
+	close(w.lines)
+	w.lines, w.pending = nil, nil
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *lineWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,152 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a LineFunc that records the lines it receives
+type recorder struct {
+	mu    sync.Mutex
+	lines []string
+	after func(line string)
+}
+
+func (r *recorder) onLine(stream, line string) {
+	r.mu.Lock()
+	r.lines = append(r.lines, stream+": "+line)
+	r.mu.Unlock()
+	if r.after != nil {
+		r.after(line)
+	}
+}
+
+func (r *recorder) got() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+func sh(script string, onLine LineFunc) Command {
+	return Command{Name: "sh", Args: []string{"-c", script}, OnLine: onLine}
+}
+
+func TestRunLines(t *testing.T) {
+	r := &recorder{}
+	out, err := Run(context.Background(), sh(`printf 'one\r\ntwo\n'; sleep 0.1; echo warn >&2; sleep 0.1; printf partial`, r.onLine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one\r\ntwo\nwarn\npartial"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	want := []string{"stdout: one", "stdout: two", "stderr: warn", "stdout: partial"}
+	if got := r.got(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	out, err := Run(context.Background(), sh(`echo before; exit 3`, nil))
+	var runErr *Error
+	if !errors.As(err, &runErr) {
+		t.Fatalf("err = %v, want a runner Error", err)
+	}
+	if runErr.Interrupted() || runErr.Output != "before\n" || out != "before\n" {
+		t.Errorf("got interrupted=%t output=%q", runErr.Interrupted(), runErr.Output)
+	}
+	if !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := &recorder{}
+	c := sh(`echo started; sleep 10 & wait; echo never`, r.onLine)
+	c.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	out, err := Run(context.Background(), c)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Run took %s: the command and its children were not killed", elapsed)
+	}
+	var runErr *Error
+	if !errors.As(err, &runErr) || !runErr.TimedOut || runErr.Canceled {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("error = %v", err)
+	}
+	if out != "started\n" || runErr.Output != "started\n" {
+		t.Errorf("partial output = %q", out)
+	}
+	if got := r.got(); len(got) != 1 || got[0] != "stdout: started" {
+		t.Errorf("lines = %q", got)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel as soon as the first line arrives; the lines already written
+	// after it must not be passed on
+	r := &recorder{}
+	r.after = func(line string) {
+		if line == "one" {
+			cancel()
+		}
+	}
+	out, err := Run(ctx, sh(`echo one; echo two; echo three; sleep 10`, r.onLine))
+
+	var runErr *Error
+	if !errors.As(err, &runErr) || !runErr.Canceled || runErr.TimedOut || !runErr.Interrupted() {
+		t.Fatalf("err = %v, want a cancellation", err)
+	}
+	if !strings.HasPrefix(out, "one\n") {
+		t.Errorf("partial output = %q", out)
+	}
+	if got := r.got(); len(got) != 1 || got[0] != "stdout: one" {
+		t.Errorf("lines after cancellation were passed on: %q", got)
+	}
+}
+
+func TestRunSlowLineFunc(t *testing.T) {
+	// The LineFunc is never called concurrently, sees every line in order,
+	// and is done when Run returns
+	var (
+		calls, active, last int
+		overlap, outOfOrder bool
+	)
+	onLine := func(stream, line string) {
+		active++
+		if active > 1 {
+			overlap = true
+		}
+		time.Sleep(time.Millisecond)
+		var n int
+		fmt.Sscan(line, &n)
+		if stream == Stdout {
+			if n != last+1 {
+				outOfOrder = true
+			}
+			last = n
+		}
+		calls++
+		active--
+	}
+	_, err := Run(context.Background(), sh(`i=1; while [ $i -le 300 ]; do echo $i; echo $i >&2; i=$((i+1)); done`, onLine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 600 || overlap || outOfOrder {
+		t.Errorf("calls = %d, overlapping = %t, out of order = %t", calls, overlap, outOfOrder)
+	}
+}
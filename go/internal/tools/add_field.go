@@ -2,142 +2,783 @@ package tools
 
 import (
 	"fmt"
-	"os"
+	"go/format"
+	"go/token"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
 )
 
 // AddFieldParams contains parameters for adding a field
 type AddFieldParams struct {
 	Resource    string `json:"resource"`
-	FieldName   string `json:"field_name"`
-	FieldType   string `json:"field_type"` // "string", "int64", "bool", "object", "array"
+	FieldName   string `json:"field_name,omitempty"` // derived from proto_path when field_type is empty
+	FieldType   string `json:"field_type,omitempty"` // see fieldTypes; leave empty to derive the field from proto_path
 	ProtoPath   string `json:"proto_path"`
 	ParentType  string `json:"parent_type,omitempty"`
 	Description string `json:"description,omitempty"`
 	JSONName    string `json:"json_name,omitempty"`
+
+	ElementType string   `json:"element_type,omitempty"` // element type of "array" and value type of "map" fields; defaults to "string"
+	EnumValues  []string `json:"enum_values,omitempty"`  // allowed values of "enum" fields, rendered as kubebuilder validation
+	RefType     string   `json:"ref_type,omitempty"`     // referenced kind of "ref" fields, e.g. "ComputeNetwork" or "compute.googleapis.com/Network"
+
+	NestedTypesIn string `json:"nested_types_in,omitempty"` // where new nested structs go: "parent" (after the parent struct, default) or "generated" (the package's types.generated.go)
+	AllVersions   bool   `json:"all_versions,omitempty"`    // add the field to every version of the resource (e.g. v1alpha1 and v1beta1)
+	DryRun        bool   `json:"dry_run,omitempty"`         // return the diff without writing
+}
+
+// generatedTypesFile is the file of a KCC API package that holds the types
+// generated from the proto
+const generatedTypesFile = "types.generated.go"
+
+// fieldTypes lists the supported AddFieldParams.FieldType values
+var fieldTypes = []string{
+	"string", "int32", "int64", "uint32", "uint64", "float", "double", "bool", "bytes",
+	"enum", "duration", "timestamp", "struct", "map", "ref", "object", "array",
+}
+
+var (
+	// jsonNamePattern matches the lowerCamelCase JSON names of KRM fields
+	jsonNamePattern = regexp.MustCompile(`^[a-z][A-Za-z0-9]*$`)
+
+	// protoPathPattern matches a fully qualified proto field name
+	protoPathPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+)
+
+// validateGoName checks that a client-supplied name can be rendered as an
+// exported Go identifier
+func validateGoName(param, name, example string) error {
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return fmt.Errorf("invalid %s: %q\n\n%s must be an exported Go identifier, e.g. %q", param, name, param, example)
+	}
+	return nil
+}
+
+// validateJSONName checks that a JSON name can be rendered into a struct tag
+func validateJSONName(name string) error {
+	if !jsonNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid json_name: %q\n\njson_name must be lowerCamelCase letters and digits, e.g. \"mtuBytes\"", name)
+	}
+	return nil
 }
 
-// AddField adds a field to a KCC resource types file
-func AddField(repoPath, typesFile string, params AddFieldParams) (string, error) {
-	filePath := filepath.Join(repoPath, typesFile)
+// fieldGoType is the Go rendering of a field type
+type fieldGoType struct {
+	Type    string
+	Markers []string // kubebuilder markers placed above the field
+	Imports []string // import specs the type needs
+}
+
+// addition is a field ready to be inserted into its parent struct
+type addition struct {
+	parent   *goStruct
+	fieldDef string
+	imports  []string
+	structs  []string // new struct declarations, placed after the parent
+	notes    []string
+}
+
+// AddField adds a field to a KCC resource types file. The parent struct may
+// be declared in any file of the types file's package; the field is inserted
+// in proto field order and the file is rewritten gofmt-clean.
+//
+// When only the proto path is given, the field name, type, JSON name,
+// description, parent struct (Spec or ObservedState) and nested structs are
+// all derived from the vendored proto.
+func AddField(cat *Catalog, typesFile string, params AddFieldParams, changes *ChangeSet) (string, error) {
+	if params.ProtoPath == "" {
+		return "", fmt.Errorf("proto_path is required, e.g. google.cloud.compute.v1.Network.mtu")
+	}
 
-	// Read file
-	contentBytes, err := os.ReadFile(filePath)
+	var result string
+	var err error
+	if params.AllVersions {
+		result, err = addFieldAllVersions(cat, typesFile, params, changes)
+	} else if typesFile, err = resolveTypesFile(cat, typesFile, params.Resource); err == nil {
+		result, err = addFieldToFile(cat, typesFile, params, changes)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+		return "", err
 	}
-	content := string(contentBytes)
+	if err := changes.Apply(); err != nil {
+		return "", err
+	}
+	return result, nil
+}
 
-	// Determine Go type
-	goType, err := getGoType(params.FieldType, params.Resource, params.FieldName)
+// addFieldAllVersions applies the same field to the direct types of every
+// version of the resource, so that alpha and beta do not drift. Each version
+// is reported separately. Versions that already map the proto field are
+// skipped; if any other version fails, the field is added to none of them.
+func addFieldAllVersions(cat *Catalog, typesFile string, params AddFieldParams, changes *ChangeSet) (string, error) {
+	if params.Resource == "" {
+		return "", fmt.Errorf("resource is required with all_versions")
+	}
+	entry, err := cat.Resolve(params.Resource)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "", fmt.Errorf("resource not found: %s\n\nUse kcc_list_resources to see the available Kinds", params.Resource)
+	}
+	primary := entry.TypesFile
+	if typesFile != "" {
+		if primary, err = confineTypesFile(cat, typesFile); err != nil {
+			return "", err
+		}
+	}
+
+	var versions []ResourceKind
+	for _, source := range entry.Sources {
+		if source.Source == "direct" {
+			versions = append(versions, source)
+		}
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("%s has no direct types in any version\n\nUse kcc_scaffold_types first", entry.Kind)
+	}
+
+	var b strings.Builder
+	checkpoint := changes.checkpoint()
+	added, failed := 0, 0
+	for _, v := range versions {
+		fmt.Fprintf(&b, "== %s (%s) ==\n", v.Version, v.TypesFile)
+		if existing := protoPathMappedBy(cat, v.TypesFile, params.ProtoPath); existing != "" {
+			fmt.Fprintf(&b, "✓ Already mapped by %s\n\n", existing)
+			continue
+		}
+
+		versionParams := params
+		if params.ParentType != "" && v.TypesFile != primary {
+			versionParams.ParentType = equivalentStruct(cat, primary, v.TypesFile, params.ParentType)
+		}
+		result, err := addFieldToFile(cat, v.TypesFile, versionParams, changes)
+		if err != nil {
+			failed++
+			fmt.Fprintf(&b, "❌ %v\n\n", err)
+			continue
+		}
+		added++
+		fmt.Fprintf(&b, "%s\n\n", result)
+	}
+
+	if failed > 0 {
+		changes.restore(checkpoint)
+		return "", fmt.Errorf("%s failed for %d of %d version(s) of %s; no version was changed\n\n%s",
+			params.ProtoPath, failed, len(versions), entry.Kind, strings.TrimRight(b.String(), "\n"))
+	}
+	summary := fmt.Sprintf("Added %s to %d of %d version(s) of %s\n\n", params.ProtoPath, added, len(versions), entry.Kind)
+	return summary + strings.TrimRight(b.String(), "\n"), nil
+}
+
+// protoPathMappedBy returns the struct of the types file's package that
+// already has a field annotated with protoPath, or ""
+func protoPathMappedBy(cat *Catalog, typesFile, protoPath string) string {
+	filePath := filepath.Join(cat.RepoPath(), typesFile)
+	var mappedBy string
+	walkGoStructs(filepath.Dir(filePath), filePath, func(s *goStruct) bool {
+		for _, f := range s.Type.Fields.List {
+			if protoAnnotation(f.Doc) == protoPath {
+				mappedBy = s.Name
+				return false
+			}
+		}
+		return true
+	})
+	return mappedBy
+}
+
+// equivalentStruct maps a struct of one version's package onto the struct of
+// another version's package that plays the same role: the same name if it
+// exists, otherwise the struct with the same +kcc:proto annotation and the
+// same Spec/ObservedState role
+func equivalentStruct(cat *Catalog, fromFile, toFile, name string) string {
+	fromPath := filepath.Join(cat.RepoPath(), fromFile)
+	toPath := filepath.Join(cat.RepoPath(), toFile)
+	if s, err := findGoStruct(filepath.Dir(toPath), name, toPath); err == nil && s != nil {
+		return name
+	}
+	from, err := findGoStruct(filepath.Dir(fromPath), name, fromPath)
+	if err != nil || from == nil {
+		return name
+	}
+	message := protoAnnotation(from.Doc)
+	if message == "" {
+		return name
+	}
+	candidates, err := findAnnotatedStructs(filepath.Dir(toPath), message, toPath)
+	if err != nil {
+		return name
+	}
+	for _, s := range candidates {
+		if strings.HasSuffix(s.Name, "ObservedState") == strings.HasSuffix(name, "ObservedState") {
+			return s.Name
+		}
+	}
+	return name
+}
+
+// addFieldToFile adds the field to one types file and its package, recording
+// the rewritten files in changes
+func addFieldToFile(cat *Catalog, typesFile string, params AddFieldParams, changes *ChangeSet) (string, error) {
+	var err error
+	filePath := filepath.Join(cat.RepoPath(), typesFile)
+	index := cat.ProtoIndex(serviceFromTypesFile(typesFile))
+
+	var add *addition
+	if params.FieldType == "" {
+		add, err = fieldFromProto(index, filePath, params)
+	} else {
+		add, err = fieldFromParams(index, filePath, typesFile, params)
+	}
+	if err != nil {
+		return "", err
+	}
+	parent := add.parent
+
+	// Pre-flight: nothing is written if the field collides with the package
+	if err := checkFieldConflicts(add, filepath.Dir(parent.Path), cat.RepoPath()); err != nil {
+		return "", err
+	}
+
+	// New nested structs go after the parent, or into the generated types file
+	var structsPath string
+	if len(add.structs) > 0 && params.NestedTypesIn == "generated" {
+		structsPath = filepath.Join(filepath.Dir(parent.Path), generatedTypesFile)
+		if !fileExists(structsPath) {
+			add.notes = append(add.notes, fmt.Sprintf("%s does not exist; nested types were placed after %s", generatedTypesFile, parent.Name))
+			structsPath = ""
+		}
+	} else if params.NestedTypesIn != "" && params.NestedTypesIn != "parent" {
+		return "", fmt.Errorf("invalid nested_types_in: %s (expected \"parent\" or \"generated\")", params.NestedTypesIn)
+	}
+	if structsPath == parent.Path {
+		structsPath = ""
+	}
+
+	structsText := "\n\n" + strings.Join(add.structs, "\n\n")
+	parentText := add.fieldDef
+	edits := []edit{insertField(parent, add.fieldDef, params.ProtoPath, index)}
+	if len(add.structs) > 0 && structsPath == "" {
+		edits = append(edits, edit{offset: parent.end(), text: structsText})
+		parentText += structsText
+	}
+	formatted, err := formatWithImports(parent.Path, applyEdits(parent.Src, edits), importsUsedBy(parentText, add.imports))
+	if err != nil {
+		return "", err
+	}
+
+	var structsFormatted []byte
+	if structsPath != "" {
+		src, err := changes.Content(structsPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		src = append(src, structsText...)
+		if structsFormatted, err = formatWithImports(structsPath, src, importsUsedBy(structsText, add.imports)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := changes.Write(parent.Path, formatted); err != nil {
+		return "", err
+	}
+	if structsPath != "" {
+		if err := changes.Write(structsPath, structsFormatted); err != nil {
+			return "", err
+		}
+	}
+
+	relPath, _ := filepath.Rel(cat.RepoPath(), parent.Path)
+	result := fmt.Sprintf("✅ Added field to %s (%s)\n\n%s", relPath, parent.Name, add.fieldDef)
+	if len(add.structs) > 0 {
+		where := relPath
+		if structsPath != "" {
+			where, _ = filepath.Rel(cat.RepoPath(), structsPath)
+		}
+		result += fmt.Sprintf("\n\nGenerated %d nested type(s) in %s:\n\n%s", len(add.structs), where, strings.Join(add.structs, "\n\n"))
+	}
+	for _, note := range add.notes {
+		result += "\n\nNote: " + note
+	}
+	return result, nil
+}
+
+// formatWithImports adds the missing imports to a modified Go file and
+// formats it
+func formatWithImports(path string, src []byte, imports []string) ([]byte, error) {
+	content, err := ensureImports(src, imports)
+	if err != nil {
+		return nil, fmt.Errorf("field would leave %s unparsable: %w", path, err)
+	}
+	formatted, err := format.Source(content)
+	if err != nil {
+		return nil, fmt.Errorf("field would leave %s unparsable: %w", path, err)
+	}
+	return formatted, nil
+}
+
+// importsUsedBy returns the import specs whose package name appears in code
+func importsUsedBy(code string, specs []string) []string {
+	var used []string
+	for _, spec := range specs {
+		name, _, _ := strings.Cut(spec, " ")
+		if strings.Contains(code, name+".") {
+			used = append(used, spec)
+		}
+	}
+	return used
+}
+
+// resolveTypesFile returns typesFile, or the direct types file of resource
+// when typesFile is empty
+func resolveTypesFile(cat *Catalog, typesFile, resource string) (string, error) {
+	if typesFile != "" {
+		return confineTypesFile(cat, typesFile)
+	}
+	if resource == "" {
+		return "", fmt.Errorf("either types_file or resource is required")
+	}
+	entry, err := cat.Resolve(resource)
 	if err != nil {
 		return "", err
 	}
+	if entry == nil {
+		return "", fmt.Errorf("resource not found: %s\n\nUse kcc_list_resources to see the available Kinds", resource)
+	}
+	if !entry.HasDirectTypes {
+		return "", fmt.Errorf("%s has no direct types file yet (expected at %s)\n\nUse kcc_scaffold_types first", entry.Kind, entry.TypesFile)
+	}
+	return entry.TypesFile, nil
+}
+
+// confineTypesFile checks a client-supplied types file against the writable
+// subtrees of the repository and returns it relative to the repository
+func confineTypesFile(cat *Catalog, typesFile string) (string, error) {
+	resolved, err := ResolveWritePath(cat.RepoPath(), typesFile)
+	if err != nil {
+		return "", withParam(err, "types_file")
+	}
+	return repoRelPath(cat.RepoPath(), resolved), nil
+}
+
+// fieldFromParams renders a field described explicitly by the caller
+func fieldFromParams(index *protoparser.Index, filePath, typesFile string, params AddFieldParams) (*addition, error) {
+	if params.FieldName == "" {
+		return nil, fmt.Errorf("field_name is required when field_type is set")
+	}
+	// The names end up in Go source and struct tags, so they are checked
+	// before anything is rendered
+	if err := validateGoName("field_name", params.FieldName, "MtuBytes"); err != nil {
+		return nil, err
+	}
+	if params.JSONName != "" {
+		if err := validateJSONName(params.JSONName); err != nil {
+			return nil, err
+		}
+	}
+	if params.Resource != "" {
+		if err := validateGoName("resource", params.Resource, "ComputeNetwork"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Determine Go type
+	goType, err := getGoType(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// References follow KCC's naming: networkRef, secondaryNetworkRefs
+	fieldName := params.FieldName
+	if isRefField(params) {
+		fieldName = refFieldName(fieldName, params.FieldType == "array")
+	}
 
 	// JSON name (default to camelCase field name)
 	jsonName := params.JSONName
 	if jsonName == "" {
-		jsonName = toCamelCase(params.FieldName)
+		jsonName = toCamelCase(fieldName)
 	}
 
-	// Build field definition
-	fieldDef := buildFieldDefinition(params.FieldName, goType, jsonName, params.ProtoPath, params.Description)
-
-	// Find insertion point
+	// Find the parent struct
 	parentType := params.ParentType
 	if parentType == "" {
 		parentType = fmt.Sprintf("%sSpec", params.Resource)
 	}
 
-	insertionPoint := findInsertionPoint(content, parentType)
-	if insertionPoint == -1 {
-		return "", fmt.Errorf("could not find parent type: %s\n\nMake sure the type exists in %s", parentType, typesFile)
+	parent, err := findGoStruct(filepath.Dir(filePath), parentType, filePath)
+	if err != nil {
+		return nil, err
 	}
+	if parent == nil {
+		return nil, fmt.Errorf("could not find parent type: %s\n\nMake sure the type exists in %s or another file of its package", parentType, typesFile)
+	}
+
+	add := &addition{parent: parent, imports: goType.Imports}
+	if params.FieldType == "object" || (params.FieldType == "array" && params.ElementType == "object") {
+		if err := addObjectStruct(index, filePath, params, goType, add); err != nil {
+			return nil, err
+		}
+	}
+	// Validation follows the proto's field_behavior, except in observed state
+	description, markers := params.Description, goType.Markers
+	if _, field := lookupProtoField(index, params.ProtoPath); field != nil && !strings.HasSuffix(parent.Name, "ObservedState") {
+		prefix, behaviorMarkers := behaviorAnnotations(field, jsonName)
+		description = prefixComment(description, prefix)
+		markers = append(behaviorMarkers, markers...)
+	}
+	if add.fieldDef, err = buildFieldDefinition(fieldName, goType.Type, jsonName, params.ProtoPath, description, markers); err != nil {
+		return nil, err
+	}
+	return add, nil
+}
 
-	// Insert field
-	lines := strings.Split(content, "\n")
-	newLines := make([]string, 0, len(lines)+3)
-	newLines = append(newLines, lines[:insertionPoint]...)
-	newLines = append(newLines, fieldDef)
-	newLines = append(newLines, lines[insertionPoint:]...)
-	content = strings.Join(newLines, "\n")
+// addObjectStruct generates the Resource_Field struct referenced by an
+// object field, recursively for its sub-messages, from the message type of
+// the proto field. An existing struct for the same message is reused.
+func addObjectStruct(index *protoparser.Index, filePath string, params AddFieldParams, goType *fieldGoType, add *addition) error {
+	structName := fmt.Sprintf("%s_%s", params.Resource, params.FieldName)
+	dir := filepath.Dir(filePath)
+	existing, err := findGoStruct(dir, structName, filePath)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		add.notes = append(add.notes, fmt.Sprintf("%s already exists and was reused", structName))
+		return nil
+	}
 
-	// Write back
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	var nested *protoparser.Message
+	if msg, field := lookupProtoField(index, params.ProtoPath); field != nil {
+		if kind, fullName := index.FieldType(msg, field); kind == protoparser.KindMessage {
+			nested = index.Message(fullName)
+		}
+	}
+	if nested == nil {
+		add.structs = append(add.structs, fmt.Sprintf("type %s struct {\n\t// TODO: Add fields here with proper +kcc:proto= annotations\n}", structName))
+		add.notes = append(add.notes, fmt.Sprintf("%s is not a message field in the vendored protos; %s was created empty", params.ProtoPath, structName))
+		return nil
 	}
 
-	return fmt.Sprintf("✅ Added field to %s\n\n%s", typesFile, fieldDef), nil
+	gen, err := packageTypeGenerator(index, dir, filePath)
+	if err != nil {
+		return err
+	}
+	name := gen.declareStruct(nested, structName, strings.HasSuffix(add.parent.Name, "ObservedState"))
+	if name != structName {
+		goType.Type = strings.Replace(goType.Type, structName, name, 1)
+		add.notes = append(add.notes, fmt.Sprintf("reused %s, which already maps %s", name, nested.FullName))
+	}
+	add.structs = gen.structs
+	add.imports = append(add.imports, gen.importLines()...)
+	return nil
 }
 
-// getGoType determines the Go type for a field
-func getGoType(fieldType, resource, fieldName string) (string, error) {
-	switch fieldType {
-	case "string":
-		return "*string", nil
-	case "int64":
-		return "*int64", nil
-	case "bool":
-		return "*bool", nil
-	case "array":
-		return "[]string", nil // Default, can be customized
-	case "object":
-		return fmt.Sprintf("*%s_%s", resource, fieldName), nil
-	default:
-		return "", fmt.Errorf("unsupported field type: %s", fieldType)
+// packageTypeGenerator returns a type generator that reuses the structs the
+// package in dir already maps to proto messages
+func packageTypeGenerator(index *protoparser.Index, dir, filePath string) (*typeGenerator, error) {
+	gen := newTypeGenerator(index)
+	err := walkGoStructs(dir, filePath, func(s *goStruct) bool {
+		if annotated := protoAnnotation(s.Doc); annotated != "" && !strings.HasSuffix(s.Name, "Spec") {
+			key := annotated
+			if strings.HasSuffix(s.Name, "ObservedState") {
+				key += "ObservedState"
+			}
+			if _, ok := gen.named[key]; !ok {
+				gen.named[key] = s.Name
+			}
+		}
+		return true
+	})
+	return gen, err
+}
+
+// fieldFromProto derives a field entirely from its proto definition. Output
+// only fields go to the ObservedState struct mapped to the message, other
+// fields to the Spec (or nested spec struct) mapped to it.
+func fieldFromProto(index *protoparser.Index, filePath string, params AddFieldParams) (*addition, error) {
+	msg, field := lookupProtoField(index, params.ProtoPath)
+	if msg == nil {
+		return nil, fmt.Errorf(`proto message for %s not found in the vendored protos
+
+Check the path with kcc_describe_proto, or pass field_name and field_type
+explicitly`, params.ProtoPath)
+	}
+	if field == nil {
+		var names []string
+		for _, f := range msg.Fields {
+			names = append(names, f.Name)
+		}
+		return nil, fmt.Errorf("%s has no field %s\n\nFields: %s",
+			msg.FullName, strings.TrimPrefix(params.ProtoPath, msg.FullName+"."), strings.Join(names, ", "))
 	}
+	observed := isOutputOnly(field)
+
+	dir := filepath.Dir(filePath)
+	parent, err := protoParentStruct(dir, filePath, msg.FullName, params.ParentType, observed)
+	if err != nil {
+		return nil, err
+	}
+
+	gen, err := packageTypeGenerator(index, dir, filePath)
+	if err != nil {
+		return nil, err
+	}
+	gen.skip[msg.FullName] = true
+
+	return &addition{
+		parent:   parent,
+		fieldDef: gen.fieldDeclaration(msg, field, observed),
+		imports:  gen.importLines(),
+		structs:  gen.structs,
+	}, nil
 }
 
-// buildFieldDefinition builds the field definition with annotations
-func buildFieldDefinition(fieldName, goType, jsonName, protoPath, description string) string {
-	var lines []string
+// protoParentStruct picks the struct that receives a field of message: the
+// one named parentType if given, otherwise the ObservedState (for output
+// only fields) or the Spec struct annotated with +kcc:proto=message
+func protoParentStruct(dir, filePath, message, parentType string, observed bool) (*goStruct, error) {
+	if parentType != "" {
+		parent, err := findGoStruct(dir, parentType, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("could not find parent type: %s in %s", parentType, dir)
+		}
+		return parent, nil
+	}
 
-	// Add description comment if provided
-	if description != "" {
-		lines = append(lines, fmt.Sprintf("\t// %s", description))
+	candidates, err := findAnnotatedStructs(dir, message, filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range candidates {
+		if strings.HasSuffix(s.Name, "ObservedState") == observed {
+			return s, nil
+		}
 	}
 
-	// Add proto annotation
-	lines = append(lines, fmt.Sprintf("\t// +kcc:proto=%s", protoPath))
+	want := "a Spec or nested spec struct"
+	if observed {
+		want = "an ObservedState struct (the field is OUTPUT_ONLY)"
+	}
+	var names []string
+	for _, s := range candidates {
+		names = append(names, s.Name)
+	}
+	return nil, fmt.Errorf(`no struct to hold the field: expected %s annotated with
+// +kcc:proto=%s
+Annotated structs found: %s
 
-	// Add field
-	lines = append(lines, fmt.Sprintf("\t%s %s `json:\"%s,omitempty\"`", fieldName, goType, jsonName))
+Add the field that holds %s first, or pass parent_type`, want, message, defaultIfEmpty(strings.Join(names, ", "), "none"), message)
+}
 
-	return strings.Join(lines, "\n")
+// insertField splices fieldDef into the parent struct: before the first
+// field mapped to a later field of the same proto message, or after the last
+// field otherwise
+func insertField(parent *goStruct, fieldDef, protoPath string, index *protoparser.Index) edit {
+	message, number := protoFieldNumber(index, protoPath)
+
+	offset, text := parent.offset(parent.Type.Fields.Closing), "\n"+fieldDef+"\n"
+	if number > 0 {
+		for _, f := range parent.Type.Fields.List {
+			m, n := protoFieldNumber(index, protoAnnotation(f.Doc))
+			if m == message && n > number {
+				offset, text = parent.fieldStart(f), fieldDef+"\n\n"
+				break
+			}
+		}
+	}
+
+	return edit{offset: offset, text: text}
 }
 
-// findInsertionPoint finds where to insert the new field
-func findInsertionPoint(content, parentType string) int {
-	lines := strings.Split(content, "\n")
+// protoFieldNumber resolves a +kcc:proto= field path such as
+// google.cloud.compute.v1.Network.mtu to its message and field number. The
+// number is 0 when the path does not name a known field.
+func protoFieldNumber(index *protoparser.Index, protoPath string) (string, int) {
+	msg, field := lookupProtoField(index, protoPath)
+	switch {
+	case msg == nil:
+		return "", 0
+	case field == nil:
+		return msg.FullName, 0
+	}
+	return msg.FullName, field.Number
+}
 
-	// Find the parent type struct
-	inStruct := false
-	lastFieldLine := -1
+// lookupProtoField resolves a +kcc:proto= field path to its message and
+// field. The field is nil when the message has no such field.
+func lookupProtoField(index *protoparser.Index, protoPath string) (*protoparser.Message, *protoparser.Field) {
+	i := strings.LastIndex(protoPath, ".")
+	if i < 0 {
+		return nil, nil
+	}
+	msg := index.Message(protoPath[:i])
+	if msg == nil {
+		return nil, nil
+	}
+	for _, f := range msg.Fields {
+		if f.Name == protoPath[i+1:] {
+			return msg, f
+		}
+	}
+	return msg, nil
+}
 
-	for i, line := range lines {
-		// Found the struct definition
-		if strings.Contains(line, fmt.Sprintf("type %s struct {", parentType)) {
-			inStruct = true
-			continue
+// serviceFromTypesFile returns the service of a types file path such as
+// apis/compute/v1beta1/network_types.go
+func serviceFromTypesFile(typesFile string) string {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(typesFile)), "/")
+	for i, part := range parts {
+		if part == "apis" && i+1 < len(parts) {
+			return parts[i+1]
 		}
+	}
+	return ""
+}
 
-		// Inside the struct, track last field line
-		if inStruct {
-			// Check if this is a field line (contains backticks for json tag)
-			if strings.Contains(line, "`json:") {
-				lastFieldLine = i
-			}
+// getGoType determines the Go type for a field, following KCC's conventions:
+// pointer scalars, enums as strings, well-known types as strings or JSON,
+// references as refs types and nested messages as Resource_Field structs
+func getGoType(params AddFieldParams) (*fieldGoType, error) {
+	switch params.FieldType {
+	case "string", "int32", "int64", "uint32", "uint64", "float", "double", "bool", "bytes":
+		return &fieldGoType{Type: scalarGoType(params.FieldType)}, nil
+	case "enum":
+		t := &fieldGoType{Type: "*string"}
+		if len(params.EnumValues) > 0 {
+			t.Markers = append(t.Markers, "+kubebuilder:validation:Enum="+strings.Join(params.EnumValues, ";"))
+		}
+		return t, nil
+	case "duration", "timestamp":
+		// Durations ("3.5s") and timestamps (RFC 3339) are kept in their JSON form
+		return &fieldGoType{Type: "*string"}, nil
+	case "struct":
+		return &fieldGoType{Type: "*apiextensionsv1.JSON", Imports: []string{apiextensionsImport}}, nil
+	case "ref":
+		refType, err := refGoType(params.RefType)
+		if err != nil {
+			return nil, err
+		}
+		return &fieldGoType{Type: "*" + refType, Imports: []string{refsImport}}, nil
+	case "object":
+		return &fieldGoType{Type: fmt.Sprintf("*%s_%s", params.Resource, params.FieldName)}, nil
+	case "map":
+		value, err := elementGoType(params)
+		if err != nil {
+			return nil, err
+		}
+		value.Type = "map[string]" + value.Type
+		return value, nil
+	case "array":
+		elem, err := elementGoType(params)
+		if err != nil {
+			return nil, err
+		}
+		elem.Type = "[]" + elem.Type
+		return elem, nil
+	default:
+		return nil, fmt.Errorf("unsupported field type: %s\n\nSupported types: %s", params.FieldType, strings.Join(fieldTypes, ", "))
+	}
+}
 
-			// End of struct
-			if strings.TrimSpace(line) == "}" {
-				// Insert before the closing brace
-				return lastFieldLine + 1
-			}
+// elementGoType returns the element type of an array or the value type of a
+// map, without the pointer used for singular fields
+func elementGoType(params AddFieldParams) (*fieldGoType, error) {
+	elem := params
+	elem.FieldType = defaultIfEmpty(params.ElementType, "string")
+	if elem.FieldType == "array" || elem.FieldType == "map" {
+		return nil, fmt.Errorf("unsupported element type for %s: %s (nested collections have no KRM representation)", params.FieldType, elem.FieldType)
+	}
+	if params.FieldType == "map" && (elem.FieldType == "ref" || elem.FieldType == "object") {
+		return nil, fmt.Errorf("unsupported value type for map: %s", elem.FieldType)
+	}
+
+	t, err := getGoType(elem)
+	if err != nil {
+		return nil, err
+	}
+	if t.Type != "[]byte" {
+		t.Type = strings.TrimPrefix(t.Type, "*")
+	}
+	// Enum validation applies to items, not to the list itself
+	t.Markers = nil
+	return t, nil
+}
+
+// refGoType returns the refs type for a referenced kind. refType is a KCC
+// kind (ComputeNetwork), its ref type (ComputeNetworkRef) or a
+// google.api.resource type (compute.googleapis.com/Network).
+func refGoType(refType string) (string, error) {
+	if refType == "" {
+		return "", fmt.Errorf(`ref_type is required for reference fields
+
+Examples:
+  "ref_type": "ComputeNetwork"
+  "ref_type": "compute.googleapis.com/Network"`)
+	}
+	if strings.Contains(refType, "/") {
+		name := refTypeName(refType)
+		if name == "" {
+			return "", fmt.Errorf("cannot derive a reference type from %s", refType)
 		}
+		return "refs." + name, nil
+	}
+	return "refs." + strings.TrimSuffix(strings.TrimPrefix(refType, "refs."), "Ref") + "Ref", nil
+}
+
+// isRefField reports whether a field holds one or more references
+func isRefField(params AddFieldParams) bool {
+	return params.FieldType == "ref" || (params.FieldType == "array" && params.ElementType == "ref")
+}
+
+// refFieldName applies KCC's naming to a reference field: Network becomes
+// NetworkRef, and a list of Networks becomes NetworkRefs
+func refFieldName(name string, repeated bool) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "Refs"), "Ref")
+	if repeated {
+		return strings.TrimSuffix(name, "s") + "Refs"
+	}
+	return name + "Ref"
+}
+
+// buildFieldDefinition builds the field definition with annotations
+func buildFieldDefinition(fieldName, goType, jsonName, protoPath, description string, markers []string) (string, error) {
+	if err := validateGoName("field_name", fieldName, "MtuBytes"); err != nil {
+		return "", err
+	}
+	if err := validateJSONName(jsonName); err != nil {
+		return "", err
 	}
+	if !protoPathPattern.MatchString(protoPath) {
+		return "", fmt.Errorf("invalid proto_path: %q\n\nExpected a fully qualified proto field, e.g. google.cloud.compute.v1.Network.mtu", protoPath)
+	}
+
+	var lines []string
+
+	// Add description comment if provided
+	if description != "" {
+		for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+			lines = append(lines, strings.TrimRight(fmt.Sprintf("\t// %s", line), " "))
+		}
+	}
+
+	// Add validation markers and proto annotation
+	for _, marker := range markers {
+		lines = append(lines, "\t// "+marker)
+	}
+	lines = append(lines, fmt.Sprintf("\t// +kcc:proto=%s", protoPath))
+
+	// Add field
+	lines = append(lines, fmt.Sprintf("\t%s %s `json:\"%s,omitempty\"`", fieldName, goType, jsonName))
 
-	return -1
+	return strings.Join(lines, "\n"), nil
 }
 
 // toCamelCase converts PascalCase to camelCase
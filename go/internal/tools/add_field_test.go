@@ -0,0 +1,366 @@
+package tools
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const testTypesFile = "apis/widgets/v1alpha1/widget_types.go"
+
+// newTestRepo lays out a minimal KCC repository with the widgets proto and
+// the given types file, and returns its catalog
+func newTestRepo(t *testing.T, typesSrc string) *Catalog {
+	t.Helper()
+	repo := t.TempDir()
+	proto, err := os.ReadFile(filepath.Join("testdata", "add_field", "widgets.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		protoRoot + "/google/cloud/widgets/v1/widgets.proto": proto,
+		testTypesFile: []byte(typesSrc),
+	}
+	for relPath, content := range files {
+		path := filepath.Join(repo, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cat := NewCatalog(repo)
+	if _, err := cat.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "add_field", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAddFieldGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string // input types file in testdata/add_field
+		params AddFieldParams
+	}{
+		{
+			name:   "between_fields",
+			before: "base.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.description"},
+		},
+		{
+			name:   "after_last_field",
+			before: "base.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.labels"},
+		},
+		{
+			name:   "observed_state",
+			before: "base.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.state"},
+		},
+		{
+			name:   "nested_message",
+			before: "base.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.config"},
+		},
+		{
+			name:   "explicit_ref",
+			before: "base.go.in",
+			params: AddFieldParams{
+				Resource:   "WidgetsWidget",
+				FieldName:  "Network",
+				FieldType:  "ref",
+				RefType:    "ComputeNetwork",
+				ProtoPath:  "google.cloud.widgets.v1.Widget.network",
+				ParentType: "WidgetSpec",
+			},
+		},
+		{
+			name:   "embedded",
+			before: "embedded.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.display_name"},
+		},
+		{
+			name:   "multiline_tags",
+			before: "multiline_tags.go.in",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.description"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := newTestRepo(t, readTestdata(t, tt.before))
+			changes := NewChangeSet(cat.RepoPath(), false)
+			if _, err := AddField(cat, testTypesFile, tt.params, changes); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "add_field", tt.name+".go.golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if want := readTestdata(t, tt.name+".go.golden"); string(got) != want {
+				t.Errorf("%s differs from %s:\n%s", testTypesFile, golden, got)
+			}
+		})
+	}
+}
+
+func TestAddFieldConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		params AddFieldParams
+		want   []string
+	}{
+		{
+			name: "duplicate Go name",
+			params: AddFieldParams{
+				FieldName:  "DisplayName",
+				FieldType:  "string",
+				JSONName:   "title",
+				ProtoPath:  "google.cloud.widgets.v1.Widget.description",
+				ParentType: "WidgetSpec",
+			},
+			want: []string{
+				"WidgetSpec already has a field named DisplayName",
+				"-\tDisplayName *string `json:\"displayName,omitempty\"`",
+			},
+		},
+		{
+			name: "duplicate JSON name",
+			params: AddFieldParams{
+				FieldName:  "Title",
+				FieldType:  "string",
+				JSONName:   "sizeGb",
+				ProtoPath:  "google.cloud.widgets.v1.Widget.description",
+				ParentType: "WidgetSpec",
+			},
+			want: []string{`WidgetSpec already has a field with JSON name "sizeGb"`},
+		},
+		{
+			name: "duplicate proto path",
+			params: AddFieldParams{
+				FieldName:  "Title",
+				FieldType:  "string",
+				ProtoPath:  "google.cloud.widgets.v1.Widget.display_name",
+				ParentType: "WidgetSpec",
+			},
+			want: []string{"google.cloud.widgets.v1.Widget.display_name is already mapped by WidgetSpec"},
+		},
+		{
+			name:   "duplicate proto field derived from the proto",
+			params: AddFieldParams{ProtoPath: "google.cloud.widgets.v1.Widget.size_gb"},
+			want: []string{
+				"WidgetSpec already has a field named SizeGb",
+				"google.cloud.widgets.v1.Widget.size_gb is already mapped by WidgetSpec",
+			},
+		},
+		{
+			name: "field of a nested message",
+			params: AddFieldParams{
+				FieldName:  "Enabled",
+				FieldType:  "bool",
+				ProtoPath:  "google.cloud.widgets.v1.Widget.Config.enabled",
+				ParentType: "WidgetSpec",
+			},
+			want: []string{"google.cloud.widgets.v1.Widget.Config.enabled is not a field of google.cloud.widgets.v1.Widget"},
+		},
+		{
+			name: "field of another message",
+			params: AddFieldParams{
+				FieldName:  "Enabled",
+				FieldType:  "bool",
+				ProtoPath:  "google.cloud.widgets.v1.WidgetConfig.enabled",
+				ParentType: "WidgetSpec",
+			},
+			want: []string{"google.cloud.widgets.v1.WidgetConfig.enabled is not a field of google.cloud.widgets.v1.Widget"},
+		},
+	}
+
+	before := readTestdata(t, "base.go.in")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := newTestRepo(t, before)
+			changes := NewChangeSet(cat.RepoPath(), false)
+			_, err := AddField(cat, testTypesFile, tt.params, changes)
+			if err == nil {
+				t.Fatal("expected a conflict")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error does not contain %q:\n%v", want, err)
+				}
+			}
+
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != before {
+				t.Errorf("types file was modified despite the conflict:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestAddFieldRejectsInvalidNames(t *testing.T) {
+	valid := AddFieldParams{
+		Resource:   "WidgetsWidget",
+		FieldName:  "Title",
+		FieldType:  "string",
+		ProtoPath:  "google.cloud.widgets.v1.Widget.description",
+		ParentType: "WidgetSpec",
+	}
+	tests := []struct {
+		name   string
+		modify func(p *AddFieldParams)
+		want   string
+	}{
+		{name: "unexported field name", modify: func(p *AddFieldParams) { p.FieldName = "title" }, want: `invalid field_name: "title"`},
+		{name: "field name with a space", modify: func(p *AddFieldParams) { p.FieldName = "Display Name" }, want: "invalid field_name"},
+		{name: "field name injecting a field", modify: func(p *AddFieldParams) { p.FieldName = "Title string\n\tEvil" }, want: "invalid field_name"},
+		{name: "keyword", modify: func(p *AddFieldParams) { p.FieldName = "func" }, want: "invalid field_name"},
+		{name: "snake_case JSON name", modify: func(p *AddFieldParams) { p.JSONName = "display_name" }, want: `invalid json_name: "display_name"`},
+		{name: "capitalised JSON name", modify: func(p *AddFieldParams) { p.JSONName = "Title" }, want: "invalid json_name"},
+		{name: "JSON name breaking the tag", modify: func(p *AddFieldParams) { p.JSONName = "title\"`" }, want: "invalid json_name"},
+		{name: "resource with a dot", modify: func(p *AddFieldParams) { p.Resource = "widgets.Widget" }, want: `invalid resource: "widgets.Widget"`},
+		{name: "proto path with a newline", modify: func(p *AddFieldParams) { p.ProtoPath += "\n\tEvil string" }, want: "invalid proto_path"},
+	}
+
+	before := readTestdata(t, "base.go.in")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := newTestRepo(t, before)
+			params := valid
+			tt.modify(&params)
+			_, err := AddField(cat, testTypesFile, params, NewChangeSet(cat.RepoPath(), false))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != before {
+				t.Errorf("types file was modified:\n%s", got)
+			}
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		cat := newTestRepo(t, before)
+		if _, err := AddField(cat, testTypesFile, valid, NewChangeSet(cat.RepoPath(), false)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestAddFieldAllVersions(t *testing.T) {
+	const betaTypesFile = "apis/widgets/v1beta1/widget_types.go"
+	base := readTestdata(t, "base.go.in")
+	added := readTestdata(t, "between_fields.go.golden")
+	unmapped := strings.Replace(base, "\t// The display name.\n", "\t// Hand-written, without a proto mapping.\n\tDescription *string `json:\"description,omitempty\"`\n\n\t// The display name.\n", 1)
+
+	tests := []struct {
+		name      string
+		beta      string // v1beta1 types; v1alpha1 starts from base.go.in
+		wantAlpha string
+		wantBeta  string
+		want      []string // snippets of the result or error
+		wantErr   bool
+	}{
+		{
+			name:      "both versions",
+			beta:      base,
+			wantAlpha: added,
+			wantBeta:  added,
+			want:      []string{"Added google.cloud.widgets.v1.Widget.description to 2 of 2 version(s) of WidgetsWidget"},
+		},
+		{
+			name:      "one version has the field",
+			beta:      added,
+			wantAlpha: added,
+			wantBeta:  added,
+			want: []string{
+				"to 1 of 2 version(s)",
+				"== v1beta1 (apis/widgets/v1beta1/widget_types.go) ==\n✓ Already mapped by WidgetSpec",
+			},
+		},
+		{
+			name:      "one version fails",
+			beta:      unmapped,
+			wantAlpha: base,
+			wantBeta:  unmapped,
+			want: []string{
+				"failed for 1 of 2 version(s) of WidgetsWidget; no version was changed",
+				"✅ Added field to apis/widgets/v1alpha1/widget_types.go",
+				"❌ refusing to add the field to WidgetSpec",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := newTestRepo(t, base)
+			beta := strings.Replace(tt.beta, "package v1alpha1", "package v1beta1", 1)
+			if err := os.MkdirAll(filepath.Join(cat.RepoPath(), filepath.Dir(betaTypesFile)), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(cat.RepoPath(), betaTypesFile), []byte(beta), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cat.Refresh(); err != nil {
+				t.Fatal(err)
+			}
+
+			params := AddFieldParams{Resource: "WidgetsWidget", ProtoPath: "google.cloud.widgets.v1.Widget.description", AllVersions: true}
+			result, err := AddField(cat, "", params, NewChangeSet(cat.RepoPath(), false))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got:\n%s", result)
+				}
+				result = err.Error()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result, want) {
+					t.Errorf("result does not contain %q:\n%s", want, result)
+				}
+			}
+
+			for file, want := range map[string]string{
+				testTypesFile: tt.wantAlpha,
+				betaTypesFile: strings.Replace(tt.wantBeta, "package v1alpha1", "package v1beta1", 1),
+			} {
+				got, err := os.ReadFile(filepath.Join(cat.RepoPath(), file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("%s:\n%s\nwant:\n%s", file, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,538 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// protoRoot is where the googleapis protos are vendored
+const protoRoot = "mockgcp/third_party/googleapis"
+
+// CatalogEntry is everything the server knows about a single KCC Kind.
+// Paths point at the existing file or, for resources that have not been
+// migrated yet, at the location the direct implementation is expected.
+type CatalogEntry struct {
+	Kind                 string         `json:"kind"`
+	Group                string         `json:"group"`
+	GVK                  string         `json:"gvk"`
+	Service              string         `json:"service"`
+	Version              string         `json:"version"`
+	Versions             []string       `json:"versions"`
+	ControllerType       string         `json:"controller_type"` // default controller: "direct", "terraform", "dcl", or "unknown"
+	DefaultController    string         `json:"default_controller"`
+	SupportedControllers []string       `json:"supported_controllers"`
+	Hybrid               bool           `json:"hybrid"`
+	ControllerSource     string         `json:"controller_source"` // "static_config", "crd", or "heuristic"
+	HasDirectTypes       bool           `json:"has_direct_types"`
+	HasTerraformTypes    bool           `json:"has_terraform_types"`
+	HasRegisteredModel   bool           `json:"has_registered_model"`
+	TypesFile            string         `json:"types_file"`
+	TerraformTypesFile   string         `json:"terraform_types_file,omitempty"`
+	CRDFile              string         `json:"crd_file,omitempty"`
+	IdentityFile         string         `json:"identity_file"`
+	ControllerFile       string         `json:"controller_file"`
+	MapperFile           string         `json:"mapper_file"`
+	MockGCPFile          string         `json:"mockgcp_file"`
+	FixturesDir          string         `json:"fixtures_dir"`
+	FixtureDirs          []string       `json:"fixture_dirs"`
+	ProtoLocation        string         `json:"proto_location,omitempty"`
+	Sources              []ResourceKind `json:"sources"`
+}
+
+// CatalogStats summarises a catalog refresh
+type CatalogStats struct {
+	Kinds        int       `json:"kinds"`
+	FilesTracked int       `json:"files_tracked"`
+	ProtoFiles   int       `json:"proto_files"`
+	Parsed       int       `json:"parsed"`
+	Removed      int       `json:"removed"`
+	Duration     string    `json:"duration"`
+	BuiltAt      time.Time `json:"built_at"`
+}
+
+// Catalog is a long-lived, in-memory index of every KCC resource in the
+// repository. It is built once at server start and shared by all tools.
+// Refresh re-walks the indexed trees but only re-parses files whose size or
+// modification time changed since the previous scan.
+type Catalog struct {
+	repoPath string
+
+	// refreshMu serialises refreshes; files is only touched while it is held
+	refreshMu sync.Mutex
+	files     map[string]*catalogFile
+
+	// mu guards the published snapshot below
+	mu      sync.RWMutex
+	kinds   []ResourceKind
+	entries map[string]*CatalogEntry
+	protos  []string
+	stats   CatalogStats
+
+	// protoCache holds parsed .proto files across tool calls
+	protoCache *protoparser.Cache
+}
+
+// catalogFile is the parsed state of a single indexed file
+type catalogFile struct {
+	modTime time.Time
+	size    int64
+	kinds   []ResourceKind // Kinds declared in a types file
+	models  []string       // Kinds registered with registry.RegisterModel
+	fixture string         // Kind of a test fixture's create.yaml
+
+	controllers   map[string]controllerConfig // static_config.go entries by Kind
+	crdKind       string                      // Kind of a CRD manifest
+	crdController string                      // legacy controller declared by the CRD labels
+	crdService    string                      // service of the CRD's API group
+	crdVersion    string                      // most stable version the CRD serves
+}
+
+// catalogSource describes one repository tree indexed by the catalog
+type catalogSource struct {
+	root  string
+	match func(relPath string) bool
+	parse func(f *catalogFile, relPath string, content []byte) // nil = listing only
+}
+
+var (
+	// registerModelPattern matches `registry.RegisterModel(krm.FooGVK, ...)`
+	registerModelPattern = regexp.MustCompile(`registry\.RegisterModel\(\s*(?:\w+\.)?([A-Za-z0-9]+)GVK\b`)
+
+	// fixtureKindPattern matches the top-level kind of a fixture manifest
+	fixtureKindPattern = regexp.MustCompile(`(?m)^kind:\s*([A-Za-z0-9]+)\s*$`)
+)
+
+var catalogSources = []catalogSource{
+	{
+		root:  "apis",
+		match: isTypesFile,
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			f.kinds = parseKinds(relPath, "direct", string(content))
+		},
+	},
+	{
+		root:  "pkg/clients/generated/apis",
+		match: isTypesFile,
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			f.kinds = parseKinds(relPath, "terraform", string(content))
+		},
+	},
+	{
+		root: "pkg/controller/direct",
+		match: func(relPath string) bool {
+			return strings.HasSuffix(relPath, ".go") && !strings.HasSuffix(relPath, "_test.go")
+		},
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			for _, m := range registerModelPattern.FindAllSubmatch(content, -1) {
+				f.models = append(f.models, string(m[1]))
+			}
+		},
+	},
+	{
+		root: "pkg/test/resourcefixture/testdata",
+		match: func(relPath string) bool {
+			return path.Base(relPath) == "create.yaml"
+		},
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			if m := fixtureKindPattern.FindSubmatch(content); m != nil {
+				f.fixture = string(m[1])
+			}
+		},
+	},
+	{
+		root: "pkg/controller/resourceconfig",
+		match: func(relPath string) bool {
+			return relPath == staticConfigFile
+		},
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			f.controllers = parseStaticControllerConfig(string(content))
+		},
+	},
+	{
+		root: "config/crds/resources",
+		match: func(relPath string) bool {
+			return strings.HasSuffix(relPath, ".yaml")
+		},
+		parse: func(f *catalogFile, relPath string, content []byte) {
+			f.crdKind, f.crdController = parseCRDController(string(content))
+			f.crdService, f.crdVersion = parseCRDGroupVersion(string(content))
+		},
+	},
+	{
+		root: protoRoot,
+		match: func(relPath string) bool {
+			return strings.HasSuffix(relPath, ".proto")
+		},
+	},
+}
+
+// NewCatalog creates an empty catalog for the repository at repoPath.
+// Call Refresh to populate it.
+func NewCatalog(repoPath string) *Catalog {
+	return &Catalog{
+		repoPath: repoPath,
+		files:    make(map[string]*catalogFile),
+		entries:  make(map[string]*CatalogEntry),
+
+		protoCache: protoparser.NewCache(),
+	}
+}
+
+// RepoPath returns the repository the catalog indexes
+func (c *Catalog) RepoPath() string {
+	return c.repoPath
+}
+
+// Refresh brings the catalog up to date with the working tree, re-parsing
+// only files that changed since the previous refresh
+func (c *Catalog) Refresh() (*CatalogStats, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refreshLocked()
+}
+
+// Rebuild discards all cached state and re-indexes the repository from scratch
+func (c *Catalog) Rebuild() (*CatalogStats, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.files = make(map[string]*catalogFile)
+	return c.refreshLocked()
+}
+
+func (c *Catalog) refreshLocked() (*CatalogStats, error) {
+	start := time.Now()
+	stats := CatalogStats{}
+	seen := make(map[string]bool)
+	var protos []string
+
+	for _, src := range catalogSources {
+		err := walkFiles(c.repoPath, src.root, src.match, func(relPath string, d fs.DirEntry) error {
+			if src.parse == nil {
+				protos = append(protos, relPath)
+				return nil
+			}
+			seen[relPath] = true
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if prev, ok := c.files[relPath]; ok && prev.size == info.Size() && prev.modTime.Equal(info.ModTime()) {
+				return nil
+			}
+
+			content, err := os.ReadFile(filepath.Join(c.repoPath, filepath.FromSlash(relPath)))
+			if err != nil {
+				return err
+			}
+			f := &catalogFile{modTime: info.ModTime(), size: info.Size()}
+			src.parse(f, relPath, content)
+			c.files[relPath] = f
+			stats.Parsed++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for relPath := range c.files {
+		if !seen[relPath] {
+			delete(c.files, relPath)
+			stats.Removed++
+		}
+	}
+
+	sort.Strings(protos)
+	kinds, entries := buildCatalogEntries(c.files, protos)
+
+	stats.Kinds = len(entries)
+	stats.FilesTracked = len(c.files)
+	stats.ProtoFiles = len(protos)
+	stats.Duration = time.Since(start).Round(time.Millisecond).String()
+	stats.BuiltAt = time.Now()
+
+	c.mu.Lock()
+	c.kinds = kinds
+	c.entries = entries
+	c.protos = protos
+	c.stats = stats
+	c.mu.Unlock()
+
+	return &stats, nil
+}
+
+// Stats returns the statistics of the most recent refresh
+func (c *Catalog) Stats() CatalogStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
+
+// Resolve maps a client-supplied resource name onto a catalog entry.
+// It returns (nil, nil) if no Kind matches, and *AmbiguousResourceError if
+// several do.
+func (c *Catalog) Resolve(resource string) (*CatalogEntry, error) {
+	if err := validateResourceName(resource); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	matched, err := resolveKind(c.kinds, resource)
+	if err != nil || len(matched) == 0 {
+		return nil, err
+	}
+
+	entry := *c.entries[matched[0].Kind]
+	return &entry, nil
+}
+
+// Entries returns a copy of every catalog entry, sorted by Kind
+func (c *Catalog) Entries() []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]CatalogEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
+
+// ProtoFiles returns the vendored .proto files that live under a directory
+// named after service
+func (c *Catalog) ProtoFiles(service string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return protoFilesForService(c.protos, service)
+}
+
+// ProtoIndex parses the protos of a service together with every vendored
+// file they import, directly or transitively. Imports that are not vendored
+// and files that fail to parse are skipped; their types resolve as
+// protoparser.KindUnknown.
+func (c *Catalog) ProtoIndex(service string) *protoparser.Index {
+	return c.protoIndex(c.ProtoFiles(service))
+}
+
+// ProtoIndexAll parses every vendored proto. The first call is slow on a
+// full googleapis tree; later calls reuse the parse cache.
+func (c *Catalog) ProtoIndexAll() *protoparser.Index {
+	c.mu.RLock()
+	protos := slices.Clone(c.protos)
+	c.mu.RUnlock()
+	return c.protoIndex(protos)
+}
+
+// protoIndex parses seeds and the vendored files they import
+func (c *Catalog) protoIndex(seeds []string) *protoparser.Index {
+	queue := slices.Clone(seeds)
+	direct := len(queue)
+	seen := make(map[string]bool)
+	var files []*protoparser.File
+
+	for i := 0; i < len(queue); i++ {
+		relPath := queue[i]
+		if seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+
+		f, err := c.protoCache.Load(filepath.Join(c.repoPath, filepath.FromSlash(relPath)), relPath)
+		if errors.Is(err, fs.ErrNotExist) && i >= direct {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping proto: %v\n", err)
+			continue
+		}
+		files = append(files, f)
+
+		for _, imp := range f.Imports {
+			queue = append(queue, path.Join(protoRoot, imp))
+		}
+	}
+	return protoparser.NewIndex(files...)
+}
+
+// protoFilesForService filters protos down to those under a directory named service
+func protoFilesForService(protos []string, service string) []string {
+	segment := "/" + service + "/"
+	var result []string
+	for _, p := range protos {
+		if strings.Contains(p, segment) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// buildCatalogEntries assembles per-Kind entries from the parsed files
+func buildCatalogEntries(files map[string]*catalogFile, protos []string) ([]ResourceKind, map[string]*CatalogEntry) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var kinds []ResourceKind
+	byKind := make(map[string][]ResourceKind)
+	models := make(map[string]string)
+	fixtures := make(map[string][]string)
+	controllers := make(map[string]controllerConfig)
+	crdControllers := make(map[string]string)
+	crdFiles := make(map[string]string)
+	var crdKinds []ResourceKind
+
+	for _, p := range paths {
+		f := files[p]
+		for _, k := range f.kinds {
+			kinds = append(kinds, k)
+			byKind[k.Kind] = append(byKind[k.Kind], k)
+		}
+		for _, kind := range f.models {
+			if _, ok := models[kind]; !ok {
+				models[kind] = p
+			}
+		}
+		if f.fixture != "" {
+			fixtures[f.fixture] = append(fixtures[f.fixture], path.Dir(p))
+		}
+		for kind, cfg := range f.controllers {
+			controllers[kind] = cfg
+		}
+		if f.crdKind != "" {
+			crdFiles[f.crdKind] = p
+			if f.crdService != "" && f.crdVersion != "" {
+				crdKinds = append(crdKinds, ResourceKind{Kind: f.crdKind, Service: f.crdService, Version: f.crdVersion, TypesFile: p, Source: "crd"})
+			}
+		}
+		if f.crdKind != "" && f.crdController != "" {
+			crdControllers[f.crdKind] = f.crdController
+		}
+	}
+
+	// Kinds without Go types are still known from their CRD, so that
+	// resources yet to be migrated can be resolved
+	for _, k := range crdKinds {
+		if _, ok := byKind[k.Kind]; !ok {
+			kinds = append(kinds, k)
+			byKind[k.Kind] = []ResourceKind{k}
+		}
+	}
+
+	entries := make(map[string]*CatalogEntry, len(byKind))
+	for kind, sources := range byKind {
+		sortKindEntries(sources)
+		direct := filterKindsBySource(sources, "direct")
+		terraform := filterKindsBySource(sources, "terraform")
+
+		preferred := sources[0]
+		service := preferred.Service
+		version := preferred.Version
+
+		stem := typesFileStem(preferred.TypesFile)
+		if len(direct) == 0 {
+			stem = targetStem(kind, service)
+		}
+
+		versionSources := direct
+		if len(versionSources) == 0 {
+			versionSources = terraform
+		}
+		if len(versionSources) == 0 {
+			versionSources = sources
+		}
+		var versions []string
+		for _, s := range versionSources {
+			if !slices.Contains(versions, s.Version) {
+				versions = append(versions, s.Version)
+			}
+		}
+
+		e := &CatalogEntry{
+			Kind:              kind,
+			Group:             fmt.Sprintf("%s.cnrm.cloud.google.com", service),
+			Service:           service,
+			Version:           version,
+			Versions:          versions,
+			HasDirectTypes:    len(direct) > 0,
+			HasTerraformTypes: len(terraform) > 0,
+			TypesFile:         fmt.Sprintf("apis/%s/%s/%s_types.go", service, version, stem),
+			IdentityFile:      fmt.Sprintf("apis/%s/%s/%s_identity.go", service, version, stem),
+			ControllerFile:    fmt.Sprintf("pkg/controller/direct/%s/%s_controller.go", service, stem),
+			MapperFile:        fmt.Sprintf("pkg/controller/direct/%s/mapper.generated.go", service),
+			MockGCPFile:       fmt.Sprintf("mockgcp/mock%s/%s.go", service, stem),
+			FixturesDir:       fmt.Sprintf("pkg/test/resourcefixture/testdata/basic/%s/%s/%s", service, version, stem),
+			CRDFile:           crdFiles[kind],
+			FixtureDirs:       fixtures[kind],
+			Sources:           sources,
+		}
+		e.GVK = fmt.Sprintf("%s/%s, Kind=%s", e.Group, e.Version, kind)
+
+		if len(direct) > 0 {
+			e.TypesFile = direct[0].TypesFile
+		}
+		if len(terraform) > 0 {
+			e.TerraformTypesFile = terraform[0].TypesFile
+		}
+		if controllerFile, ok := models[kind]; ok {
+			e.HasRegisteredModel = true
+			e.ControllerFile = controllerFile
+		}
+
+		var static *controllerConfig
+		if cfg, ok := controllers[kind]; ok {
+			static = &cfg
+		}
+		resolveControllers(e, static, crdControllers[kind])
+
+		if serviceProtos := protoFilesForService(protos, service); len(serviceProtos) > 0 {
+			e.ProtoLocation = serviceProtos[0]
+		}
+
+		entries[kind] = e
+	}
+
+	return kinds, entries
+}
+
+// targetStem derives the file stem of a not-yet-migrated Kind by dropping the
+// service prefix, following KCC's naming (ComputeURLMap -> urlmap)
+func targetStem(kind, service string) string {
+	return strings.ToLower(kindWithoutService(kind, service))
+}
+
+// ServiceTitle capitalises a service name as it prefixes Kinds and mock
+// server types (compute -> Compute)
+func ServiceTitle(service string) string {
+	return cases.Title(language.Und, cases.NoLower).String(service)
+}
+
+// kindWithoutService strips the service prefix from a Kind, ignoring case
+// (ComputeURLMap -> URLMap)
+func kindWithoutService(kind, service string) string {
+	if len(kind) > len(service) && strings.EqualFold(kind[:len(service)], service) {
+		return kind[len(service):]
+	}
+	return kind
+}
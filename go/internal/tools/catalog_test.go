@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newFixtureCatalog indexes testdata/catalog, a repository laid out like KCC
+// with direct, Terraform, DCL and CRD-only Kinds:
+//
+//	ComputeForwardingRule     direct and generated types, terraform by default
+//	ComputeRegionURLMap       generated types only (terraform)
+//	ComputeURLMap             generated types only (terraform)
+//	DataprocWorkflowTemplate  generated types only, DCL CRD
+//	PubSubLiteReservation     CRD only (terraform)
+//	RedisCluster              direct types and controller only
+func newFixtureCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	repo, err := filepath.Abs(filepath.Join("testdata", "catalog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := NewCatalog(repo)
+	if _, err := cat.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+// copyFixture copies testdata/catalog into a temporary repository that tests
+// may write to, and returns its catalog
+func copyFixture(t *testing.T) *Catalog {
+	t.Helper()
+	repo := t.TempDir()
+	if err := os.CopyFS(repo, os.DirFS(filepath.Join("testdata", "catalog"))); err != nil {
+		t.Fatal(err)
+	}
+	cat := NewCatalog(repo)
+	if _, err := cat.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func TestCatalogIndexesUnmigratedKinds(t *testing.T) {
+	cat := newFixtureCatalog(t)
+
+	tests := []struct {
+		resource       string
+		kind           string
+		service        string
+		versions       []string
+		controller     string
+		hasDirect      bool
+		hasTerraform   bool
+		typesFile      string // where the direct types are or will be
+		terraformTypes string
+	}{
+		{
+			resource:       "ComputeURLMap",
+			kind:           "ComputeURLMap",
+			service:        "compute",
+			versions:       []string{"v1beta1"},
+			controller:     "terraform",
+			hasTerraform:   true,
+			typesFile:      "apis/compute/v1beta1/urlmap_types.go",
+			terraformTypes: "pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go",
+		},
+		{
+			resource:       "dataprocworkflowtemplate",
+			kind:           "DataprocWorkflowTemplate",
+			service:        "dataproc",
+			versions:       []string{"v1beta1"},
+			controller:     "dcl",
+			hasTerraform:   true,
+			typesFile:      "apis/dataproc/v1beta1/workflowtemplate_types.go",
+			terraformTypes: "pkg/clients/generated/apis/dataproc/v1beta1/dataprocworkflowtemplate_types.go",
+		},
+		{
+			resource:   "PubSubLiteReservation",
+			kind:       "PubSubLiteReservation",
+			service:    "pubsublite",
+			versions:   []string{"v1beta1"},
+			controller: "terraform",
+			typesFile:  "apis/pubsublite/v1beta1/reservation_types.go",
+		},
+		{
+			resource:       "ComputeForwardingRule",
+			kind:           "ComputeForwardingRule",
+			service:        "compute",
+			versions:       []string{"v1beta1"},
+			controller:     "terraform",
+			hasDirect:      true,
+			hasTerraform:   true,
+			typesFile:      "apis/compute/v1beta1/forwardingrule_types.go",
+			terraformTypes: "pkg/clients/generated/apis/compute/v1beta1/computeforwardingrule_types.go",
+		},
+		{
+			resource:   "RedisCluster",
+			kind:       "RedisCluster",
+			service:    "redis",
+			versions:   []string{"v1beta1"},
+			controller: "direct",
+			hasDirect:  true,
+			typesFile:  "apis/redis/v1beta1/cluster_types.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			e, err := cat.Resolve(tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e == nil {
+				t.Fatalf("%s is not in the catalog", tt.resource)
+			}
+			if e.Kind != tt.kind || e.Service != tt.service || fmt.Sprint(e.Versions) != fmt.Sprint(tt.versions) {
+				t.Errorf("got %s (%s, %v), want %s (%s, %v)", e.Kind, e.Service, e.Versions, tt.kind, tt.service, tt.versions)
+			}
+			if e.DefaultController != tt.controller {
+				t.Errorf("default controller = %s, want %s", e.DefaultController, tt.controller)
+			}
+			if e.HasDirectTypes != tt.hasDirect || e.HasTerraformTypes != tt.hasTerraform {
+				t.Errorf("direct types = %t, generated types = %t", e.HasDirectTypes, e.HasTerraformTypes)
+			}
+			if e.TypesFile != tt.typesFile || e.TerraformTypesFile != tt.terraformTypes {
+				t.Errorf("types files = %s, %s; want %s, %s", e.TypesFile, e.TerraformTypesFile, tt.typesFile, tt.terraformTypes)
+			}
+			if e.CRDFile == "" {
+				t.Error("CRD file not found")
+			}
+		})
+	}
+
+	if got := cat.Stats().Kinds; got != 6 {
+		t.Errorf("indexed %d Kinds, want 6", got)
+	}
+}
@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a burst of file events has to settle before the
+// catalog is refreshed, so a checkout or a scaffold refreshes it once
+const watchDebounce = 200 * time.Millisecond
+
+// Watch keeps the catalog up to date until ctx is cancelled. It watches the
+// indexed trees and refreshes shortly after a file in them changes. If the
+// trees cannot be watched, e.g. because the inotify watch limit is reached,
+// it falls back to refreshing every interval.
+func (c *Catalog) Watch(ctx context.Context, interval time.Duration) {
+	w, err := newCatalogWatcher(c.repoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot watch the repository, refreshing the catalog every %s instead: %v\n", interval, err)
+		c.poll(ctx, interval)
+		return
+	}
+	defer w.close()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.events():
+			if !ok {
+				return
+			}
+			relevant, err := w.handle(event)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: cannot watch the repository, refreshing the catalog every %s instead: %v\n", interval, err)
+				w.close()
+				c.refresh()
+				c.poll(ctx, interval)
+				return
+			}
+			if relevant {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-w.errors():
+			if !ok {
+				return
+			}
+			// Events may have been lost, so refresh either way
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				fmt.Fprintf(os.Stderr, "Warning: watching the repository: %v\n", err)
+			}
+			debounce.Reset(watchDebounce)
+		case <-debounce.C:
+			c.refresh()
+		}
+	}
+}
+
+// poll refreshes the catalog every interval until ctx is cancelled
+func (c *Catalog) poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refresh()
+		}
+	}
+}
+
+// refresh runs a background refresh, which has no caller to report to
+func (c *Catalog) refresh() {
+	if _, err := c.Refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: catalog refresh failed: %v\n", err)
+	}
+}
+
+// catalogWatcher watches every directory of the catalog's source trees. A
+// tree that does not exist yet is covered by watching its nearest existing
+// ancestor, so it is picked up once it is created.
+type catalogWatcher struct {
+	watcher *fsnotify.Watcher
+	roots   []string        // absolute paths of the source trees
+	watched map[string]bool // directories currently watched
+}
+
+func newCatalogWatcher(repoPath string) (*catalogWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &catalogWatcher{watcher: watcher, watched: make(map[string]bool)}
+	for _, src := range catalogSources {
+		w.roots = append(w.roots, filepath.Join(repoPath, filepath.FromSlash(src.root)))
+	}
+	if err := w.watchRoots(); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *catalogWatcher) events() <-chan fsnotify.Event { return w.watcher.Events }
+func (w *catalogWatcher) errors() <-chan error          { return w.watcher.Errors }
+func (w *catalogWatcher) close()                        { w.watcher.Close() }
+
+// watchRoots watches each source tree, or its nearest existing ancestor if
+// the tree does not exist
+func (w *catalogWatcher) watchRoots() error {
+	for _, root := range w.roots {
+		dir := root
+		for !isDir(dir) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+		var err error
+		if dir == root {
+			err = w.addTree(root)
+		} else {
+			err = w.add(dir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTree watches dir and every directory below it
+func (w *catalogWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Removed while walking; its parent's events cover that
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.add(path)
+	})
+}
+
+func (w *catalogWatcher) add(dir string) error {
+	if w.watched[dir] {
+		return nil
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	w.watched[dir] = true
+	return nil
+}
+
+// forget drops dir and the directories below it, whose watches the kernel
+// removed along with them
+func (w *catalogWatcher) forget(dir string) {
+	for path := range w.watched {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			w.watcher.Remove(path)
+			delete(w.watched, path)
+		}
+	}
+}
+
+// handle updates the watches for an event and reports whether it concerns
+// the source trees
+func (w *catalogWatcher) handle(event fsnotify.Event) (bool, error) {
+	inRoot, aboveRoot := false, false
+	for _, root := range w.roots {
+		switch {
+		case event.Name == root || strings.HasPrefix(event.Name, root+string(filepath.Separator)):
+			inRoot = true
+		case strings.HasPrefix(root, event.Name+string(filepath.Separator)):
+			aboveRoot = true
+		}
+	}
+	if !inRoot && !aboveRoot || event.Op == fsnotify.Chmod {
+		return false, nil
+	}
+
+	switch {
+	case event.Has(fsnotify.Create) && isDir(event.Name):
+		if inRoot {
+			return true, w.addTree(event.Name)
+		}
+		// A missing tree, or a directory on the way to it, was created
+		return true, w.watchRoots()
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		if w.watched[event.Name] {
+			w.forget(event.Name)
+			return true, w.watchRoots()
+		}
+	}
+	return true, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatchRefreshesOnChange(t *testing.T) {
+	cat := newTestRepo(t, readTestdata(t, "base.go.in"))
+	repo := cat.RepoPath()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cat.Watch(ctx, time.Hour) // polling would not refresh within the test
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// Give the watcher time to set up before changing files
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		name string
+		path string
+		kind string // Kind the catalog resolves afterwards, if any
+	}{
+		{name: "new directory in a tree", path: "apis/gadgets/v1alpha1/gadget_types.go", kind: "GadgetsGadget"},
+		{name: "tree created after start", path: "config/crds/resources/apiextensions.k8s.io_v1_customresourcedefinition_gizmos.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracked := cat.Stats().FilesTracked
+			content := "kind: CustomResourceDefinition\n"
+			if tt.kind != "" {
+				content = "package v1alpha1\n\nvar " + tt.kind + "GVK = GroupVersion.WithKind(\"" + tt.kind + "\")\n"
+			}
+			path := filepath.Join(repo, filepath.FromSlash(tt.path))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			waitFor(t, tt.path+" to be indexed", func() bool {
+				return cat.Stats().FilesTracked == tracked+1
+			})
+			if tt.kind != "" {
+				if _, err := cat.Resolve(tt.kind); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+
+	t.Run("removed file", func(t *testing.T) {
+		tracked := cat.Stats().FilesTracked
+		if err := os.RemoveAll(filepath.Join(repo, "apis", "gadgets")); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "the removed file to be dropped", func() bool {
+			return cat.Stats().FilesTracked == tracked-1
+		})
+	})
+}
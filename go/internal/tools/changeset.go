@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/oplog"
+)
+
+// File change actions
+const (
+	ActionCreate = "create"
+	ActionModify = "modify"
+)
+
+// FileChange is one file a tool writes
+type FileChange struct {
+	Path   string `json:"path"`   // relative to the repository
+	Action string `json:"action"` // "create" or "modify"
+	abs    string
+	before []byte
+	after  []byte
+}
+
+// ChangePreview is what a dry run returns instead of writing
+type ChangePreview struct {
+	DryRun  bool         `json:"dry_run"`
+	Files   []FileChange `json:"files"`
+	Diff    string       `json:"diff"`
+	Summary string       `json:"summary,omitempty"` // the tool's result, had it written the files
+}
+
+// ChangeSet collects the files a mutating tool writes, so the whole change
+// can be previewed as a diff before anything touches the working tree. With
+// an operation attached, every write is recorded with its before-image so
+// it can be undone.
+type ChangeSet struct {
+	repoPath string
+	dryRun   bool
+	changes  []*FileChange
+	op       *oplog.Operation
+}
+
+// NewChangeSet returns an empty change set for the repository. With dryRun,
+// Apply writes nothing.
+func NewChangeSet(repoPath string, dryRun bool) *ChangeSet {
+	return &ChangeSet{repoPath: repoPath, dryRun: dryRun}
+}
+
+// WithOperation records the change set's writes in op
+func (c *ChangeSet) WithOperation(op *oplog.Operation) *ChangeSet {
+	c.op = op
+	return c
+}
+
+// OperationID returns the ID under which the writes were logged, or "" if
+// nothing was logged
+func (c *ChangeSet) OperationID() string {
+	if c.op == nil || len(c.op.Files) == 0 {
+		return ""
+	}
+	return c.op.ID
+}
+
+// DryRun reports whether the change set only previews its changes
+func (c *ChangeSet) DryRun() bool {
+	return c != nil && c.dryRun
+}
+
+// Write records the new content of the file at path (absolute). Writing the
+// same file again replaces the pending content; writing unchanged content
+// records nothing. Paths outside the writable KCC subtrees are rejected with
+// a PathError.
+func (c *ChangeSet) Write(path string, content []byte) error {
+	path, err := ResolveWritePath(c.repoPath, path)
+	if err != nil {
+		return err
+	}
+	for _, change := range c.changes {
+		if change.abs == path {
+			change.after = content
+			return nil
+		}
+	}
+	change := &FileChange{Path: repoRelPath(c.repoPath, path), Action: ActionCreate, abs: path, after: content}
+	if before, err := os.ReadFile(path); err == nil {
+		if bytes.Equal(before, content) {
+			return nil
+		}
+		change.Action = ActionModify
+		change.before = before
+	}
+	c.changes = append(c.changes, change)
+	return nil
+}
+
+// Content returns the pending content of path, or what is on disk if the
+// change set does not touch it
+func (c *ChangeSet) Content(path string) ([]byte, error) {
+	path, err := resolveRepoPath(c.repoPath, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range c.changes {
+		if change.abs == path {
+			return change.after, nil
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// checkpoint returns the changes recorded so far, for restore
+func (c *ChangeSet) checkpoint() []FileChange {
+	return c.Files()
+}
+
+// restore drops the changes recorded since checkpoint returned files
+func (c *ChangeSet) restore(files []FileChange) {
+	c.changes = make([]*FileChange, len(files))
+	for i := range files {
+		change := files[i]
+		c.changes[i] = &change
+	}
+}
+
+// Files lists the recorded changes in the order they were made
+func (c *ChangeSet) Files() []FileChange {
+	files := make([]FileChange, 0, len(c.changes))
+	for _, change := range c.changes {
+		files = append(files, *change)
+	}
+	return files
+}
+
+// Apply writes the recorded changes, creating directories as needed. Each
+// file is replaced atomically and its before-image logged first. It is a
+// no-op for a dry run.
+func (c *ChangeSet) Apply() error {
+	if c.dryRun {
+		return nil
+	}
+	// Whatever was written before a failure is still logged, so it can be
+	// undone
+	defer c.Save()
+	for _, change := range c.changes {
+		if err := c.Capture(change.abs); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(change.abs), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		perm := os.FileMode(0644)
+		if info, err := os.Stat(change.abs); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if err := oplog.WriteFile(change.abs, change.after, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Capture logs the before-images of files something other than the change
+// set, such as the mapper generator, is about to write. They are subject to
+// the same confinement as Write.
+func (c *ChangeSet) Capture(paths ...string) error {
+	if c.dryRun {
+		return nil
+	}
+	for _, path := range paths {
+		path, err := ResolveWritePath(c.repoPath, path)
+		if err != nil {
+			return err
+		}
+		if err := c.op.Capture(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save persists the operation log entry for the writes made so far. Failing
+// to log is reported but does not fail the tool: the writes have happened.
+func (c *ChangeSet) Save() {
+	if err := c.op.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record operation: %v\n", err)
+	}
+}
+
+// Preview returns the changes as a unified diff
+func (c *ChangeSet) Preview() *ChangePreview {
+	var b strings.Builder
+	for _, change := range c.changes {
+		from := "a/" + change.Path
+		if change.Action == ActionCreate {
+			from = "/dev/null"
+		}
+		b.WriteString(unifiedDiff(from, "b/"+change.Path, string(change.before), string(change.after)))
+	}
+	return &ChangePreview{DryRun: c.dryRun, Files: c.Files(), Diff: b.String()}
+}
+
+// FormatChangePreview renders a dry run: the files that would be written,
+// the tool's own summary and the diff
+func FormatChangePreview(preview *ChangePreview) string {
+	var b strings.Builder
+	b.WriteString("🔍 Dry run: no files were written\n\n")
+	if len(preview.Files) == 0 {
+		b.WriteString("No files would change\n")
+	}
+	for _, f := range preview.Files {
+		verb := "Would modify"
+		if f.Action == ActionCreate {
+			verb = "Would create"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", verb, f.Path)
+	}
+	if preview.Summary != "" {
+		fmt.Fprintf(&b, "\nResult if applied:\n%s\n", preview.Summary)
+	}
+	if preview.Diff != "" {
+		fmt.Fprintf(&b, "\n%s", preview.Diff)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
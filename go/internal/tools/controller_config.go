@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ReconcilerAnnotation selects the controller of a resource that supports
+// more than one
+const ReconcilerAnnotation = "alpha.cnrm.cloud.google.com/reconciler"
+
+// staticConfigFile declares the default and supported controllers per Kind
+const staticConfigFile = "pkg/controller/resourceconfig/static_config.go"
+
+// controllerConfig is the set of controllers able to reconcile a Kind
+type controllerConfig struct {
+	defaultController    string
+	supportedControllers []string
+}
+
+var (
+	// staticConfigEntryPattern matches one entry of ControllerConfigStatic:
+	//
+	//	{Group: "compute.cnrm.cloud.google.com", Kind: "ComputeURLMap"}: {
+	//		DefaultController:    k8s.ReconcilerTypeTerraform,
+	//		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeTerraform, k8s.ReconcilerTypeDirect},
+	//	},
+	staticConfigEntryPattern = regexp.MustCompile(`\{\s*Group:\s*"[^"]*",\s*Kind:\s*"([A-Za-z0-9]+)"\s*\}:\s*\{\s*DefaultController:\s*k8s\.ReconcilerType(\w+),\s*SupportedControllers:\s*\[\]k8s\.ReconcilerType\{([^}]*)\}`)
+
+	// reconcilerTypePattern matches a single k8s.ReconcilerTypeX constant
+	reconcilerTypePattern = regexp.MustCompile(`k8s\.ReconcilerType(\w+)`)
+
+	// crdKindPattern matches spec.names.kind of a CRD manifest
+	crdKindPattern = regexp.MustCompile(`(?m)^\s+kind:\s*([A-Za-z0-9]+)\s*$`)
+
+	// crdGroupPattern matches spec.group of a KCC CRD manifest
+	crdGroupPattern = regexp.MustCompile(`(?m)^  group:\s*([a-z0-9]+)\.cnrm\.cloud\.google\.com\s*$`)
+
+	// crdVersionPattern matches the name of an entry of spec.versions
+	crdVersionPattern = regexp.MustCompile(`(?m)^  (?:- |  )name:\s*(v[0-9]+(?:(?:alpha|beta)[0-9]*)?)\s*$`)
+)
+
+// parseStaticControllerConfig extracts the per-Kind controller configuration
+// from static_config.go
+func parseStaticControllerConfig(content string) map[string]controllerConfig {
+	configs := make(map[string]controllerConfig)
+	for _, m := range staticConfigEntryPattern.FindAllStringSubmatch(content, -1) {
+		cfg := controllerConfig{defaultController: reconcilerName(m[2])}
+		for _, t := range reconcilerTypePattern.FindAllStringSubmatch(m[3], -1) {
+			cfg.supportedControllers = append(cfg.supportedControllers, reconcilerName(t[1]))
+		}
+		configs[m[1]] = cfg
+	}
+	return configs
+}
+
+// parseCRDController returns the Kind of a CRD manifest and the legacy
+// controller its generator labels declare ("terraform", "dcl", or "")
+func parseCRDController(content string) (kind, controller string) {
+	if m := crdKindPattern.FindStringSubmatch(content); m != nil {
+		kind = m[1]
+	}
+	switch {
+	case strings.Contains(content, `cnrm.cloud.google.com/dcl2crd: "true"`):
+		controller = "dcl"
+	case strings.Contains(content, `cnrm.cloud.google.com/tf2crd: "true"`):
+		controller = "terraform"
+	}
+	return kind, controller
+}
+
+// parseCRDGroupVersion returns the service of a KCC CRD manifest and its most
+// stable version, or "" if the manifest does not declare them
+func parseCRDGroupVersion(content string) (service, version string) {
+	if m := crdGroupPattern.FindStringSubmatch(content); m != nil {
+		service = m[1]
+	}
+	for _, m := range crdVersionPattern.FindAllStringSubmatch(content, -1) {
+		if version == "" || versionRank(m[1]) > versionRank(version) {
+			version = m[1]
+		}
+	}
+	return service, version
+}
+
+// reconcilerName maps a k8s.ReconcilerTypeX suffix onto the controller names
+// used in tool output
+func reconcilerName(suffix string) string {
+	return strings.ToLower(suffix)
+}
+
+// resolveControllers fills in the controller fields of a catalog entry.
+// static_config.go is authoritative; otherwise the CRD generator labels and
+// the presence of a registered direct model are combined. Types under apis/
+// alone do not make a Kind direct while generated Terraform types exist. A
+// Kind that has both a direct and a legacy controller is hybrid: without
+// static configuration the legacy controller stays the default and direct is
+// opted into with the reconciler annotation.
+func resolveControllers(e *CatalogEntry, static *controllerConfig, crdController string) {
+	switch {
+	case static != nil:
+		e.ControllerSource = "static_config"
+		e.DefaultController = static.defaultController
+		e.SupportedControllers = static.supportedControllers
+
+	case crdController != "":
+		e.ControllerSource = "crd"
+		e.DefaultController = crdController
+		e.SupportedControllers = []string{crdController}
+		if e.HasRegisteredModel {
+			e.SupportedControllers = append(e.SupportedControllers, "direct")
+		}
+
+	default:
+		e.ControllerSource = "heuristic"
+		switch {
+		case e.HasRegisteredModel:
+			e.DefaultController = "direct"
+		case e.HasTerraformTypes:
+			e.DefaultController = "terraform"
+		case e.HasDirectTypes:
+			e.DefaultController = "direct"
+		default:
+			e.DefaultController = "unknown"
+		}
+		e.SupportedControllers = []string{e.DefaultController}
+	}
+
+	e.ControllerType = e.DefaultController
+	e.Hybrid = slices.Contains(e.SupportedControllers, "direct") && len(e.SupportedControllers) > 1
+}
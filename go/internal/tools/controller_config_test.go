@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStaticControllerConfig(t *testing.T) {
+	content := `package resourceconfig
+
+import (
+	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/k8s"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var ControllerConfigStatic = map[schema.GroupKind]ControllerConfig{
+	{Group: "alloydb.cnrm.cloud.google.com", Kind: "AlloyDBBackup"}: {DefaultController: k8s.ReconcilerTypeDirect, SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeDirect}},
+	{Group: "compute.cnrm.cloud.google.com", Kind: "ComputeForwardingRule"}: {
+		DefaultController:    k8s.ReconcilerTypeTerraform,
+		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeTerraform, k8s.ReconcilerTypeDirect},
+	},
+	{Group: "dataproc.cnrm.cloud.google.com", Kind: "DataprocWorkflowTemplate"}: {
+		DefaultController:    k8s.ReconcilerTypeDCL,
+		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeDCL},
+	},
+	// {Group: "redis.cnrm.cloud.google.com", Kind: "RedisInstance"}: nothing to see
+}
+`
+	want := map[string]controllerConfig{
+		"AlloyDBBackup":            {defaultController: "direct", supportedControllers: []string{"direct"}},
+		"ComputeForwardingRule":    {defaultController: "terraform", supportedControllers: []string{"terraform", "direct"}},
+		"DataprocWorkflowTemplate": {defaultController: "dcl", supportedControllers: []string{"dcl"}},
+	}
+	if got := parseStaticControllerConfig(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseStaticControllerConfig() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// crdManifest returns a CRD manifest as KCC generates it
+func crdManifest(label, group, kind string, versions ...string) string {
+	content := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  annotations:
+    cnrm.cloud.google.com/version: 0.0.0-dev
+  creationTimestamp: null
+  labels:
+` + label + `    cnrm.cloud.google.com/managed-by-kcc: "true"
+    cnrm.cloud.google.com/system: "true"
+  name: ` + group + `
+spec:
+  group: ` + group + `
+  names:
+    categories:
+    - gcp
+    kind: ` + kind + `
+    listKind: ` + kind + `List
+  scope: Namespaced
+  versions:
+`
+	for _, v := range versions {
+		content += `  - additionalPrinterColumns:
+    - jsonPath: .metadata.creationTimestamp
+      name: Age
+      type: date
+    name: ` + v + `
+    schema:
+      openAPIV3Schema:
+        properties:
+          kind:
+            description: 'Kind is a string value representing the REST resource'
+            type: string
+`
+	}
+	return content
+}
+
+func TestParseCRD(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		wantKind       string
+		wantController string
+		wantService    string
+		wantVersion    string
+	}{
+		{
+			name:           "dcl2crd",
+			content:        crdManifest("    cnrm.cloud.google.com/dcl2crd: \"true\"\n", "dataproc.cnrm.cloud.google.com", "DataprocWorkflowTemplate", "v1beta1"),
+			wantKind:       "DataprocWorkflowTemplate",
+			wantController: "dcl",
+			wantService:    "dataproc",
+			wantVersion:    "v1beta1",
+		},
+		{
+			name:           "tf2crd with several versions",
+			content:        crdManifest("    cnrm.cloud.google.com/tf2crd: \"true\"\n", "pubsublite.cnrm.cloud.google.com", "PubSubLiteReservation", "v1alpha1", "v1beta1"),
+			wantKind:       "PubSubLiteReservation",
+			wantController: "terraform",
+			wantService:    "pubsublite",
+			wantVersion:    "v1beta1",
+		},
+		{
+			name:        "direct CRD",
+			content:     crdManifest("", "redis.cnrm.cloud.google.com", "RedisCluster", "v1alpha1", "v1", "v1beta1"),
+			wantKind:    "RedisCluster",
+			wantService: "redis",
+			wantVersion: "v1",
+		},
+		{
+			name:     "non-KCC group",
+			content:  crdManifest("", "example.com", "Gizmo", "v1"),
+			wantKind: "Gizmo",
+			// The version is still found; callers only use it with a service
+			wantVersion: "v1",
+		},
+		{
+			name:    "not a CRD",
+			content: "apiVersion: v1\nkind: ConfigMap\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, controller := parseCRDController(tt.content)
+			if kind != tt.wantKind || controller != tt.wantController {
+				t.Errorf("parseCRDController() = %q, %q, want %q, %q", kind, controller, tt.wantKind, tt.wantController)
+			}
+			service, version := parseCRDGroupVersion(tt.content)
+			if service != tt.wantService || version != tt.wantVersion {
+				t.Errorf("parseCRDGroupVersion() = %q, %q, want %q, %q", service, version, tt.wantService, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestResolveControllers(t *testing.T) {
+	tests := []struct {
+		name          string
+		entry         CatalogEntry
+		static        *controllerConfig
+		crdController string
+		wantSource    string
+		wantDefault   string
+		wantSupported []string
+		wantHybrid    bool
+	}{
+		{
+			name:          "static config overrides CRD label and model",
+			entry:         CatalogEntry{HasRegisteredModel: true, HasDirectTypes: true, HasTerraformTypes: true},
+			static:        &controllerConfig{defaultController: "terraform", supportedControllers: []string{"terraform", "direct"}},
+			crdController: "dcl",
+			wantSource:    "static_config",
+			wantDefault:   "terraform",
+			wantSupported: []string{"terraform", "direct"},
+			wantHybrid:    true,
+		},
+		{
+			name:          "static direct",
+			entry:         CatalogEntry{HasTerraformTypes: true},
+			static:        &controllerConfig{defaultController: "direct", supportedControllers: []string{"direct"}},
+			crdController: "terraform",
+			wantSource:    "static_config",
+			wantDefault:   "direct",
+			wantSupported: []string{"direct"},
+		},
+		{
+			name:          "tf2crd",
+			entry:         CatalogEntry{HasDirectTypes: true, HasTerraformTypes: true},
+			crdController: "terraform",
+			wantSource:    "crd",
+			wantDefault:   "terraform",
+			wantSupported: []string{"terraform"},
+		},
+		{
+			name:          "dcl2crd with a registered model",
+			entry:         CatalogEntry{HasRegisteredModel: true, HasDirectTypes: true},
+			crdController: "dcl",
+			wantSource:    "crd",
+			wantDefault:   "dcl",
+			wantSupported: []string{"dcl", "direct"},
+			wantHybrid:    true,
+		},
+		{
+			name:          "registered model",
+			entry:         CatalogEntry{HasRegisteredModel: true, HasDirectTypes: true, HasTerraformTypes: true},
+			wantSource:    "heuristic",
+			wantDefault:   "direct",
+			wantSupported: []string{"direct"},
+		},
+		{
+			name:          "direct types beside terraform types",
+			entry:         CatalogEntry{HasDirectTypes: true, HasTerraformTypes: true},
+			wantSource:    "heuristic",
+			wantDefault:   "terraform",
+			wantSupported: []string{"terraform"},
+		},
+		{
+			name:          "direct types only",
+			entry:         CatalogEntry{HasDirectTypes: true},
+			wantSource:    "heuristic",
+			wantDefault:   "direct",
+			wantSupported: []string{"direct"},
+		},
+		{
+			name:          "nothing known",
+			wantSource:    "heuristic",
+			wantDefault:   "unknown",
+			wantSupported: []string{"unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.entry
+			resolveControllers(&e, tt.static, tt.crdController)
+			if e.ControllerSource != tt.wantSource || e.DefaultController != tt.wantDefault || e.ControllerType != tt.wantDefault {
+				t.Errorf("source %s, default %s, type %s; want %s, %s", e.ControllerSource, e.DefaultController, e.ControllerType, tt.wantSource, tt.wantDefault)
+			}
+			if !reflect.DeepEqual(e.SupportedControllers, tt.wantSupported) || e.Hybrid != tt.wantHybrid {
+				t.Errorf("supported %v, hybrid %t; want %v, %t", e.SupportedControllers, e.Hybrid, tt.wantSupported, tt.wantHybrid)
+			}
+		})
+	}
+}
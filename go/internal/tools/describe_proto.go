@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
+)
+
+// DescribeProtoParams contains parameters for describing a proto message
+type DescribeProtoParams struct {
+	Message string `json:"message"`           // full name (google.cloud.compute.v1.Network) or short name (Network)
+	Service string `json:"service,omitempty"` // KCC service to search, e.g. "compute"; all vendored protos when empty
+}
+
+// ProtoFieldInfo describes a single field of a proto message
+type ProtoFieldInfo struct {
+	Name              string                         `json:"name"`
+	Number            int                            `json:"number"`
+	Type              string                         `json:"type"`          // as declared, e.g. "repeated NetworkPeering"
+	TypeKind          string                         `json:"type_kind"`     // "scalar", "message", "enum", or "unknown"
+	ResolvedType      string                         `json:"resolved_type"` // full name of message and enum types
+	Repeated          bool                           `json:"repeated"`
+	Map               bool                           `json:"map"`
+	Oneof             string                         `json:"oneof,omitempty"`
+	Comment           string                         `json:"comment,omitempty"`
+	FieldBehavior     []string                       `json:"field_behavior,omitempty"`
+	ResourceReference *protoparser.ResourceReference `json:"resource_reference,omitempty"`
+	Deprecated        bool                           `json:"deprecated,omitempty"`
+	EnumValues        []string                       `json:"enum_values,omitempty"`
+}
+
+// ProtoRPCInfo describes an RPC of the service that owns a message
+type ProtoRPCInfo struct {
+	Service         string `json:"service"`
+	Name            string `json:"name"`
+	InputType       string `json:"input_type"`
+	OutputType      string `json:"output_type"`
+	HTTPMethod      string `json:"http_method,omitempty"`
+	HTTPPath        string `json:"http_path,omitempty"`
+	LROResponseType string `json:"lro_response_type,omitempty"`
+	Comment         string `json:"comment,omitempty"`
+}
+
+// ProtoMessageInfo is the result of DescribeProto
+type ProtoMessageInfo struct {
+	FullName       string                `json:"full_name"`
+	File           string                `json:"file"`
+	Comment        string                `json:"comment,omitempty"`
+	Resource       *protoparser.Resource `json:"resource,omitempty"`
+	Fields         []ProtoFieldInfo      `json:"fields"`
+	Oneofs         []string              `json:"oneofs,omitempty"`
+	NestedMessages []string              `json:"nested_messages,omitempty"`
+	NestedEnums    []string              `json:"nested_enums,omitempty"`
+	Services       []string              `json:"services"`
+	RPCs           []ProtoRPCInfo        `json:"rpcs"`
+	OtherMatches   []string              `json:"other_matches,omitempty"`
+}
+
+// DescribeProto finds a message in the vendored googleapis protos and
+// describes its fields, resource annotation and the RPCs of its service
+func DescribeProto(cat *Catalog, params DescribeProtoParams) (*ProtoMessageInfo, error) {
+	if params.Message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+
+	var index *protoparser.Index
+	if params.Service != "" {
+		index = cat.ProtoIndex(params.Service)
+	} else {
+		index = cat.ProtoIndexAll()
+	}
+
+	candidates := index.FindMessages(params.Message)
+	if len(candidates) == 0 {
+		scope := fmt.Sprintf("the %d vendored protos under %s", len(index.Files()), protoRoot)
+		if params.Service != "" {
+			scope = fmt.Sprintf("the %s protos (%d files, including imports)", params.Service, len(index.Files()))
+		}
+		return nil, fmt.Errorf("no proto message named %s in %s\n\nUse the full name, e.g. google.cloud.%s.v1.%s",
+			params.Message, scope, strings.ToLower(defaultIfEmpty(params.Service, "compute")), params.Message)
+	}
+	sortProtoCandidates(candidates)
+
+	info := describeMessage(index, candidates[0])
+	for _, c := range candidates[1:] {
+		info.OtherMatches = append(info.OtherMatches, c.FullName)
+	}
+	return info, nil
+}
+
+// describeMessage builds the description of a resolved message
+func describeMessage(index *protoparser.Index, msg *protoparser.Message) *ProtoMessageInfo {
+	info := &ProtoMessageInfo{
+		FullName: msg.FullName,
+		File:     msg.File.Path,
+		Comment:  msg.Comment,
+		Resource: msg.Resource,
+		Fields:   []ProtoFieldInfo{},
+		Oneofs:   msg.Oneofs,
+		Services: []string{},
+		RPCs:     []ProtoRPCInfo{},
+	}
+
+	for _, f := range msg.Fields {
+		kind, resolved := index.FieldType(msg, f)
+		field := ProtoFieldInfo{
+			Name:              f.Name,
+			Number:            f.Number,
+			Type:              protoTypeString(f),
+			TypeKind:          string(kind),
+			ResolvedType:      resolved,
+			Repeated:          f.IsRepeated(),
+			Map:               f.IsMap(),
+			Oneof:             f.Oneof,
+			Comment:           f.Comment,
+			FieldBehavior:     f.FieldBehavior,
+			ResourceReference: f.ResourceReference,
+			Deprecated:        f.Deprecated,
+		}
+		if e := index.Enum(resolved); kind == protoparser.KindEnum && e != nil {
+			for _, v := range e.Values {
+				field.EnumValues = append(field.EnumValues, v.Name)
+			}
+		}
+		info.Fields = append(info.Fields, field)
+	}
+	for _, nested := range msg.Messages {
+		info.NestedMessages = append(info.NestedMessages, nested.Name)
+	}
+	for _, e := range msg.Enums {
+		info.NestedEnums = append(info.NestedEnums, e.Name)
+	}
+
+	for _, svc := range index.ServicesFor(msg) {
+		info.Services = append(info.Services, svc.FullName)
+		for _, m := range svc.Methods {
+			info.RPCs = append(info.RPCs, ProtoRPCInfo{
+				Service:         svc.Name,
+				Name:            m.Name,
+				InputType:       m.InputType,
+				OutputType:      m.OutputType,
+				HTTPMethod:      strings.ToUpper(m.HTTPMethod),
+				HTTPPath:        m.HTTPPath,
+				LROResponseType: m.LROResponseType,
+				Comment:         m.Comment,
+			})
+		}
+	}
+	return info
+}
+
+// FormatProtoMessage renders a message description as text
+func FormatProtoMessage(info *ProtoMessageInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "message %s (%s)\n", info.FullName, info.File)
+	if info.Comment != "" {
+		fmt.Fprintf(&b, "%s\n", info.Comment)
+	}
+	if info.Resource != nil {
+		fmt.Fprintf(&b, "\nResource: %s\n", info.Resource.Type)
+		for _, p := range info.Resource.Patterns {
+			fmt.Fprintf(&b, "  pattern: %s\n", p)
+		}
+	}
+
+	b.WriteString("\n")
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tFIELD\tTYPE\tBEHAVIOR\tCOMMENT")
+	for _, f := range info.Fields {
+		name := f.Name
+		if f.Oneof != "" {
+			name = fmt.Sprintf("%s (oneof %s)", name, f.Oneof)
+		}
+		behavior := strings.Join(f.FieldBehavior, ",")
+		if f.ResourceReference != nil {
+			behavior = strings.TrimPrefix(behavior+",ref:"+f.ResourceReference.Type+f.ResourceReference.ChildType, ",")
+		}
+		if f.Deprecated {
+			behavior = strings.TrimPrefix(behavior+",DEPRECATED", ",")
+		}
+		comment, _, _ := strings.Cut(f.Comment, "\n")
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", f.Number, name, f.Type, dash(behavior), comment)
+	}
+	w.Flush()
+
+	if len(info.RPCs) > 0 {
+		fmt.Fprintf(&b, "\nRPCs (%s):\n", strings.Join(info.Services, ", "))
+		for _, r := range info.RPCs {
+			fmt.Fprintf(&b, "  %s.%s(%s) returns (%s)", r.Service, r.Name, r.InputType, r.OutputType)
+			if r.HTTPMethod != "" {
+				fmt.Fprintf(&b, "  %s %s", r.HTTPMethod, r.HTTPPath)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(info.OtherMatches) > 0 {
+		fmt.Fprintf(&b, "\nOther matches: %s\n", strings.Join(info.OtherMatches, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// defaultIfEmpty returns s, or def when s is empty
+func defaultIfEmpty(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
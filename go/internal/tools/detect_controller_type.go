@@ -2,111 +2,115 @@ package tools
 
 import (
 	"fmt"
-	"os/exec"
-	"strings"
+	"slices"
 )
 
 // ControllerTypeInfo contains information about a resource's controller type
 type ControllerTypeInfo struct {
-	Resource           string  `json:"resource"`
-	Type               string  `json:"type"` // "direct", "terraform", or "unknown"
-	Location           *string `json:"location"`
-	MigrationNeeded    bool    `json:"migration_needed"`
-	HasDirectTypes     bool    `json:"has_direct_types"`
-	HasTerraformTypes  bool    `json:"has_terraform_types"`
-	HasProto           bool    `json:"has_proto"`
-	ProtoLocation      *string `json:"proto_location"`
-	Service            *string `json:"service"`
-	Version            *string `json:"version"`
-}
+	Resource             string   `json:"resource"`
+	Kind                 *string  `json:"kind"`
+	Type                 string   `json:"type"` // default controller: "direct", "terraform", "dcl", or "unknown"
+	DefaultController    string   `json:"default_controller"`
+	SupportedControllers []string `json:"supported_controllers"`
+	Hybrid               bool     `json:"hybrid"`
+	ReconcilerAnnotation *string  `json:"reconciler_annotation"` // selects the non-default controller of a hybrid resource
+	ControllerSource     string   `json:"controller_source"`
+	Location             *string  `json:"location"`
+	MigrationNeeded      bool     `json:"migration_needed"`
+	HasDirectTypes       bool     `json:"has_direct_types"`
+	HasTerraformTypes    bool     `json:"has_terraform_types"`
+	HasProto             bool     `json:"has_proto"`
+	ProtoLocation        *string  `json:"proto_location"`
+	Service              *string  `json:"service"`
+	Version              *string  `json:"version"`
+	DirectCandidates     []string `json:"direct_candidates"`
+	TerraformCandidates  []string `json:"terraform_candidates"`
 
-// DetectControllerType detects if a resource uses direct controller or Terraform
-func DetectControllerType(repoPath, resource string) (*ControllerTypeInfo, error) {
-	resourceLower := strings.ToLower(resource)
+	// entry is the catalog entry the info was derived from, if any
+	entry *CatalogEntry
+}
 
-	// Check for direct controller types (apis/{service}/{version}/*_types.go)
-	directTypesCmd := fmt.Sprintf("find apis/ -name \"*%s*_types.go\" 2>/dev/null || true", resourceLower)
-	cmd := exec.Command("sh", "-c", directTypesCmd)
-	cmd.Dir = repoPath
-	directOutput, err := cmd.Output()
+// DetectControllerType detects whether a resource is reconciled by a direct,
+// Terraform or DCL controller, and which one is the default when it supports
+// more than one
+func DetectControllerType(cat *Catalog, resource string) (*ControllerTypeInfo, error) {
+	entry, err := cat.Resolve(resource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search for direct types: %w", err)
+		return nil, err
 	}
 
-	directTypesFiles := strings.Split(strings.TrimSpace(string(directOutput)), "\n")
-	directTypesFiles = removeEmpty(directTypesFiles)
+	return controllerTypeInfoForEntry(resource, entry), nil
+}
 
-	// Check for Terraform-based types (pkg/clients/generated/)
-	tfTypesCmd := fmt.Sprintf("find pkg/clients/generated/ -name \"*%s*types.go\" 2>/dev/null || true", resourceLower)
-	cmd = exec.Command("sh", "-c", tfTypesCmd)
-	cmd.Dir = repoPath
-	tfOutput, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to search for terraform types: %w", err)
+// controllerTypeInfoForEntry builds the controller info for a catalog entry;
+// a nil entry yields type "unknown"
+func controllerTypeInfoForEntry(resource string, entry *CatalogEntry) *ControllerTypeInfo {
+	info := &ControllerTypeInfo{
+		Resource:             resource,
+		Type:                 "unknown",
+		DefaultController:    "unknown",
+		SupportedControllers: []string{},
+		DirectCandidates:     []string{},
+		TerraformCandidates:  []string{},
+	}
+	if entry == nil {
+		return info
 	}
 
-	tfTypesFiles := strings.Split(strings.TrimSpace(string(tfOutput)), "\n")
-	tfTypesFiles = removeEmpty(tfTypesFiles)
-
-	hasDirectTypes := len(directTypesFiles) > 0
-	hasTerraformTypes := len(tfTypesFiles) > 0
-
-	controllerType := "unknown"
-	var location *string
-	var service *string
-	var version *string
+	// Direct types live in apis/{service}/{version}/*_types.go, Terraform-based
+	// types in pkg/clients/generated/apis/{service}/{version}/*_types.go
+	directTypes := filterKindsBySource(entry.Sources, "direct")
+	tfTypes := filterKindsBySource(entry.Sources, "terraform")
 
-	if hasDirectTypes {
-		controllerType = "direct"
-		location = &directTypesFiles[0]
+	location := entry.TypesFile
+	if !entry.HasDirectTypes {
+		location = entry.TerraformTypesFile
+	}
+	if location == "" {
+		// Only the CRD declares the Kind
+		location = entry.CRDFile
+	}
 
-		// Parse: apis/{service}/{version}/{resource}_types.go
-		parts := strings.Split(*location, "/")
-		if len(parts) >= 4 {
-			service = &parts[1]
-			version = &parts[2]
-		}
-	} else if hasTerraformTypes {
-		controllerType = "terraform"
-		location = &tfTypesFiles[0]
+	info.entry = entry
+	info.Kind = &entry.Kind
+	info.Type = entry.ControllerType
+	info.DefaultController = entry.DefaultController
+	info.SupportedControllers = entry.SupportedControllers
+	info.Hybrid = entry.Hybrid
+	info.ControllerSource = entry.ControllerSource
+	info.Location = &location
+	info.MigrationNeeded = entry.ControllerType != "unknown" && !slices.Contains(entry.SupportedControllers, "direct")
+	info.HasDirectTypes = entry.HasDirectTypes
+	info.HasTerraformTypes = entry.HasTerraformTypes
+	info.Service = &entry.Service
+	info.Version = &entry.Version
+	info.DirectCandidates = kindTypesFiles(directTypes)
+	info.TerraformCandidates = kindTypesFiles(tfTypes)
 
-		// Parse: pkg/clients/generated/apis/{service}/{version}/{resource}_types.go
-		parts := strings.Split(*location, "/")
-		if len(parts) >= 6 {
-			service = &parts[4]
-			version = &parts[5]
+	// Hybrid resources pick their controller per object
+	if entry.Hybrid {
+		annotation := fmt.Sprintf("%s: direct", ReconcilerAnnotation)
+		if entry.DefaultController == "direct" {
+			annotation = fmt.Sprintf("%s: %s", ReconcilerAnnotation, otherController(entry.SupportedControllers, "direct"))
 		}
+		info.ReconcilerAnnotation = &annotation
 	}
 
 	// Check for proto definitions
-	hasProto := false
-	var protoLocation *string
+	if entry.ProtoLocation != "" {
+		info.HasProto = true
+		info.ProtoLocation = &entry.ProtoLocation
+	}
 
-	if service != nil {
-		protoCmd := fmt.Sprintf("find mockgcp/third_party/googleapis -path \"*/%s/*\" -name \"*.proto\" 2>/dev/null | head -5 || true", *service)
-		cmd = exec.Command("sh", "-c", protoCmd)
-		cmd.Dir = repoPath
-		protoOutput, err := cmd.Output()
-		if err == nil {
-			protoFiles := strings.Split(strings.TrimSpace(string(protoOutput)), "\n")
-			protoFiles = removeEmpty(protoFiles)
-			if len(protoFiles) > 0 {
-				hasProto = true
-				protoLocation = &protoFiles[0]
-			}
+	return info
+}
+
+// otherController returns the first supported controller that is not exclude
+func otherController(supported []string, exclude string) string {
+	for _, c := range supported {
+		if c != exclude {
+			return c
 		}
 	}
-
-	return &ControllerTypeInfo{
-		Resource:          resource,
-		Type:              controllerType,
-		Location:          location,
-		MigrationNeeded:   controllerType == "terraform",
-		HasDirectTypes:    hasDirectTypes,
-		HasTerraformTypes: hasTerraformTypes,
-		HasProto:          hasProto,
-		ProtoLocation:     protoLocation,
-		Service:           service,
-		Version:           version,
-	}, nil
+	return exclude
 }
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDetectControllerType(t *testing.T) {
+	cat := newFixtureCatalog(t)
+
+	tests := []struct {
+		resource   string
+		kind       string
+		controller string
+		supported  []string
+		source     string
+		location   string
+		migrate    bool
+		annotation string // reconciler annotation of a hybrid resource
+		proto      string
+	}{
+		{
+			resource:   "computeurlmap",
+			kind:       "ComputeURLMap",
+			controller: "terraform",
+			supported:  []string{"terraform"},
+			source:     "static_config",
+			location:   "pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go",
+			migrate:    true,
+		},
+		{
+			resource:   "DataprocWorkflowTemplate",
+			kind:       "DataprocWorkflowTemplate",
+			controller: "dcl",
+			supported:  []string{"dcl"},
+			source:     "crd",
+			location:   "pkg/clients/generated/apis/dataproc/v1beta1/dataprocworkflowtemplate_types.go",
+			migrate:    true,
+			proto:      "mockgcp/third_party/googleapis/google/cloud/dataproc/v1/workflow_templates.proto",
+		},
+		{
+			resource:   "PubSubLiteReservation",
+			kind:       "PubSubLiteReservation",
+			controller: "terraform",
+			supported:  []string{"terraform"},
+			source:     "crd",
+			location:   "config/crds/resources/apiextensions.k8s.io_v1_customresourcedefinition_pubsublitereservations.pubsublite.cnrm.cloud.google.com.yaml",
+			migrate:    true,
+		},
+		{
+			resource:   "ComputeForwardingRule",
+			kind:       "ComputeForwardingRule",
+			controller: "terraform",
+			supported:  []string{"terraform", "direct"},
+			source:     "static_config",
+			location:   "apis/compute/v1beta1/forwardingrule_types.go",
+			annotation: "alpha.cnrm.cloud.google.com/reconciler: direct",
+		},
+		{
+			resource:   "RedisCluster",
+			kind:       "RedisCluster",
+			controller: "direct",
+			supported:  []string{"direct"},
+			source:     "static_config",
+			location:   "apis/redis/v1beta1/cluster_types.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			info, err := DetectControllerType(cat, tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Kind == nil || *info.Kind != tt.kind {
+				t.Fatalf("kind = %v, want %s", info.Kind, tt.kind)
+			}
+			if info.Type != tt.controller || fmt.Sprint(info.SupportedControllers) != fmt.Sprint(tt.supported) || info.ControllerSource != tt.source {
+				t.Errorf("controller = %s %v from %s, want %s %v from %s",
+					info.Type, info.SupportedControllers, info.ControllerSource, tt.controller, tt.supported, tt.source)
+			}
+			if info.Location == nil || *info.Location != tt.location {
+				t.Errorf("location = %v, want %s", info.Location, tt.location)
+			}
+			if info.MigrationNeeded != tt.migrate {
+				t.Errorf("migration needed = %t, want %t", info.MigrationNeeded, tt.migrate)
+			}
+			if got := deref(info.ReconcilerAnnotation); got != tt.annotation {
+				t.Errorf("reconciler annotation = %q, want %q", got, tt.annotation)
+			}
+			if got := deref(info.ProtoLocation); got != tt.proto {
+				t.Errorf("proto = %q, want %q", got, tt.proto)
+			}
+		})
+	}
+}
+
+func TestDetectControllerTypeUnknown(t *testing.T) {
+	cat := newFixtureCatalog(t)
+	info, err := DetectControllerType(cat, "SpannerInstance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Type != "unknown" || info.Kind != nil {
+		t.Errorf("got %s %v for a resource that is not in the repository", info.Type, info.Kind)
+	}
+}
+
+// deref returns the value of an optional string, or ""
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each hunk
+	diffContext = 3
+
+	// maxLCSCells bounds the longest common subsequence table; larger changes
+	// are shown as one replacement of the changed lines
+	maxLCSCells = 1 << 22
+
+	// noNewlineMarker follows a last line that has no newline, as in GNU diff
+	noNewlineMarker = "\n\\ No newline at end of file"
+)
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff renders the difference between two texts in unified diff
+// format, or "" if they are equal
+func unifiedDiff(fromName, toName, before, after string) string {
+	if before == after {
+		return ""
+	}
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the extent of its hunk
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				hunkEnd = i + 1
+			} else if i-hunkEnd >= 2*diffContext {
+				break
+			}
+		}
+		hunkEnd = min(hunkEnd+diffContext, len(ops))
+
+		// Line numbers of the hunk in both texts
+		fromLine, toLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.line)
+		}
+		start = hunkEnd
+	}
+	return b.String()
+}
+
+// hunkRange renders the "start,count" of a hunk header; an empty range
+// starts at the line before it, as in GNU diff
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their newlines. A last line
+// without a newline carries noNewlineMarker, so that it differs from the
+// same line with one and is printed with the marker.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if !strings.HasSuffix(text, "\n") {
+		lines[len(lines)-1] += noNewlineMarker
+	}
+	return lines
+}
+
+// diffLines computes a line edit script. The common prefix and suffix are
+// matched directly; the changed middle, which is small for the edits tools
+// make, is aligned by alignLines.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ops = append(ops, alignLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// alignLines aligns two texts with a longest common subsequence. Inputs whose
+// table would exceed maxLCSCells are shown as a removal of every line of a
+// followed by an addition of every line of b.
+func alignLines(a, b []string) []diffOp {
+	var ops []diffOp
+	if (len(a)+1)*(len(b)+1) > maxLCSCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
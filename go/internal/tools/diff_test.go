@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines "1" to "n", each ending with a newline
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d\n", i+1)
+	}
+	return lines
+}
+
+// edited returns the numbered lines with line numbers in replace changed
+func edited(n int, replace ...int) string {
+	lines := numbered(n)
+	for _, i := range replace {
+		lines[i-1] = fmt.Sprintf("changed %d\n", i)
+	}
+	return strings.Join(lines, "")
+}
+
+var diffTests = []struct {
+	name          string
+	before, after string
+	want          string // expected diff body after the ---/+++ header; "" to only compare with diff -u
+}{
+	{
+		name:   "insert at start",
+		before: "a\nb\nc\nd\ne\n",
+		after:  "new\na\nb\nc\nd\ne\n",
+		want:   "@@ -1,3 +1,4 @@\n+new\n a\n b\n c\n",
+	},
+	{
+		name:   "insert at end",
+		before: "a\nb\nc\nd\ne\n",
+		after:  "a\nb\nc\nd\ne\nnew\n",
+		want:   "@@ -3,3 +3,4 @@\n c\n d\n e\n+new\n",
+	},
+	{
+		name:   "delete only",
+		before: "a\nb\nc\nd\ne\nf\ng\n",
+		after:  "a\nb\nc\ne\nf\ng\n",
+		want:   "@@ -1,7 +1,6 @@\n a\n b\n c\n-d\n e\n f\n g\n",
+	},
+	{
+		name:   "delete everything",
+		before: "a\nb\n",
+		after:  "",
+		want:   "@@ -1,2 +0,0 @@\n-a\n-b\n",
+	},
+	{
+		name:   "create",
+		before: "",
+		after:  "a\n",
+		want:   "@@ -0,0 +1 @@\n+a\n",
+	},
+	{
+		name:   "hunks closer than twice the context are merged",
+		before: edited(20),
+		after:  edited(20, 5, 11),
+		want:   "@@ -2,13 +2,13 @@\n 2\n 3\n 4\n-5\n+changed 5\n 6\n 7\n 8\n 9\n 10\n-11\n+changed 11\n 12\n 13\n 14\n",
+	},
+	{
+		name:   "hunks exactly twice the context apart are merged",
+		before: edited(20),
+		after:  edited(20, 5, 12),
+	},
+	{
+		name:   "hunks further apart are separate",
+		before: edited(20),
+		after:  edited(20, 5, 13),
+		want: "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+changed 5\n 6\n 7\n 8\n" +
+			"@@ -10,7 +10,7 @@\n 10\n 11\n 12\n-13\n+changed 13\n 14\n 15\n 16\n",
+	},
+	{
+		name:   "newline removed at end of file",
+		before: "a\nb\n",
+		after:  "a\nb",
+		want:   "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+	},
+	{
+		name:   "newline added at end of file",
+		before: "a\nb",
+		after:  "a\nb\n",
+		want:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+	},
+	{
+		name:   "change before a last line without newline",
+		before: "a\nb\nc",
+		after:  "a\nB\nc",
+		want:   "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n\\ No newline at end of file\n",
+	},
+	{
+		name:   "replace in the middle of a long file",
+		before: edited(40),
+		after:  edited(40, 20, 21),
+	},
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, tt := range diffTests {
+		if tt.want == "" {
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("a/f", "b/f", tt.before, tt.after)
+			want := "--- a/f\n+++ b/f\n" + tt.want
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+
+	if got := unifiedDiff("a/f", "b/f", "same\n", "same\n"); got != "" {
+		t.Errorf("diff of equal texts = %q", got)
+	}
+}
+
+// TestUnifiedDiffMatchesDiff compares the output with the system's diff -u
+func TestUnifiedDiffMatchesDiff(t *testing.T) {
+	diff, err := exec.LookPath("diff")
+	if err != nil {
+		t.Skip("diff is not installed")
+	}
+	dir := t.TempDir()
+	for _, tt := range diffTests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := filepath.Join(dir, "before"), filepath.Join(dir, "after")
+			if err := os.WriteFile(before, []byte(tt.before), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(after, []byte(tt.after), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := exec.Command(diff, "-u", "--label", "a/f", "--label", "b/f", before, after).Output()
+			var exitErr *exec.ExitError
+			if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+				t.Fatalf("diff -u: %v", err)
+			}
+			if got := unifiedDiff("a/f", "b/f", tt.before, tt.after); got != string(out) {
+				t.Errorf("got:\n%s\ndiff -u:\n%s", got, out)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffLargeChange(t *testing.T) {
+	n := 3000 // (n+1)^2 exceeds maxLCSCells
+	before := edited(n)
+	afterLines := make([]string, n)
+	for i := range afterLines {
+		afterLines[i] = fmt.Sprintf("other %d\n", i)
+	}
+	after := "header\n" + strings.Join(afterLines, "") + "footer\n"
+
+	got := unifiedDiff("a/f", "b/f", before, after)
+	wantHeader := fmt.Sprintf("--- a/f\n+++ b/f\n@@ -1,%d +1,%d @@\n", n, n+2)
+	if !strings.HasPrefix(got, wantHeader) {
+		t.Fatalf("header = %q, want %q", got[:min(len(got), 80)], wantHeader)
+	}
+	removed, added := 0, 0
+	for _, line := range strings.Split(strings.TrimPrefix(got, wantHeader), "\n") {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			removed++
+		case strings.HasPrefix(line, "+"):
+			added++
+		}
+	}
+	if removed != n || added != n+2 {
+		t.Errorf("removed %d and added %d lines, want %d and %d", removed, added, n, n+2)
+	}
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// protoFieldNamePattern matches a single proto field name, without a path
+var protoFieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// fieldConflict is a reason not to insert a field, with the existing
+// declaration it collides with
+type fieldConflict struct {
+	reason   string
+	existing string // "file:line" and source of the existing declaration, if any
+}
+
+// checkFieldConflicts parses the field about to be added and compares it with
+// the parent struct and the rest of the package. It rejects duplicated Go
+// names and JSON names, proto paths that are already mapped, proto paths
+// outside the parent's own +kcc:proto message, and nested structs whose
+// names are taken.
+func checkFieldConflicts(add *addition, dir, repoPath string) error {
+	field, err := parseFieldDefinition(add.fieldDef)
+	if err != nil {
+		return err
+	}
+	name := field.Names[0].Name
+	jsonName := jsonTagName(field)
+	protoPath := protoAnnotation(field.Doc)
+	parent := add.parent
+	observed := strings.HasSuffix(parent.Name, "ObservedState")
+
+	var conflicts []fieldConflict
+	for _, f := range parent.Type.Fields.List {
+		for _, n := range f.Names {
+			if n.Name == name {
+				conflicts = append(conflicts, fieldConflict{
+					reason:   fmt.Sprintf("%s already has a field named %s", parent.Name, name),
+					existing: parent.describeField(f, repoPath),
+				})
+			}
+		}
+		if jsonName != "" && jsonTagName(f) == jsonName {
+			conflicts = append(conflicts, fieldConflict{
+				reason:   fmt.Sprintf("%s already has a field with JSON name %q", parent.Name, jsonName),
+				existing: parent.describeField(f, repoPath),
+			})
+		}
+	}
+
+	if message := protoAnnotation(parent.Doc); message != "" && protoPath != "" && !isFieldOf(protoPath, message) {
+		conflicts = append(conflicts, fieldConflict{
+			reason: fmt.Sprintf("%s is not a field of %s, the message %s maps (+kcc:proto=%s)", protoPath, message, parent.Name, message),
+		})
+	}
+
+	newStructs := make(map[string]bool)
+	for _, decl := range add.structs {
+		if s, err := parseStructDeclaration(decl); err == nil {
+			newStructs[s] = true
+		}
+	}
+
+	err = walkGoStructs(dir, parent.Path, func(s *goStruct) bool {
+		if newStructs[s.Name] {
+			conflicts = append(conflicts, fieldConflict{
+				reason:   fmt.Sprintf("a struct named %s already exists", s.Name),
+				existing: s.location(s.Spec.Pos(), repoPath),
+			})
+		}
+		if protoPath == "" || strings.HasSuffix(s.Name, "ObservedState") != observed {
+			return true
+		}
+		for _, f := range s.Type.Fields.List {
+			if protoAnnotation(f.Doc) == protoPath {
+				conflicts = append(conflicts, fieldConflict{
+					reason:   fmt.Sprintf("%s is already mapped by %s", protoPath, s.Name),
+					existing: s.describeField(f, repoPath),
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return formatConflicts(add, conflicts)
+}
+
+// isFieldOf reports whether protoPath names a field declared directly in
+// message, e.g. "pkg.Msg.field" but not "pkg.Msg.Nested.field"
+func isFieldOf(protoPath, message string) bool {
+	field, ok := strings.CutPrefix(protoPath, message+".")
+	return ok && protoFieldNamePattern.MatchString(field)
+}
+
+// formatConflicts renders conflicts as a diff-style explanation: the
+// existing declarations prefixed with "-" and the rejected field with "+"
+func formatConflicts(add *addition, conflicts []fieldConflict) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "refusing to add the field to %s:\n", add.parent.Name)
+	for _, c := range conflicts {
+		fmt.Fprintf(&b, "  - %s\n", c.reason)
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range conflicts {
+		if c.existing == "" || seen[c.existing] {
+			continue
+		}
+		seen[c.existing] = true
+		b.WriteString("\n" + c.existing)
+	}
+
+	b.WriteString("\n+++ rejected field\n")
+	for _, line := range strings.Split(add.fieldDef, "\n") {
+		fmt.Fprintf(&b, "+%s\n", line)
+	}
+	b.WriteString("\nRename the field (field_name/json_name), pass a different parent_type, or remove the existing field first")
+	return fmt.Errorf("%s", b.String())
+}
+
+// describeField renders an existing field as the "-" side of a conflict
+func (s *goStruct) describeField(f *ast.Field, repoPath string) string {
+	start := s.fieldStart(f)
+	end := s.offset(f.End())
+	var b strings.Builder
+	b.WriteString(s.location(f.Pos(), repoPath))
+	for _, line := range strings.Split(string(s.Src[start:end]), "\n") {
+		fmt.Fprintf(&b, "-%s\n", line)
+	}
+	return b.String()
+}
+
+// location renders the "--- file:line (struct)" header of a conflict
+func (s *goStruct) location(pos token.Pos, repoPath string) string {
+	relPath, err := filepath.Rel(repoPath, s.Path)
+	if err != nil {
+		relPath = s.Path
+	}
+	return fmt.Sprintf("--- %s:%d (%s)\n", relPath, s.Fset.Position(pos).Line, s.Name)
+}
+
+// parseFieldDefinition parses a rendered field declaration
+func parseFieldDefinition(fieldDef string) (*ast.Field, error) {
+	src := "package p\ntype t struct {\n" + fieldDef + "\n}\n"
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("generated field is not valid Go: %w\n\n%s", err, fieldDef)
+	}
+	fields := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List
+	if len(fields) != 1 || len(fields[0].Names) != 1 {
+		return nil, fmt.Errorf("generated field is not a single named field:\n\n%s", fieldDef)
+	}
+	return fields[0], nil
+}
+
+// parseStructDeclaration returns the type name of a rendered struct
+// declaration
+func parseStructDeclaration(decl string) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+decl, 0)
+	if err != nil {
+		return "", err
+	}
+	return file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Name.Name, nil
+}
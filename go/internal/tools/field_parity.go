@@ -0,0 +1,522 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
+)
+
+// Field parity statuses
+const (
+	ParityMatched        = "matched"          // same name on both sides
+	ParityRenamed        = "renamed"          // KRM name differs, e.g. networkRef <-> network
+	ParityMissingInProto = "missing_in_proto" // KRM field with no proto counterpart
+	ParityNewInProto     = "new_in_proto"     // proto field not exposed in KRM yet
+	ParityKCCOnly        = "kcc_only"         // KRM bookkeeping or identity field
+)
+
+// FieldParityParams contains parameters for a field parity report
+type FieldParityParams struct {
+	Resource     string `json:"resource"`
+	ProtoMessage string `json:"proto_message,omitempty"` // full or short name; defaults to the Kind without its service prefix
+}
+
+// FieldMapping maps a single KRM field onto a proto field
+type FieldMapping struct {
+	Status    string `json:"status"`
+	KRMPath   string `json:"krm_path,omitempty"`
+	KRMType   string `json:"krm_type,omitempty"`
+	ProtoPath string `json:"proto_path,omitempty"`
+	ProtoType string `json:"proto_type,omitempty"`
+	Breaking  bool   `json:"breaking,omitempty"` // dropping or retyping the KRM field would break existing objects
+	Note      string `json:"note,omitempty"`
+}
+
+// FieldParityReport compares the existing KRM schema of a resource with its
+// proto message
+type FieldParityReport struct {
+	Resource        string         `json:"resource"`
+	Kind            string         `json:"kind"`
+	Source          string         `json:"source"` // "terraform_types" or "crd"
+	SourceFile      string         `json:"source_file"`
+	ProtoMessage    string         `json:"proto_message"`
+	ProtoFile       string         `json:"proto_file"`
+	ProtoCandidates []string       `json:"proto_candidates,omitempty"`
+	Summary         map[string]int `json:"summary"`
+	Fields          []FieldMapping `json:"fields"`
+}
+
+// maxParityDepth bounds recursion into nested messages
+const maxParityDepth = 10
+
+// wellKnownKinds maps well-known proto types onto the KRM kind they use
+var wellKnownKinds = map[string]string{
+	"google.protobuf.Timestamp":   "string",
+	"google.protobuf.Duration":    "string",
+	"google.protobuf.FieldMask":   "string",
+	"google.protobuf.Struct":      "json",
+	"google.protobuf.Value":       "json",
+	"google.protobuf.ListValue":   "json",
+	"google.protobuf.Any":         "json",
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.BytesValue":  "string",
+	"google.protobuf.BoolValue":   "boolean",
+	"google.protobuf.Int32Value":  "integer",
+	"google.protobuf.Int64Value":  "integer",
+	"google.protobuf.UInt32Value": "integer",
+	"google.protobuf.UInt64Value": "integer",
+	"google.protobuf.FloatValue":  "number",
+	"google.protobuf.DoubleValue": "number",
+}
+
+// identityFields are top-level KRM fields that become part of the resource
+// name rather than fields of the proto message
+var identityFields = map[string]bool{
+	"resourceID":      true,
+	"projectRef":      true,
+	"folderRef":       true,
+	"organizationRef": true,
+	"location":        true,
+}
+
+// statusBookkeeping are status fields maintained by KCC itself
+var statusBookkeeping = map[string]bool{
+	"conditions":         true,
+	"observedGeneration": true,
+	"externalRef":        true,
+}
+
+// FieldParity compares the Terraform/DCL-era schema of a resource (the
+// generated types under pkg/clients/generated/apis, or the CRD under
+// config/crds) field by field with its proto message
+func FieldParity(cat *Catalog, params FieldParityParams) (*FieldParityReport, error) {
+	entry, err := cat.Resolve(params.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("resource not found: %s\n\nUse kcc_list_resources to see the available Kinds", params.Resource)
+	}
+
+	schema, source, sourceFile, err := loadKRMSchema(cat.RepoPath(), entry)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cat.ProtoIndex(entry.Service)
+	msg, candidates, err := findProtoMessage(cat.RepoPath(), index, entry, params.ProtoMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FieldParityReport{
+		Resource:     params.Resource,
+		Kind:         entry.Kind,
+		Source:       source,
+		SourceFile:   sourceFile,
+		ProtoMessage: msg.FullName,
+		ProtoFile:    msg.File.Path,
+		Summary:      make(map[string]int),
+		Fields:       []FieldMapping{},
+	}
+	for _, c := range candidates {
+		if c.FullName != msg.FullName {
+			report.ProtoCandidates = append(report.ProtoCandidates, c.FullName)
+		}
+	}
+
+	// Spec, status and status.observedState all map onto the top-level message
+	var top []krmPathField
+	for _, f := range schema.Spec {
+		top = append(top, krmPathField{path: "spec." + f.Name, field: f})
+	}
+	for _, f := range schema.Status {
+		switch {
+		case statusBookkeeping[f.Name]:
+			report.Fields = append(report.Fields, FieldMapping{
+				Status:  ParityKCCOnly,
+				KRMPath: "status." + f.Name,
+				KRMType: f.Type,
+				Note:    "maintained by KCC",
+			})
+		case f.Name == "observedState" && f.Kind == "object":
+			for _, nested := range f.Fields {
+				top = append(top, krmPathField{path: "status.observedState." + nested.Name, field: nested})
+			}
+		default:
+			top = append(top, krmPathField{path: "status." + f.Name, field: f})
+		}
+	}
+
+	c := &parityComparer{index: index, report: report}
+	c.compare(top, msg, "", true, map[string]bool{msg.FullName: true})
+
+	for _, f := range report.Fields {
+		report.Summary[f.Status]++
+	}
+	return report, nil
+}
+
+// loadKRMSchema reads the legacy schema of a Kind, preferring the generated
+// Go types over the CRD
+func loadKRMSchema(repoPath string, entry *CatalogEntry) (*krmSchema, string, string, error) {
+	if entry.TerraformTypesFile != "" {
+		content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(entry.TerraformTypesFile)))
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to read file: %w", err)
+		}
+		schema, err := parseTerraformTypes(entry.TerraformTypesFile, string(content), entry.Kind)
+		return schema, "terraform_types", entry.TerraformTypesFile, err
+	}
+
+	if entry.CRDFile != "" {
+		content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(entry.CRDFile)))
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to read file: %w", err)
+		}
+		schema, err := parseCRDSchema(entry.CRDFile, string(content), entry.Version)
+		return schema, "crd", entry.CRDFile, err
+	}
+
+	return nil, "", "", fmt.Errorf(`no legacy schema found for %s
+
+Field parity compares one of:
+- pkg/clients/generated/apis/%s/{version}/*_types.go
+- config/crds/resources/*.yaml
+with the proto message. Neither declares %s.`, entry.Kind, entry.Service, entry.Kind)
+}
+
+// findProtoMessage selects the proto message of a Kind: the requested name,
+// else the +kcc:proto annotation on an existing Spec, else the Kind without
+// its service prefix. It also returns every candidate that matched.
+func findProtoMessage(repoPath string, index *protoparser.Index, entry *CatalogEntry, requested string) (*protoparser.Message, []*protoparser.Message, error) {
+	names := []string{requested}
+	if requested == "" {
+		names = []string{kindWithoutService(entry.Kind, entry.Service), entry.Kind}
+		if annotated := specProtoAnnotation(repoPath, entry); annotated != "" {
+			names = append([]string{annotated}, names...)
+		}
+	}
+
+	for _, name := range names {
+		candidates := index.FindMessages(name)
+		if len(candidates) == 0 {
+			continue
+		}
+		sortProtoCandidates(candidates)
+		return candidates[0], candidates, nil
+	}
+
+	return nil, nil, fmt.Errorf(`no proto message named %s in the %s protos
+
+Searched %d file(s) under %s.
+Pass proto_message with the full message name, e.g. google.cloud.%s.v1.%s`,
+		strings.Join(names, " or "), entry.Service, len(index.Files()), protoRoot, entry.Service, names[len(names)-1])
+}
+
+// specProtoAnnotationPattern matches the +kcc:proto annotation of a Spec struct
+var specProtoAnnotationPattern = regexp.MustCompile(`(?m)^//\s*\+kcc:proto=([\w.]+)\s*$(?:\n//[^\n]*)*\ntype (\w+)Spec struct`)
+
+// specProtoAnnotation returns the message named by the +kcc:proto annotation
+// of the Kind's Spec in its direct types file, if any
+func specProtoAnnotation(repoPath string, entry *CatalogEntry) string {
+	if !entry.HasDirectTypes {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(entry.TypesFile)))
+	if err != nil {
+		return ""
+	}
+	for _, m := range specProtoAnnotationPattern.FindAllStringSubmatch(string(content), -1) {
+		if m[2] == entry.Kind {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// sortProtoCandidates orders candidate messages: top-level messages before
+// nested ones, then GA packages before beta and alpha
+func sortProtoCandidates(candidates []*protoparser.Message) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		aTop := a.FullName == a.File.Package+"."+a.Name
+		bTop := b.FullName == b.File.Package+"."+b.Name
+		if aTop != bTop {
+			return aTop
+		}
+		aRank := versionRank(path.Ext("." + a.File.Package))
+		bRank := versionRank(path.Ext("." + b.File.Package))
+		if aRank != bRank {
+			return aRank > bRank
+		}
+		return a.FullName < b.FullName
+	})
+}
+
+// krmPathField is a KRM field together with its path from the object root
+type krmPathField struct {
+	path  string
+	field *krmField
+}
+
+// parityComparer walks a KRM schema and a proto message side by side
+type parityComparer struct {
+	index  *protoparser.Index
+	report *FieldParityReport
+}
+
+// compare matches KRM fields against the fields of msg and recurses into
+// nested objects. seen holds the messages on the current path.
+func (c *parityComparer) compare(fields []krmPathField, msg *protoparser.Message, protoPrefix string, top bool, seen map[string]bool) {
+	byKey := make(map[string]*protoparser.Field, len(msg.Fields))
+	for _, pf := range msg.Fields {
+		byKey[normalizeFieldName(pf.Name)] = pf
+	}
+
+	// Each proto field is matched by at most one KRM field. Exact names are
+	// assigned first, so that networkRef cannot claim the proto field network
+	// when the KRM schema has a network field too.
+	matches := make([]*protoparser.Field, len(fields))
+	renamed := make([]bool, len(fields))
+	used := make(map[string]bool)
+	for i, kf := range fields {
+		if pf := byKey[normalizeFieldName(kf.field.Name)]; pf != nil && !used[pf.Name] {
+			matches[i] = pf
+			used[pf.Name] = true
+		}
+	}
+	for i, kf := range fields {
+		if matches[i] != nil {
+			continue
+		}
+		for _, candidate := range renameCandidates(kf.field.Name, top) {
+			if pf := byKey[candidate]; pf != nil && !used[pf.Name] {
+				matches[i], renamed[i] = pf, true
+				used[pf.Name] = true
+				break
+			}
+		}
+	}
+
+	for i, kf := range fields {
+		f := kf.field
+		pf := matches[i]
+		status := ParityMatched
+		if renamed[i] {
+			status = ParityRenamed
+		}
+
+		if pf == nil {
+			mapping := FieldMapping{
+				Status:   ParityMissingInProto,
+				KRMPath:  kf.path,
+				KRMType:  f.Type,
+				Breaking: strings.HasPrefix(kf.path, "spec."),
+			}
+			if top && identityFields[f.Name] {
+				mapping.Status = ParityKCCOnly
+				mapping.Breaking = false
+				mapping.Note = "part of the resource identity (name / parent)"
+			} else if mapping.Breaking {
+				mapping.Note = "keep the field and map it by hand, or deprecate it"
+			}
+			c.report.Fields = append(c.report.Fields, mapping)
+			continue
+		}
+
+		protoPath := joinProtoPath(protoPrefix, pf.Name)
+		protoKind, nested := c.protoFieldKind(msg, pf)
+
+		mapping := FieldMapping{
+			Status:    status,
+			KRMPath:   kf.path,
+			KRMType:   f.Type,
+			ProtoPath: protoPath,
+			ProtoType: protoTypeString(pf),
+		}
+		if ok, reason := kindsCompatible(f, protoKind, pf.IsRepeated()); !ok {
+			mapping.Breaking = true
+			mapping.Note = reason
+		} else if status == ParityRenamed {
+			mapping.Note = fmt.Sprintf("KRM name differs from proto field %s", pf.Name)
+			if f.Kind == "reference" {
+				mapping.Note = fmt.Sprintf("reference resolves to proto field %s", pf.Name)
+			}
+		}
+		c.report.Fields = append(c.report.Fields, mapping)
+
+		if nested != nil && len(f.Fields) > 0 && !seen[nested.FullName] && len(seen) < maxParityDepth {
+			elemPath := kf.path
+			if f.Repeated {
+				elemPath += "[]"
+			}
+			var children []krmPathField
+			for _, child := range f.Fields {
+				children = append(children, krmPathField{path: elemPath + "." + child.Name, field: child})
+			}
+			seen[nested.FullName] = true
+			c.compare(children, nested, protoPath, false, seen)
+			delete(seen, nested.FullName)
+		}
+	}
+
+	for _, pf := range msg.Fields {
+		if used[pf.Name] {
+			continue
+		}
+		mapping := FieldMapping{
+			Status:    ParityNewInProto,
+			ProtoPath: joinProtoPath(protoPrefix, pf.Name),
+			ProtoType: protoTypeString(pf),
+		}
+		if top && pf.Name == "name" {
+			mapping.Note = "usually built from spec.resourceID and the parent"
+		}
+		c.report.Fields = append(c.report.Fields, mapping)
+	}
+}
+
+// protoFieldKind classifies a proto field the way KRM schemas do and returns
+// the message to recurse into, if any
+func (c *parityComparer) protoFieldKind(msg *protoparser.Message, f *protoparser.Field) (string, *protoparser.Message) {
+	if f.IsMap() {
+		return "map", nil
+	}
+
+	kind, fullName := c.index.FieldType(msg, f)
+	switch kind {
+	case protoparser.KindScalar:
+		switch fullName {
+		case "string", "bytes":
+			return "string", nil
+		case "bool":
+			return "boolean", nil
+		case "float", "double":
+			return "number", nil
+		default:
+			return "integer", nil
+		}
+	case protoparser.KindEnum:
+		return "string", nil
+	}
+
+	if k, ok := wellKnownKinds[fullName]; ok {
+		return k, nil
+	}
+	return "object", c.index.Message(fullName)
+}
+
+// kindsCompatible reports whether a KRM field can carry the values of a proto
+// field of the given kind, and why not
+func kindsCompatible(f *krmField, protoKind string, protoRepeated bool) (bool, string) {
+	if f.Repeated != protoRepeated {
+		if f.Repeated {
+			return false, fmt.Sprintf("KRM field is a list but the proto field is a single %s", protoKind)
+		}
+		return false, fmt.Sprintf("KRM field is a single %s but the proto field is repeated", f.Kind)
+	}
+
+	switch {
+	case f.Kind == protoKind,
+		f.Kind == "reference" && protoKind == "string",
+		f.Kind == "json" && protoKind == "object",
+		f.Kind == "object" && protoKind == "json":
+		return true, ""
+	}
+	return false, fmt.Sprintf("type mismatch: KRM %s vs proto %s", f.Kind, protoKind)
+}
+
+// renameCandidates returns normalized proto field names a KRM field is
+// commonly renamed from, e.g. networkRef <- network, network_id
+func renameCandidates(name string, top bool) []string {
+	n := strings.ToLower(name)
+	var candidates []string
+	switch {
+	case strings.HasSuffix(name, "Refs"):
+		base := strings.TrimSuffix(n, "refs")
+		candidates = append(candidates, base+"s", base, base+"ids", base+"names", base+"uris", base+"urls")
+	case strings.HasSuffix(name, "Ref"):
+		base := strings.TrimSuffix(n, "ref")
+		candidates = append(candidates, base, base+"id", base+"name", base+"uri", base+"url", base+"email")
+	}
+	if top {
+		switch name {
+		case "resourceID":
+			candidates = append(candidates, "name")
+		case "location":
+			candidates = append(candidates, "region", "zone")
+		case "numericId":
+			candidates = append(candidates, "id")
+		}
+	}
+	if strings.HasSuffix(n, "s") {
+		candidates = append(candidates, strings.TrimSuffix(n, "s"))
+	} else {
+		candidates = append(candidates, n+"s")
+	}
+	return candidates
+}
+
+// normalizeFieldName makes KRM camelCase and proto snake_case names comparable
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+func joinProtoPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// protoTypeString renders the type of a proto field as declared
+func protoTypeString(f *protoparser.Field) string {
+	if f.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", f.MapKey, f.MapValue)
+	}
+	if f.IsRepeated() {
+		return "repeated " + f.Type
+	}
+	return f.Type
+}
+
+// FormatFieldParity renders a parity report as a compact text table
+func FormatFieldParity(report *FieldParityReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s) vs %s (%s)\n\n", report.Kind, report.SourceFile, report.ProtoMessage, report.ProtoFile)
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tKRM FIELD\tKRM TYPE\tPROTO FIELD\tPROTO TYPE\tNOTE")
+	for _, f := range report.Fields {
+		status := f.Status
+		if f.Breaking {
+			status += " ⚠️"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", status, dash(f.KRMPath), dash(f.KRMType), dash(f.ProtoPath), dash(f.ProtoType), f.Note)
+	}
+	w.Flush()
+
+	fmt.Fprintf(&b, "\n%d matched, %d renamed, %d missing in proto, %d new in proto, %d KCC-only",
+		report.Summary[ParityMatched], report.Summary[ParityRenamed], report.Summary[ParityMissingInProto],
+		report.Summary[ParityNewInProto], report.Summary[ParityKCCOnly])
+	if len(report.ProtoCandidates) > 0 {
+		fmt.Fprintf(&b, "\nOther candidate messages: %s", strings.Join(report.ProtoCandidates, ", "))
+	}
+	return b.String()
+}
+
+// dash renders empty table cells as "-"
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
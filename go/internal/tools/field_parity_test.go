@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
+)
+
+const parityTestProto = `
+syntax = "proto3";
+
+package test.v1;
+
+message Widget {
+  string name = 1;
+  string display_name = 2;
+  string network = 3;
+  int64 size_gb = 4;
+  repeated string tags = 5;
+  Config config = 6;
+  string kms_key = 7;
+  map<string, string> labels = 8;
+
+  message Config {
+    bool enabled = 1;
+  }
+}
+`
+
+// compareWidget runs the parity comparison of spec fields against Widget and
+// returns the mappings by KRM path, or by proto path for new-in-proto fields
+func compareWidget(t *testing.T, fields ...*krmField) map[string]FieldMapping {
+	t.Helper()
+	file, err := protoparser.Parse("test.proto", parityTestProto)
+	if err != nil {
+		t.Fatalf("parsing test proto: %v", err)
+	}
+	index := protoparser.NewIndex(file)
+	msg := index.Message("test.v1.Widget")
+
+	var top []krmPathField
+	for _, f := range fields {
+		top = append(top, krmPathField{path: "spec." + f.Name, field: f})
+	}
+	report := &FieldParityReport{}
+	c := &parityComparer{index: index, report: report}
+	c.compare(top, msg, "", true, map[string]bool{msg.FullName: true})
+
+	mappings := make(map[string]FieldMapping)
+	for _, m := range report.Fields {
+		key := m.KRMPath
+		if key == "" {
+			key = m.ProtoPath
+		}
+		if _, dup := mappings[key]; dup {
+			t.Fatalf("%s reported twice", key)
+		}
+		mappings[key] = m
+	}
+	return mappings
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []*krmField
+		key      string // KRM path, or proto path for new-in-proto fields
+		status   string
+		proto    string
+		breaking bool
+	}{
+		{
+			name:   "matched",
+			fields: []*krmField{{Name: "displayName", Kind: "string", Type: "string"}},
+			key:    "spec.displayName",
+			status: ParityMatched,
+			proto:  "display_name",
+		},
+		{
+			name:   "renamed reference",
+			fields: []*krmField{{Name: "networkRef", Kind: "reference", Type: "object"}},
+			key:    "spec.networkRef",
+			status: ParityRenamed,
+			proto:  "network",
+		},
+		{
+			name:   "renamed reference with suffix",
+			fields: []*krmField{{Name: "kmsKeyRef", Kind: "reference", Type: "object"}},
+			key:    "spec.kmsKeyRef",
+			status: ParityRenamed,
+			proto:  "kms_key",
+		},
+		{
+			name:     "missing in proto",
+			fields:   []*krmField{{Name: "legacyMode", Kind: "string", Type: "string"}},
+			key:      "spec.legacyMode",
+			status:   ParityMissingInProto,
+			breaking: true,
+		},
+		{
+			name:   "identity field",
+			fields: []*krmField{{Name: "projectRef", Kind: "reference", Type: "object"}},
+			key:    "spec.projectRef",
+			status: ParityKCCOnly,
+		},
+		{
+			name:   "resourceID maps onto name",
+			fields: []*krmField{{Name: "resourceID", Kind: "string", Type: "string"}},
+			key:    "spec.resourceID",
+			status: ParityRenamed,
+			proto:  "name",
+		},
+		{
+			name:   "new in proto",
+			fields: []*krmField{{Name: "displayName", Kind: "string", Type: "string"}},
+			key:    "size_gb",
+			status: ParityNewInProto,
+			proto:  "size_gb",
+		},
+		{
+			name:     "breaking type change",
+			fields:   []*krmField{{Name: "sizeGb", Kind: "string", Type: "string"}},
+			key:      "spec.sizeGb",
+			status:   ParityMatched,
+			proto:    "size_gb",
+			breaking: true,
+		},
+		{
+			name:     "single KRM field for a repeated proto field",
+			fields:   []*krmField{{Name: "tags", Kind: "string", Type: "string"}},
+			key:      "spec.tags",
+			status:   ParityMatched,
+			proto:    "tags",
+			breaking: true,
+		},
+		{
+			name:   "list matches repeated",
+			fields: []*krmField{{Name: "tags", Kind: "string", Type: "array<string>", Repeated: true}},
+			key:    "spec.tags",
+			status: ParityMatched,
+			proto:  "tags",
+		},
+		{
+			name:   "map",
+			fields: []*krmField{{Name: "labels", Kind: "map", Type: "map"}},
+			key:    "spec.labels",
+			status: ParityMatched,
+			proto:  "labels",
+		},
+		{
+			name: "nested object",
+			fields: []*krmField{{Name: "config", Kind: "object", Type: "object", Fields: []*krmField{
+				{Name: "enabled", Kind: "boolean", Type: "boolean"},
+			}}},
+			key:    "spec.config.enabled",
+			status: ParityMatched,
+			proto:  "config.enabled",
+		},
+		{
+			name: "exact match wins over a rename declared first",
+			fields: []*krmField{
+				{Name: "networkRef", Kind: "reference", Type: "object"},
+				{Name: "network", Kind: "string", Type: "string"},
+			},
+			key:    "spec.network",
+			status: ParityMatched,
+			proto:  "network",
+		},
+		{
+			name: "a proto field is matched only once",
+			fields: []*krmField{
+				{Name: "networkRef", Kind: "reference", Type: "object"},
+				{Name: "network", Kind: "string", Type: "string"},
+			},
+			key:      "spec.networkRef",
+			status:   ParityMissingInProto,
+			breaking: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings := compareWidget(t, tt.fields...)
+			m, ok := mappings[tt.key]
+			if !ok {
+				t.Fatalf("no mapping for %s; got %v", tt.key, mappings)
+			}
+			if m.Status != tt.status {
+				t.Errorf("status = %s, want %s (note: %s)", m.Status, tt.status, m.Note)
+			}
+			if m.ProtoPath != tt.proto {
+				t.Errorf("proto path = %q, want %q", m.ProtoPath, tt.proto)
+			}
+			if m.Breaking != tt.breaking {
+				t.Errorf("breaking = %t, want %t (note: %s)", m.Breaking, tt.breaking, m.Note)
+			}
+		})
+	}
+}
+
+func TestParseCRDSchema(t *testing.T) {
+	const crd = `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.test.cnrm.cloud.google.com
+spec:
+  versions:
+  - name: v1alpha1
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec:
+            properties:
+              stale:
+                type: string
+  - name: v1beta1
+    storage: true
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec:
+            required:
+            - displayName
+            properties:
+              displayName:
+                description: |-
+                  The display name,
+                  shown in the console.
+                type: string
+              networkRef:
+                properties:
+                  external:
+                    type: string
+                type: object
+              tags:
+                items:
+                  type: string
+                type: array
+              labels:
+                additionalProperties:
+                  type: string
+                type: object
+              settings:
+                x-kubernetes-preserve-unknown-fields: true
+              config:
+                properties:
+                  enabled:
+                    type: boolean
+                type: object
+          status:
+            properties:
+              observedGeneration:
+                type: integer
+---
+kind: Ignored
+`
+
+	schema, err := parseCRDSchema("crd.yaml", crd, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name, kind, typ string
+		required        bool
+	}{
+		{"displayName", "string", "string", true},
+		{"networkRef", "reference", "object", false},
+		{"tags", "string", "array<string>", false},
+		{"labels", "map", "map", false},
+		{"settings", "json", "object", false},
+		{"config", "object", "object", false},
+	}
+	if len(schema.Spec) != len(want) {
+		t.Fatalf("got %d spec fields, want %d (storage version not chosen?)", len(schema.Spec), len(want))
+	}
+	for i, w := range want {
+		f := schema.Spec[i]
+		if f.Name != w.name || f.Kind != w.kind || f.Type != w.typ || f.Required != w.required {
+			t.Errorf("field %d = %s %s %s required=%t, want %s %s %s required=%t",
+				i, f.Name, f.Kind, f.Type, f.Required, w.name, w.kind, w.typ, w.required)
+		}
+	}
+	if got := schema.Spec[0].Description; got != "The display name, shown in the console." {
+		t.Errorf("description = %q", got)
+	}
+	if got := schema.Spec[5].Fields; len(got) != 1 || got[0].Name != "enabled" || got[0].Kind != "boolean" {
+		t.Errorf("nested fields of config = %+v", got)
+	}
+	if len(schema.Status) != 1 || schema.Status[0].Name != "observedGeneration" {
+		t.Errorf("status = %+v", schema.Status)
+	}
+
+	v1alpha1, err := parseCRDSchema("crd.yaml", crd, "v1alpha1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v1alpha1.Spec) != 1 || v1alpha1.Spec[0].Name != "stale" {
+		t.Errorf("requested version not chosen: %+v", v1alpha1.Spec)
+	}
+}
@@ -3,82 +3,61 @@ package tools
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
 // ResourceLocation represents the location of KCC resource files
 type ResourceLocation struct {
-	Resource        string                 `json:"resource"`
-	Service         string                 `json:"service"`
-	Version         string                 `json:"version"`
-	TypesFile       string                 `json:"types_file"`
-	ControllerFile  string                 `json:"controller_file"`
-	MapperFile      string                 `json:"mapper_file"`
-	TestFixturesDir string                 `json:"test_fixtures_dir"`
-	FilesExist      map[string]bool        `json:"files_exist"`
+	Resource        string          `json:"resource"`
+	Kind            string          `json:"kind"`
+	Service         string          `json:"service"`
+	Version         string          `json:"version"`
+	TypesFile       string          `json:"types_file"`
+	ControllerFile  string          `json:"controller_file"`
+	MapperFile      string          `json:"mapper_file"`
+	TestFixturesDir string          `json:"test_fixtures_dir"`
+	FilesExist      map[string]bool `json:"files_exist"`
+	Candidates      []ResourceKind  `json:"candidates"`
 }
 
-// FindResource locates files for a KCC resource
-func FindResource(repoPath, resource string) (*ResourceLocation, error) {
-	// Normalize resource name
-	resourceLower := strings.ToLower(resource)
-
-	// Find types file
-	findCmd := fmt.Sprintf("find apis/ -name \"*%s*types.go\" 2>/dev/null || true", resourceLower)
-	cmd := exec.Command("sh", "-c", findCmd)
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+// FindResource locates files for a KCC resource.
+// The resource may be a Kind (ComputeURLMap), a types file stem (urlmap) or a
+// unique fragment of a Kind; ambiguous input returns *AmbiguousResourceError.
+func FindResource(cat *Catalog, resource string) (*ResourceLocation, error) {
+	entry, err := cat.Resolve(resource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search for types file: %w", err)
+		return nil, err
 	}
 
-	typesFiles := strings.Split(strings.TrimSpace(string(output)), "\n")
-	typesFiles = removeEmpty(typesFiles)
-
-	if len(typesFiles) == 0 {
+	if entry == nil || !entry.HasDirectTypes {
 		return nil, fmt.Errorf(`Resource not found: %s
 
-Searched for: apis/**/*%s*types.go
-
-Make sure the resource exists and has a direct controller.`, resource, resourceLower)
-	}
-
-	// Parse path: apis/{service}/{version}/{resource}_types.go
-	typesFile := typesFiles[0]
-	pathParts := strings.Split(typesFile, "/")
+Searched for: GroupVersion.WithKind declarations in apis/**/*_types.go
 
-	if len(pathParts) < 4 {
-		return nil, fmt.Errorf("unexpected types file path format: %s", typesFile)
+Make sure the resource exists and has a direct controller.`, resource)
 	}
 
-	service := pathParts[1]
-	version := pathParts[2]
-	resourceName := strings.TrimSuffix(pathParts[3], "_types.go")
-
-	// Expected file locations
-	controllerFile := filepath.Join("pkg", "controller", "direct", service, fmt.Sprintf("%s_controller.go", resourceName))
-	mapperFile := filepath.Join("pkg", "controller", "direct", service, "mapper.generated.go")
-	testFixturesDir := filepath.Join("pkg", "test", "resourcefixture", "testdata", "basic", service, version, resourceName)
+	repoPath := cat.RepoPath()
 
 	// Check existence
 	filesExist := map[string]bool{
-		"types":         fileExists(filepath.Join(repoPath, typesFile)),
-		"controller":    fileExists(filepath.Join(repoPath, controllerFile)),
-		"mapper":        fileExists(filepath.Join(repoPath, mapperFile)),
-		"test_fixtures": fileExists(filepath.Join(repoPath, testFixturesDir)),
+		"types":         fileExists(filepath.Join(repoPath, entry.TypesFile)),
+		"controller":    fileExists(filepath.Join(repoPath, entry.ControllerFile)),
+		"mapper":        fileExists(filepath.Join(repoPath, entry.MapperFile)),
+		"test_fixtures": fileExists(filepath.Join(repoPath, entry.FixturesDir)),
 	}
 
 	return &ResourceLocation{
-		Resource:        resourceName,
-		Service:         service,
-		Version:         version,
-		TypesFile:       typesFile,
-		ControllerFile:  controllerFile,
-		MapperFile:      mapperFile,
-		TestFixturesDir: testFixturesDir,
+		Resource:        typesFileStem(entry.TypesFile),
+		Kind:            entry.Kind,
+		Service:         entry.Service,
+		Version:         entry.Version,
+		TypesFile:       entry.TypesFile,
+		ControllerFile:  entry.ControllerFile,
+		MapperFile:      entry.MapperFile,
+		TestFixturesDir: entry.FixturesDir,
 		FilesExist:      filesExist,
+		Candidates:      filterKindsBySource(entry.Sources, "direct"),
 	}, nil
 }
 
@@ -87,14 +66,3 @@ func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
-
-// removeEmpty removes empty strings from a slice
-func removeEmpty(strs []string) []string {
-	result := make([]string, 0, len(strs))
-	for _, s := range strs {
-		if s != "" {
-			result = append(result, s)
-		}
-	}
-	return result
-}
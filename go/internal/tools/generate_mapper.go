@@ -1,27 +1,194 @@
 package tools
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
 )
 
-// GenerateMapper generates the KRM ↔ Proto mapper for a resource
-func GenerateMapper(repoPath, resource string) (string, error) {
-	// Run the mapper generation script
-	cmd := exec.Command("./dev/tasks/generate-mapper", resource)
-	cmd.Dir = repoPath
+// MapperResult is the outcome of running the mapper generator
+type MapperResult struct {
+	Resource    string             `json:"resource"`
+	Success     bool               `json:"success"`
+	Output      string             `json:"output"` // partial if the run was interrupted
+	TimedOut    bool               `json:"timed_out,omitempty"`
+	Canceled    bool               `json:"canceled,omitempty"`
+	Operation   string             `json:"operation,omitempty"` // operation log ID of the files written, for kcc_undo
+	Diagnostics []MapperDiagnostic `json:"diagnostics"`
+}
 
-	output, err := cmd.CombinedOutput()
+// GenerateMapper generates the KRM ↔ Proto mapper for a resource. The
+// resource is resolved through the catalog and the generator is given its
+// Kind. Generator and compiler messages are parsed into diagnostics; a
+// failed, timed out or cancelled run is reported in the result rather than
+// as an error. The generated files are logged in changes, so a run can be
+// undone.
+func GenerateMapper(ctx context.Context, cat *Catalog, resource string, changes *ChangeSet, opts runner.Options) (*MapperResult, error) {
+	entry, err := cat.Resolve(resource)
 	if err != nil {
-		return "", fmt.Errorf(`Failed to generate mapper for %s:
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("resource not found: %s\n\nUse kcc_list_resources to see the available Kinds", resource)
+	}
+	if err := changes.Capture(generatedFiles(cat, entry)...); err != nil {
+		return nil, err
+	}
+	// Run the mapper generation script
+	output, err := runner.Run(ctx, runner.Command{
+		Name:    "./dev/tasks/generate-mapper",
+		Args:    []string{entry.Kind},
+		Dir:     cat.RepoPath(),
+		Timeout: opts.Timeout,
+		OnLine:  opts.OnLine,
+	})
+	changes.Save()
+	result := &MapperResult{
+		Resource:    entry.Kind,
+		Success:     err == nil,
+		Output:      output,
+		Diagnostics: parseMapperOutput(cat.RepoPath(), output),
+		Operation:   changes.OperationID(),
+	}
+	var runErr *runner.Error
+	if errors.As(err, &runErr) && runErr.Interrupted() {
+		result.TimedOut = runErr.TimedOut
+		result.Canceled = runErr.Canceled
+		d := MapperDiagnostic{
+			Severity: "error",
+			Class:    DiagGeneratorError,
+			Message:  runErr.Error(),
+		}
+		if runErr.TimedOut {
+			d.Hint = "Raise timeouts.generate_mapper_seconds (or KCC_GENERATE_MAPPER_TIMEOUT_SECONDS) if the generator needs longer"
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+	if err != nil && len(result.Diagnostics) == 0 {
+		// Nothing recognisable in the output (or the script did not start):
+		// report the failure itself so callers always get a diagnostic
+		message := strings.TrimSpace(output)
+		if message == "" {
+			message = err.Error()
+		}
+		result.Diagnostics = append(result.Diagnostics, MapperDiagnostic{
+			Severity: "error",
+			Class:    DiagGeneratorError,
+			Message:  lastLine(message),
+		})
+	}
+	locateProtoPaths(cat, entry, result.Diagnostics)
+	if err == nil {
+		result.Diagnostics = append(result.Diagnostics, unmappedFields(cat, entry)...)
+	}
+	return result, nil
+}
+
+// generatedFiles lists the files the mapper generator may write for a
+// resource: the *.generated.go files of its controller package and of the
+// API packages of its direct versions
+func generatedFiles(cat *Catalog, entry *CatalogEntry) []string {
+	dirs := map[string]string{} // directory -> file the generator creates there
+	mapperDir := filepath.Join("pkg", "controller", "direct", entry.Service)
+	if entry.MapperFile != "" {
+		mapperDir = filepath.Dir(filepath.FromSlash(entry.MapperFile))
+	}
+	dirs[mapperDir] = "mapper.generated.go"
+	for _, source := range entry.Sources {
+		if source.Source == "direct" && source.TypesFile != "" {
+			dirs[filepath.Dir(filepath.FromSlash(source.TypesFile))] = generatedTypesFile
+		}
+	}
+
+	var files []string
+	for dir, created := range dirs {
+		dir = filepath.Join(cat.RepoPath(), dir)
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.generated.go"))
+		if !containsString(matches, filepath.Join(dir, created)) {
+			matches = append(matches, filepath.Join(dir, created))
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FormatMapperResult renders a mapper run as text
+func FormatMapperResult(result *MapperResult) string {
+	var b strings.Builder
+	switch {
+	case result.Success:
+		fmt.Fprintf(&b, "✅ Mapper generated successfully for %s\n", result.Resource)
+	case result.TimedOut:
+		fmt.Fprintf(&b, "⏱️  Mapper generation for %s timed out; output so far is below\n", result.Resource)
+	case result.Canceled:
+		fmt.Fprintf(&b, "🛑 Mapper generation for %s was cancelled; output so far is below\n", result.Resource)
+	default:
+		fmt.Fprintf(&b, "❌ Failed to generate mapper for %s\n", result.Resource)
+	}
+
+	if len(result.Diagnostics) > 0 {
+		fmt.Fprintf(&b, "\n%d diagnostic(s):\n", len(result.Diagnostics))
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tCLASS\tLOCATION\tFIELD\tPROTO PATH\tMESSAGE")
+		for _, d := range result.Diagnostics {
+			location := d.File
+			if d.Line > 0 {
+				location = fmt.Sprintf("%s:%d", d.File, d.Line)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Severity, d.Class, dash(location), dash(d.Field), dash(d.ProtoPath), d.Message)
+		}
+		w.Flush()
 
-%s
+		var hints []string
+		for _, d := range result.Diagnostics {
+			if d.Hint != "" && !containsString(hints, d.Hint) {
+				hints = append(hints, d.Hint)
+			}
+		}
+		if len(hints) > 0 {
+			b.WriteString("\nHow to fix:\n")
+			for _, h := range hints {
+				fmt.Fprintf(&b, "- %s\n", h)
+			}
+		}
+	}
 
+	if output := strings.TrimSpace(result.Output); output != "" {
+		fmt.Fprintf(&b, "\nGenerator output:\n%s\n", output)
+	}
+	if result.Operation != "" {
+		fmt.Fprintf(&b, "\nWritten files recorded as operation %s (revert with kcc_undo)\n", result.Operation)
+	}
+	if !result.Success && !result.TimedOut && !result.Canceled {
+		b.WriteString(`
 Make sure:
 1. Proto annotations (+kcc:proto=) are correct
 2. Proto definitions exist in mockgcp/third_party/googleapis/
-3. Field names match proto (use snake_case in annotation)`, resource, string(output))
+3. Field names match proto (use snake_case in annotation)
+`)
 	}
+	return strings.TrimRight(b.String(), "\n")
+}
 
-	return fmt.Sprintf("✅ Mapper generated successfully for %s\n\n%s", resource, string(output)), nil
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
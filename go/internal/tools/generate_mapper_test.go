@@ -0,0 +1,69 @@
+//go:build unix
+
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
+)
+
+// fakeGenerator installs a dev/tasks/generate-mapper script that records its
+// arguments in args.txt
+func fakeGenerator(t *testing.T, repo string) (argsFile string) {
+	t.Helper()
+	script := filepath.Join(repo, "dev", "tasks", "generate-mapper")
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\" > args.txt\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(repo, "args.txt")
+}
+
+func TestGenerateMapperResolvesResource(t *testing.T) {
+	cat := copyFixture(t)
+	argsFile := fakeGenerator(t, cat.RepoPath())
+
+	result, err := GenerateMapper(context.Background(), cat, "computeurlmap", NewChangeSet(cat.RepoPath(), false), runner.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || result.Resource != "ComputeURLMap" {
+		t.Errorf("got success = %t for %s", result.Success, result.Resource)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(args) != "ComputeURLMap\n" {
+		t.Errorf("generator arguments = %q, want the resolved Kind", args)
+	}
+}
+
+func TestGenerateMapperRejectsUnresolvedResources(t *testing.T) {
+	cat := copyFixture(t)
+	argsFile := fakeGenerator(t, cat.RepoPath())
+
+	for resource, want := range map[string]string{
+		"-h":          "invalid resource name",
+		"--foo=bar":   "invalid resource name",
+		"Spanner":     "resource not found: Spanner",
+		"URLMap":      "Ambiguous resource: URLMap matches 2 kinds",
+		"":            "invalid resource name",
+		"compute url": "invalid resource name",
+	} {
+		_, err := GenerateMapper(context.Background(), cat, resource, NewChangeSet(cat.RepoPath(), false), runner.Options{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("GenerateMapper(%q) = %v, want an error containing %q", resource, err, want)
+		}
+	}
+	if _, err := os.Stat(argsFile); !os.IsNotExist(err) {
+		t.Error("the generator ran for a resource that did not resolve")
+	}
+}
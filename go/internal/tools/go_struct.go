@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goStruct is a named struct type located in one of a package's source files
+type goStruct struct {
+	Name string
+	Path string // file declaring the struct
+	Src  []byte
+	Fset *token.FileSet
+	File *ast.File
+	Spec *ast.TypeSpec
+	Type *ast.StructType
+	Doc  *ast.CommentGroup // doc comment of the type declaration
+}
+
+// findGoStruct locates the declaration of struct name among the Go files of
+// dir. preferred, if set, is searched first, since types files usually
+// declare their own structs. Test files are ignored.
+func findGoStruct(dir, name, preferred string) (*goStruct, error) {
+	var found *goStruct
+	err := walkGoStructs(dir, preferred, func(s *goStruct) bool {
+		if s.Name == name {
+			found = s
+			return false
+		}
+		return true
+	})
+	return found, err
+}
+
+// findAnnotatedStructs returns the structs of dir whose doc comment maps them
+// to the proto message with the given full name
+func findAnnotatedStructs(dir, message, preferred string) ([]*goStruct, error) {
+	var found []*goStruct
+	err := walkGoStructs(dir, preferred, func(s *goStruct) bool {
+		if protoAnnotation(s.Doc) == message {
+			found = append(found, s)
+		}
+		return true
+	})
+	return found, err
+}
+
+// walkGoStructs calls fn for every struct type declared in the Go files of
+// dir, preferred file first, until fn returns false
+func walkGoStructs(dir, preferred string, fn func(*goStruct) bool) error {
+	return walkGoStructSources(dir, preferred, os.ReadFile, fn)
+}
+
+// walkGoStructSources is walkGoStructs with the file contents returned by
+// read, e.g. the pending content of a change set
+func walkGoStructSources(dir, preferred string, read func(path string) ([]byte, error), fn func(*goStruct) bool) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i] == preferred && files[j] != preferred
+	})
+
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := read(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		structs, err := parseGoStructSource(path, src)
+		if err != nil {
+			return err
+		}
+		for _, s := range structs {
+			if !fn(s) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// parseGoStructs parses path and returns its struct type declarations
+func parseGoStructs(path string) ([]*goStruct, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return parseGoStructSource(path, src)
+}
+
+// parseGoStructSource parses src, the content of path, and returns its
+// struct type declarations
+func parseGoStructSource(path string, src []byte) ([]*goStruct, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var structs []*goStruct
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			structs = append(structs, &goStruct{
+				Name: ts.Name.Name, Path: path, Src: src, Fset: fset, File: file,
+				Spec: ts, Type: st, Doc: doc,
+			})
+		}
+	}
+	return structs, nil
+}
+
+// end returns the offset just past the struct's type declaration
+func (s *goStruct) end() int {
+	return s.offset(s.Spec.End())
+}
+
+// offset returns the byte offset of pos in the struct's file
+func (s *goStruct) offset(pos token.Pos) int {
+	return s.Fset.Position(pos).Offset
+}
+
+// lineStart returns the offset of the start of the line containing offset
+func (s *goStruct) lineStart(offset int) int {
+	return strings.LastIndexByte(string(s.Src[:offset]), '\n') + 1
+}
+
+// fieldStart returns the offset of the line where a field's declaration,
+// including its doc comment, begins
+func (s *goStruct) fieldStart(f *ast.Field) int {
+	pos := f.Pos()
+	if f.Doc != nil {
+		pos = f.Doc.Pos()
+	}
+	return s.lineStart(s.offset(pos))
+}
+
+// protoAnnotation returns the +kcc:proto= value of a doc comment, or ""
+func protoAnnotation(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if value, ok := strings.CutPrefix(text, "+kcc:proto="); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// jsonTagName returns the name in a field's json struct tag, or ""
+func jsonTagName(f *ast.Field) string {
+	if f.Tag == nil {
+		return ""
+	}
+	tag := strings.Trim(f.Tag.Value, "`")
+	_, rest, ok := strings.Cut(tag, `json:"`)
+	if !ok {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, `"`)
+	name, _, _ := strings.Cut(value, ",")
+	return name
+}
+
+// ensureImports adds the import specs (e.g. `refs "example.com/refs"`) that
+// src does not import yet. The result is meant to be passed through
+// format.Source, which sorts the import block.
+func ensureImports(src []byte, specs []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := make(map[string]bool)
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			imported[path] = true
+		}
+	}
+	var missing []string
+	for _, spec := range specs {
+		quoted := spec[strings.Index(spec, `"`):]
+		if path, err := strconv.Unquote(quoted); err == nil && !imported[path] {
+			missing = append(missing, spec)
+			imported[path] = true
+		}
+	}
+	if len(missing) == 0 {
+		return src, nil
+	}
+
+	offset, text := fset.Position(file.Name.End()).Offset, "\n\nimport (\n\t"+strings.Join(missing, "\n\t")+"\n)"
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Rparen.IsValid() {
+			offset, text = fset.Position(gen.Rparen).Offset, "\n\t"+strings.Join(missing, "\n\t")+"\n"
+			// Join the existing group when the paren sits on its own line
+			if start := strings.LastIndexByte(string(src[:offset]), '\n') + 1; strings.TrimSpace(string(src[start:offset])) == "" {
+				offset, text = start, "\t"+strings.Join(missing, "\n\t")+"\n"
+			}
+		} else {
+			offset, text = fset.Position(gen.End()).Offset, "\nimport "+strings.Join(missing, "\nimport ")
+		}
+		break
+	}
+
+	out := make([]byte, 0, len(src)+len(text))
+	out = append(out, src[:offset]...)
+	out = append(out, text...)
+	return append(out, src[offset:]...), nil
+}
+
+// edit inserts text at an offset of a source file
+type edit struct {
+	offset int
+	text   string
+}
+
+// applyEdits applies insertions to src, which must not overlap
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+	out := append([]byte(nil), src...)
+	for _, e := range edits {
+		out = append(out[:e.offset], append([]byte(e.text), out[e.offset:]...)...)
+	}
+	return out
+}
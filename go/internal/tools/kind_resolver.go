@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ResourceKind describes a single Kind declaration found in a *_types.go file,
+// or in a CRD manifest for Kinds that have no Go types
+type ResourceKind struct {
+	Kind      string `json:"kind"`
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	TypesFile string `json:"types_file"` // the CRD manifest for source "crd"
+	Source    string `json:"source"`     // "direct", "terraform" or "crd"
+}
+
+// AmbiguousResourceError is returned when a resource name matches more than one Kind
+type AmbiguousResourceError struct {
+	Input      string         `json:"input"`
+	Candidates []ResourceKind `json:"candidates"`
+}
+
+func (e *AmbiguousResourceError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Ambiguous resource: %s matches %d kinds\n\nCandidates:\n", e.Input, len(kindNames(e.Candidates)))
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "  - %s (%s/%s, %s) %s\n", c.Kind, c.Service, c.Version, c.Source, c.TypesFile)
+	}
+	b.WriteString("\nPass the full Kind (e.g. ComputeURLMap) to select one.")
+	return b.String()
+}
+
+var (
+	// directKindPattern matches `var FooGVK = GroupVersion.WithKind("Foo")` in apis/
+	directKindPattern = regexp.MustCompile(`GroupVersion\.WithKind\(\s*"([A-Za-z0-9]+)"\s*\)`)
+
+	// terraformKindPattern matches `SchemeBuilder.Register(&Foo{}, &FooList{})`
+	// in pkg/clients/generated/; the GVK variables themselves are declared in
+	// each package's register.go
+	terraformKindPattern = regexp.MustCompile(`SchemeBuilder\.Register\(\s*&([A-Za-z0-9]+)\{\}`)
+)
+
+// isTypesFile reports whether a repository path names a *_types.go file
+func isTypesFile(relPath string) bool {
+	return strings.HasSuffix(relPath, "_types.go")
+}
+
+// parseKinds extracts the Kind declarations from the contents of a types file
+func parseKinds(relPath, source, content string) []ResourceKind {
+	parts := strings.Split(relPath, "/")
+	if len(parts) < 3 {
+		return nil
+	}
+	service := parts[len(parts)-3]
+	version := parts[len(parts)-2]
+
+	pattern := directKindPattern
+	if source == "terraform" {
+		pattern = terraformKindPattern
+	}
+
+	var kinds []ResourceKind
+	seen := make(map[string]bool)
+	for _, m := range pattern.FindAllStringSubmatch(content, -1) {
+		kind := m[1]
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		kinds = append(kinds, ResourceKind{
+			Kind:      kind,
+			Service:   service,
+			Version:   version,
+			TypesFile: relPath,
+			Source:    source,
+		})
+	}
+	return kinds
+}
+
+// resolveKind maps a client-supplied resource name onto the Kinds known in the
+// repository. Matching is attempted from most to least precise: exact Kind,
+// case-insensitive Kind, types file stem, Kind suffix, then substring. The
+// first strategy that matches wins; if it matches more than one distinct Kind
+// an *AmbiguousResourceError listing every candidate is returned.
+//
+// All entries for the winning Kind (every version and source) are returned,
+// ordered by preference (see sortKindEntries).
+func resolveKind(kinds []ResourceKind, resource string) ([]ResourceKind, error) {
+	lower := strings.ToLower(resource)
+
+	strategies := []func(k ResourceKind) bool{
+		func(k ResourceKind) bool { return k.Kind == resource },
+		func(k ResourceKind) bool { return strings.ToLower(k.Kind) == lower },
+		func(k ResourceKind) bool { return typesFileStem(k.TypesFile) == lower },
+		func(k ResourceKind) bool { return strings.HasSuffix(strings.ToLower(k.Kind), lower) },
+		func(k ResourceKind) bool { return strings.Contains(strings.ToLower(k.Kind), lower) },
+	}
+
+	for _, match := range strategies {
+		var matched []ResourceKind
+		for _, k := range kinds {
+			if match(k) {
+				matched = append(matched, k)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		sortKindEntries(matched)
+		if names := kindNames(matched); len(names) > 1 {
+			return nil, &AmbiguousResourceError{Input: resource, Candidates: matched}
+		}
+		return matched, nil
+	}
+
+	return nil, nil
+}
+
+// typesFileStem returns the resource part of a *_types.go file name
+func typesFileStem(relPath string) string {
+	return strings.TrimSuffix(filepath.Base(relPath), "_types.go")
+}
+
+// kindNames returns the distinct Kind names in entries, sorted
+func kindNames(entries []ResourceKind) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, e := range entries {
+		if !seen[e.Kind] {
+			seen[e.Kind] = true
+			names = append(names, e.Kind)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortKindEntries orders entries by Kind, then direct before terraform before
+// crd, then most stable version first (v1 > v1beta1 > v1alpha1)
+func sortKindEntries(entries []ResourceKind) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if ra, rb := sourceRank(a.Source), sourceRank(b.Source); ra != rb {
+			return ra < rb
+		}
+		if ra, rb := versionRank(a.Version), versionRank(b.Version); ra != rb {
+			return ra > rb
+		}
+		return a.TypesFile < b.TypesFile
+	})
+}
+
+// sourceRank orders the sources of a Kind from most to least specific
+func sourceRank(source string) int {
+	switch source {
+	case "direct":
+		return 0
+	case "terraform":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// versionRank ranks Kubernetes API versions by stability
+func versionRank(version string) int {
+	switch {
+	case strings.Contains(version, "alpha"):
+		return 1
+	case strings.Contains(version, "beta"):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// filterKindsBySource returns the entries that come from source
+func filterKindsBySource(entries []ResourceKind, source string) []ResourceKind {
+	var result []ResourceKind
+	for _, e := range entries {
+		if e.Source == source {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// kindTypesFiles returns the types file of every entry
+func kindTypesFiles(entries []ResourceKind) []string {
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, e.TypesFile)
+	}
+	return files
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testKinds mimics the declarations of a KCC checkout, in walk order
+var testKinds = []ResourceKind{
+	{Kind: "AlloyDBCluster", Service: "alloydb", Version: "v1beta1", TypesFile: "apis/alloydb/v1beta1/cluster_types.go", Source: "direct"},
+	{Kind: "ComputeRegionURLMap", Service: "compute", Version: "v1beta1", TypesFile: "pkg/clients/generated/apis/compute/v1beta1/computeregionurlmap_types.go", Source: "terraform"},
+	{Kind: "ComputeURLMap", Service: "compute", Version: "v1beta1", TypesFile: "pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go", Source: "terraform"},
+	{Kind: "ComputeURLMap", Service: "compute", Version: "v1alpha1", TypesFile: "apis/compute/v1alpha1/urlmap_types.go", Source: "direct"},
+	{Kind: "ComputeURLMap", Service: "compute", Version: "v1beta1", TypesFile: "apis/compute/v1beta1/urlmap_types.go", Source: "direct"},
+	{Kind: "PubSubLiteReservation", Service: "pubsublite", Version: "v1alpha1", TypesFile: "config/crds/resources/apiextensions.k8s.io_v1_customresourcedefinition_pubsublitereservations.pubsublite.cnrm.cloud.google.com.yaml", Source: "crd"},
+	{Kind: "RedisCluster", Service: "redis", Version: "v1beta1", TypesFile: "apis/redis/v1beta1/cluster_types.go", Source: "direct"},
+	{Kind: "RedisInstance", Service: "redis", Version: "v1beta1", TypesFile: "pkg/clients/generated/apis/redis/v1beta1/redisinstance_types.go", Source: "terraform"},
+	{Kind: "Redisinstance", Service: "redis", Version: "v1alpha1", TypesFile: "apis/redis/v1alpha1/redisinstance_types.go", Source: "direct"},
+}
+
+func TestResolveKind(t *testing.T) {
+	tests := []struct {
+		name      string
+		resource  string
+		want      []string // types files of the entries, in order
+		ambiguous []string // Kinds of an ambiguity, in order
+	}{
+		{
+			name:     "exact",
+			resource: "ComputeURLMap",
+			want: []string{
+				"apis/compute/v1beta1/urlmap_types.go",
+				"apis/compute/v1alpha1/urlmap_types.go",
+				"pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go",
+			},
+		},
+		{
+			name:     "exact wins over case-insensitive",
+			resource: "Redisinstance",
+			want:     []string{"apis/redis/v1alpha1/redisinstance_types.go"},
+		},
+		{
+			name:      "case-insensitive",
+			resource:  "redisinstance",
+			ambiguous: []string{"RedisInstance", "Redisinstance"},
+		},
+		{
+			name:     "case-insensitive CRD-only",
+			resource: "pubsublitereservation",
+			want:     []string{"config/crds/resources/apiextensions.k8s.io_v1_customresourcedefinition_pubsublitereservations.pubsublite.cnrm.cloud.google.com.yaml"},
+		},
+		{
+			// URLMap is a suffix of ComputeRegionURLMap too
+			name:     "stem rather than suffix",
+			resource: "URLMap",
+			want:     []string{"apis/compute/v1beta1/urlmap_types.go", "apis/compute/v1alpha1/urlmap_types.go"},
+		},
+		{
+			name:      "stem shared across services",
+			resource:  "cluster",
+			ambiguous: []string{"AlloyDBCluster", "RedisCluster"},
+		},
+		{
+			name:      "suffix",
+			resource:  "rlMap",
+			ambiguous: []string{"ComputeRegionURLMap", "ComputeURLMap"},
+		},
+		{
+			name:     "unique suffix",
+			resource: "RegionURLMap",
+			want:     []string{"pkg/clients/generated/apis/compute/v1beta1/computeregionurlmap_types.go"},
+		},
+		{
+			name:     "substring",
+			resource: "LiteReserv",
+			want:     []string{"config/crds/resources/apiextensions.k8s.io_v1_customresourcedefinition_pubsublitereservations.pubsublite.cnrm.cloud.google.com.yaml"},
+		},
+		{
+			name:      "ambiguous substring",
+			resource:  "Redis",
+			ambiguous: []string{"RedisCluster", "RedisInstance", "Redisinstance"},
+		},
+		{
+			name:     "no match",
+			resource: "Spanner",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds := append([]ResourceKind(nil), testKinds...)
+			got, err := resolveKind(kinds, tt.resource)
+
+			var ambiguous *AmbiguousResourceError
+			if tt.ambiguous != nil {
+				if !errors.As(err, &ambiguous) {
+					t.Fatalf("resolveKind(%q) = %v, %v; want an AmbiguousResourceError", tt.resource, got, err)
+				}
+				if names := kindNames(ambiguous.Candidates); !reflect.DeepEqual(names, tt.ambiguous) {
+					t.Errorf("candidates = %v, want %v", names, tt.ambiguous)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if files := kindTypesFiles(got); len(files)+len(tt.want) > 0 && !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("resolveKind(%q) = %v, want %v", tt.resource, files, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmbiguousResourceError(t *testing.T) {
+	kinds := append([]ResourceKind(nil), testKinds...)
+	_, err := resolveKind(kinds, "rlmaP")
+	if err == nil {
+		t.Fatal("expected an ambiguity")
+	}
+	// Candidates are grouped by Kind; within one, direct types and stable
+	// versions come first
+	want := `Ambiguous resource: rlmaP matches 2 kinds
+
+Candidates:
+  - ComputeRegionURLMap (compute/v1beta1, terraform) pkg/clients/generated/apis/compute/v1beta1/computeregionurlmap_types.go
+  - ComputeURLMap (compute/v1beta1, direct) apis/compute/v1beta1/urlmap_types.go
+  - ComputeURLMap (compute/v1alpha1, direct) apis/compute/v1alpha1/urlmap_types.go
+  - ComputeURLMap (compute/v1beta1, terraform) pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go
+
+Pass the full Kind (e.g. ComputeURLMap) to select one.`
+	if got := err.Error(); got != want {
+		t.Errorf("error:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// krmField is a field of a KRM schema, read either from the generated
+// Terraform/DCL Go types or from a CRD's OpenAPI schema
+type krmField struct {
+	Name        string // JSON name
+	Type        string // as declared, e.g. "*string" or "array<object>"
+	Kind        string // "string", "integer", "number", "boolean", "object", "map", "json", or "reference"
+	Repeated    bool   // a list of Kind
+	Required    bool
+	Description string
+	Fields      []*krmField // for objects and lists of objects
+}
+
+// krmSchema is the spec and status of a Kind
+type krmSchema struct {
+	Spec   []*krmField
+	Status []*krmField
+}
+
+// parseTerraformTypes reads the Spec and Status structs of kind, and the
+// structs they reference, from a generated types file under
+// pkg/clients/generated/apis
+func parseTerraformTypes(filename, content, kind string) (*krmSchema, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	structs := make(map[string]*ast.StructType)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				structs[spec.Name.Name] = st
+			}
+		}
+		return true
+	})
+
+	spec, ok := structs[kind+"Spec"]
+	if !ok {
+		return nil, fmt.Errorf("%s does not declare %sSpec", filename, kind)
+	}
+	schema := &krmSchema{Spec: goStructFields(spec, structs, map[string]bool{})}
+	if status, ok := structs[kind+"Status"]; ok {
+		schema.Status = goStructFields(status, structs, map[string]bool{})
+	}
+	return schema, nil
+}
+
+// goStructFields converts the JSON-tagged fields of a struct, recursing into
+// struct types declared in the same file. visiting guards against cycles.
+func goStructFields(st *ast.StructType, structs map[string]*ast.StructType, visiting map[string]bool) []*krmField {
+	var fields []*krmField
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 || f.Tag == nil {
+			continue
+		}
+		tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		field := &krmField{
+			Name:     name,
+			Type:     types.ExprString(f.Type),
+			Required: !strings.Contains(opts, "omitempty"),
+		}
+		var doc []string
+		if f.Doc != nil {
+			for _, line := range strings.Split(f.Doc.Text(), "\n") {
+				line = strings.TrimSpace(line)
+				switch {
+				case line == "+optional":
+					field.Required = false
+				case line == "+required":
+					field.Required = true
+				case line != "" && !strings.HasPrefix(line, "+"):
+					doc = append(doc, line)
+				}
+			}
+		}
+		field.Description = strings.Join(doc, " ")
+
+		expr := f.Type
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+		if arr, ok := expr.(*ast.ArrayType); ok {
+			field.Repeated = true
+			expr = arr.Elt
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+		}
+
+		switch t := expr.(type) {
+		case *ast.MapType:
+			field.Kind = "map"
+		case *ast.SelectorExpr:
+			switch t.Sel.Name {
+			case "ResourceRef":
+				field.Kind = "reference"
+			case "JSON":
+				field.Kind = "json"
+			default:
+				field.Kind = "object"
+			}
+		case *ast.Ident:
+			field.Kind = goScalarKind(t.Name)
+			if nested, ok := structs[t.Name]; ok && !visiting[t.Name] {
+				visiting[t.Name] = true
+				field.Kind = "object"
+				field.Fields = goStructFields(nested, structs, visiting)
+				delete(visiting, t.Name)
+			}
+		default:
+			field.Kind = "object"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// goScalarKind maps a Go scalar type name onto a schema kind
+func goScalarKind(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "int", "int32", "int64", "uint32", "uint64":
+		return "integer"
+	case "float32", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	}
+	return "object"
+}
+
+// parseCRDSchema reads the spec and status schema of a CRD manifest for the
+// given version, falling back to the storage version
+func parseCRDSchema(filename, content, version string) (*krmSchema, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	versions := yamlItems(yamlValue(yamlValue(&root, "spec"), "versions"))
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s declares no versions", filename)
+	}
+	chosen := versions[0]
+	for _, v := range versions {
+		if yamlScalar(yamlValue(v, "storage")) == "true" {
+			chosen = v
+		}
+	}
+	for _, v := range versions {
+		if yamlScalar(yamlValue(v, "name")) == version {
+			chosen = v
+		}
+	}
+
+	properties := yamlValue(yamlValue(yamlValue(chosen, "schema"), "openAPIV3Schema"), "properties")
+	spec := yamlValue(properties, "spec")
+	if spec == nil {
+		return nil, fmt.Errorf("%s has no spec schema for version %s", filename, yamlScalar(yamlValue(chosen, "name")))
+	}
+	return &krmSchema{
+		Spec:   crdObjectFields(spec),
+		Status: crdObjectFields(yamlValue(properties, "status")),
+	}, nil
+}
+
+// crdObjectFields converts the properties of an OpenAPI object schema
+func crdObjectFields(schema *yaml.Node) []*krmField {
+	properties := yamlValue(schema, "properties")
+	if properties == nil {
+		return nil
+	}
+	required := make(map[string]bool)
+	for _, item := range yamlItems(yamlValue(schema, "required")) {
+		required[yamlScalar(item)] = true
+	}
+
+	var fields []*krmField
+	for _, name := range yamlKeys(properties) {
+		field := crdField(name, yamlValue(properties, name))
+		field.Required = required[name]
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// crdField converts a single OpenAPI property schema
+func crdField(name string, schema *yaml.Node) *krmField {
+	field := &krmField{Name: name, Description: strings.Join(strings.Fields(yamlScalar(yamlValue(schema, "description"))), " ")}
+
+	typ := yamlScalar(yamlValue(schema, "type"))
+	elem := schema
+	if typ == "array" {
+		field.Repeated = true
+		elem = yamlValue(schema, "items")
+		typ = yamlScalar(yamlValue(elem, "type"))
+	}
+
+	switch {
+	case (strings.HasSuffix(name, "Ref") || strings.HasSuffix(name, "Refs")) && yamlValue(yamlValue(elem, "properties"), "external") != nil:
+		field.Kind = "reference"
+	case yamlScalar(yamlValue(elem, "x-kubernetes-preserve-unknown-fields")) == "true":
+		field.Kind = "json"
+	case typ == "object" && yamlValue(elem, "properties") == nil && yamlValue(elem, "additionalProperties") != nil:
+		field.Kind = "map"
+	case typ == "object" || typ == "":
+		field.Kind = "object"
+		field.Fields = crdObjectFields(elem)
+	default:
+		field.Kind = typ
+	}
+
+	field.Type = field.Kind
+	if field.Kind == "reference" || field.Kind == "json" {
+		field.Type = "object"
+	}
+	if field.Repeated {
+		field.Type = fmt.Sprintf("array<%s>", field.Type)
+	}
+	return field
+}
+
+// yamlNode returns the content of a document or alias node
+func yamlNode(n *yaml.Node) *yaml.Node {
+	for n != nil && (n.Kind == yaml.DocumentNode || n.Kind == yaml.AliasNode) {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		} else if len(n.Content) > 0 {
+			n = n.Content[0]
+		} else {
+			return nil
+		}
+	}
+	return n
+}
+
+// yamlValue returns the value of key in a mapping, or nil
+func yamlValue(n *yaml.Node, key string) *yaml.Node {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return yamlNode(n.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// yamlKeys returns the keys of a mapping in document order
+func yamlKeys(n *yaml.Node) []string {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keys = append(keys, n.Content[i].Value)
+	}
+	return keys
+}
+
+// yamlItems returns the items of a sequence, or nil
+func yamlItems(n *yaml.Node) []*yaml.Node {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// yamlScalar returns the value of a scalar, or "" for nil and collections
+func yamlScalar(n *yaml.Node) string {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
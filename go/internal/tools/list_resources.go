@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ListResourcesParams contains filters and ordering for listing resources
+type ListResourcesParams struct {
+	Service    string `json:"service,omitempty"`
+	Type       string `json:"type,omitempty"`    // default controller ("direct", "terraform", "dcl") or "hybrid"
+	Phase      string `json:"phase,omitempty"`   // phase number, phase name, or "complete"
+	SortBy     string `json:"sort_by,omitempty"` // "kind" (default), "service", "type", or "phase"
+	Descending bool   `json:"descending,omitempty"`
+	Offset     int    `json:"offset,omitempty"` // number of matching resources to skip
+	Limit      int    `json:"limit,omitempty"`  // page size, defaults to 50
+}
+
+// defaultListLimit is the page size of ListResources. Every listed resource
+// has its migration files read, so pages are kept small.
+const defaultListLimit = 50
+
+// ResourceSummary is a single row of the resource listing
+type ResourceSummary struct {
+	Kind           string   `json:"kind"`
+	Service        string   `json:"service"`
+	Versions       []string `json:"versions"`
+	ControllerType string   `json:"controller_type"`
+	Hybrid         bool     `json:"hybrid"`
+	Phase          int      `json:"phase"`
+	PhaseName      string   `json:"phase_name"`
+	Progress       string   `json:"progress"`
+	TypesFile      string   `json:"types_file"`
+}
+
+// ResourceList is the result of ListResources
+type ResourceList struct {
+	Total     int                 `json:"total"` // resources matching the filters, across all pages
+	Offset    int                 `json:"offset"`
+	Filters   ListResourcesParams `json:"filters"`
+	Resources []ResourceSummary   `json:"resources"`
+}
+
+// ListResources enumerates every Kind in the catalog with its controller type
+// and migration phase, applying the given filters and ordering. The phase
+// checks read each resource's migration files, so they only run for the
+// returned page unless the resources are filtered or sorted by phase.
+func ListResources(cat *Catalog, params ListResourcesParams) (*ResourceList, error) {
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = "kind"
+	}
+	switch sortBy {
+	case "kind", "service", "type", "phase":
+	default:
+		return nil, fmt.Errorf("unsupported sort_by: %s\n\nUse one of: kind, service, type, phase", sortBy)
+	}
+	if params.Offset < 0 || params.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit must not be negative")
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultListLimit
+	}
+
+	type listed struct {
+		summary ResourceSummary
+		info    *ControllerTypeInfo
+	}
+	var rows []listed
+	for _, entry := range cat.Entries() {
+		if params.Service != "" && !strings.EqualFold(entry.Service, params.Service) {
+			continue
+		}
+		if params.Type != "" && !matchesType(entry, params.Type) {
+			continue
+		}
+
+		info := controllerTypeInfoForEntry(entry.Kind, &entry)
+		rows = append(rows, listed{
+			summary: ResourceSummary{
+				Kind:           entry.Kind,
+				Service:        entry.Service,
+				Versions:       entry.Versions,
+				ControllerType: entry.ControllerType,
+				Hybrid:         entry.Hybrid,
+				TypesFile:      *info.Location,
+			},
+			info: info,
+		})
+	}
+
+	verifier := newPhaseVerifier(cat)
+	addPhase := func(r *listed) error {
+		status, err := buildMigrationStatus(verifier, r.summary.Kind, r.info)
+		if err != nil {
+			return err
+		}
+		r.summary.Phase = status.CurrentPhase.Number
+		r.summary.PhaseName = status.CurrentPhase.Name
+		r.summary.Progress = status.OverallProgress
+		return nil
+	}
+
+	byPhase := params.Phase != "" || sortBy == "phase"
+	if byPhase {
+		filtered := rows[:0]
+		for _, r := range rows {
+			if err := addPhase(&r); err != nil {
+				return nil, err
+			}
+			if params.Phase == "" || matchesPhase(r.summary, params.Phase) {
+				filtered = append(filtered, r)
+			}
+		}
+		rows = filtered
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].summary, rows[j].summary
+		if params.Descending {
+			a, b = b, a
+		}
+		switch sortBy {
+		case "service":
+			if a.Service != b.Service {
+				return a.Service < b.Service
+			}
+		case "type":
+			if a.ControllerType != b.ControllerType {
+				return a.ControllerType < b.ControllerType
+			}
+		case "phase":
+			if a.Phase != b.Phase {
+				return a.Phase < b.Phase
+			}
+		}
+		return a.Kind < b.Kind
+	})
+
+	total := len(rows)
+	page := rows[min(params.Offset, total):min(params.Offset+params.Limit, total)]
+	resources := make([]ResourceSummary, 0, len(page))
+	for _, r := range page {
+		if !byPhase {
+			if err := addPhase(&r); err != nil {
+				return nil, err
+			}
+		}
+		resources = append(resources, r.summary)
+	}
+
+	return &ResourceList{
+		Total:     total,
+		Offset:    params.Offset,
+		Filters:   params,
+		Resources: resources,
+	}, nil
+}
+
+// matchesType reports whether an entry has the requested default controller,
+// or supports several controllers when "hybrid" is requested
+func matchesType(entry CatalogEntry, controllerType string) bool {
+	if strings.EqualFold(controllerType, "hybrid") {
+		return entry.Hybrid
+	}
+	return strings.EqualFold(entry.ControllerType, controllerType)
+}
+
+// matchesPhase reports whether a summary is in the requested phase, given as
+// a phase number, a phase name, or "complete"
+func matchesPhase(summary ResourceSummary, phase string) bool {
+	if n, err := strconv.Atoi(phase); err == nil {
+		return summary.Phase == n && summary.PhaseName != "Complete"
+	}
+	return strings.EqualFold(summary.PhaseName, phase)
+}
+
+// FormatResourceTable renders a resource list as a compact text table
+func FormatResourceTable(list *ResourceList) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tSERVICE\tVERSIONS\tTYPE\tPHASE\tPROGRESS")
+	for _, r := range list.Resources {
+		controllerType := r.ControllerType
+		if r.Hybrid {
+			controllerType += " (hybrid)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d %s\t%s\n",
+			r.Kind, r.Service, strings.Join(r.Versions, ","), controllerType, r.Phase, r.PhaseName, r.Progress)
+	}
+	w.Flush()
+	switch {
+	case len(list.Resources) == list.Total:
+		fmt.Fprintf(&b, "\n%d resources", list.Total)
+	case len(list.Resources) == 0:
+		fmt.Fprintf(&b, "\nOffset %d is past the last of %d resources", list.Offset, list.Total)
+	default:
+		fmt.Fprintf(&b, "\n%d-%d of %d resources (pass offset for more)", list.Offset+1, list.Offset+len(list.Resources), list.Total)
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// kinds returns the Kinds of a resource list, in order
+func (l *ResourceList) kinds() []string {
+	var kinds []string
+	for _, r := range l.Resources {
+		kinds = append(kinds, r.Kind)
+	}
+	return kinds
+}
+
+func TestListResources(t *testing.T) {
+	cat := newFixtureCatalog(t)
+
+	tests := []struct {
+		name   string
+		params ListResourcesParams
+		want   []string
+		total  int
+	}{
+		{
+			name:   "all",
+			params: ListResourcesParams{},
+			want:   []string{"ComputeForwardingRule", "ComputeRegionURLMap", "ComputeURLMap", "DataprocWorkflowTemplate", "PubSubLiteReservation", "RedisCluster"},
+		},
+		{
+			name:   "terraform",
+			params: ListResourcesParams{Type: "terraform"},
+			want:   []string{"ComputeForwardingRule", "ComputeRegionURLMap", "ComputeURLMap", "PubSubLiteReservation"},
+		},
+		{
+			name:   "dcl",
+			params: ListResourcesParams{Type: "dcl"},
+			want:   []string{"DataprocWorkflowTemplate"},
+		},
+		{
+			name:   "hybrid",
+			params: ListResourcesParams{Type: "hybrid"},
+			want:   []string{"ComputeForwardingRule"},
+		},
+		{
+			name:   "service",
+			params: ListResourcesParams{Service: "Compute", SortBy: "kind", Descending: true},
+			want:   []string{"ComputeURLMap", "ComputeRegionURLMap", "ComputeForwardingRule"},
+		},
+		{
+			name:   "phase",
+			params: ListResourcesParams{Phase: "API Types"},
+			want:   []string{"DataprocWorkflowTemplate"},
+		},
+		{
+			name:   "sorted by type",
+			params: ListResourcesParams{SortBy: "type", Limit: 3},
+			want:   []string{"DataprocWorkflowTemplate", "RedisCluster", "ComputeForwardingRule"},
+			total:  6,
+		},
+		{
+			name:   "second page",
+			params: ListResourcesParams{Offset: 4, Limit: 4},
+			want:   []string{"PubSubLiteReservation", "RedisCluster"},
+			total:  6,
+		},
+		{
+			name:   "past the end",
+			params: ListResourcesParams{Offset: 10},
+			total:  6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := ListResources(cat, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := list.kinds(); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			total := tt.total
+			if total == 0 {
+				total = len(tt.want)
+			}
+			if list.Total != total {
+				t.Errorf("total = %d, want %d", list.Total, total)
+			}
+			for _, r := range list.Resources {
+				if r.Phase == 0 || r.Progress == "" {
+					t.Errorf("%s has no migration phase", r.Kind)
+				}
+			}
+		})
+	}
+}
+
+func TestListResourcesUnmigrated(t *testing.T) {
+	list, err := ListResources(newFixtureCatalog(t), ListResourcesParams{Service: "compute"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKind := make(map[string]ResourceSummary)
+	for _, r := range list.Resources {
+		byKind[r.Kind] = r
+	}
+	urlMap := byKind["ComputeURLMap"]
+	if urlMap.ControllerType != "terraform" || urlMap.TypesFile != "pkg/clients/generated/apis/compute/v1beta1/computeurlmap_types.go" {
+		t.Errorf("ComputeURLMap = %+v", urlMap)
+	}
+	if urlMap.Phase != 1 || urlMap.Progress != "0/7 phases" {
+		t.Errorf("ComputeURLMap is in phase %d (%s), want the first of 7", urlMap.Phase, urlMap.Progress)
+	}
+
+	out := FormatResourceTable(list)
+	if !strings.Contains(out, "ComputeForwardingRule  compute  v1beta1   terraform (hybrid)") || !strings.HasSuffix(out, "\n3 resources") {
+		t.Errorf("unexpected table:\n%s", out)
+	}
+
+	page, err := ListResources(newFixtureCatalog(t), ListResourcesParams{Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := FormatResourceTable(page); !strings.HasSuffix(out, "\n3-4 of 6 resources (pass offset for more)") {
+		t.Errorf("unexpected table footer:\n%s", out)
+	}
+}
+
+func TestListResourcesInvalid(t *testing.T) {
+	cat := newFixtureCatalog(t)
+	for _, params := range []ListResourcesParams{{SortBy: "size"}, {Offset: -1}, {Limit: -1}} {
+		if _, err := ListResources(cat, params); err == nil {
+			t.Errorf("ListResources(%+v) succeeded", params)
+		}
+	}
+}
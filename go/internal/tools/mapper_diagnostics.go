@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic classes reported by kcc_generate_mapper
+const (
+	DiagUnknownProtoField    = "unknown_proto_field"
+	DiagUnknownProtoMessage  = "unknown_proto_message"
+	DiagTypeMismatch         = "type_mismatch"
+	DiagMissingManualMapping = "missing_manual_mapping"
+	DiagUndefined            = "undefined"
+	DiagCompileError         = "compile_error"
+	DiagUnmappedField        = "unmapped_field"
+	DiagGeneratorError       = "generator_error"
+)
+
+// MapperDiagnostic is a single problem reported while generating a mapper
+type MapperDiagnostic struct {
+	Severity  string `json:"severity"` // "error" or "warning"
+	Class     string `json:"class"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Field     string `json:"field,omitempty"`      // Go field, e.g. "NetworkSpec.Mtu"
+	ProtoPath string `json:"proto_path,omitempty"` // +kcc:proto= path involved, if known
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+var (
+	// goErrorPattern matches compiler and vet errors: file.go:12:3: message
+	goErrorPattern = regexp.MustCompile(`^(?:#\s*)?((?:[\w.@-]+/)*[\w.-]+\.go):(\d+)(?::(\d+))?:\s+(.*)$`)
+
+	// unknownFieldPatterns match the generator's messages about +kcc:proto
+	// paths that name no field of the message
+	unknownFieldPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(?:proto )?field "?([\w.]+)"? (?:not found|does not exist)(?: in (?:message )?"?([\w.]+)"?)?`),
+		regexp.MustCompile(`(?i)unknown (?:proto )?field "?([\w.]+)"?(?: in (?:message )?"?([\w.]+)"?)?`),
+		regexp.MustCompile(`(?i)(?:could not|cannot|unable to) find (?:proto )?field "?([\w.]+)"?(?: in (?:message )?"?([\w.]+)"?)?`),
+	}
+
+	// unknownMessagePatterns match messages about unknown proto messages
+	unknownMessagePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(?:proto )?message "?([\w.]+)"? (?:not found|does not exist)`),
+		regexp.MustCompile(`(?i)(?:could not|cannot|unable to) find (?:proto )?message "?([\w.]+)"?`),
+		regexp.MustCompile(`(?i)unknown (?:proto )?message "?([\w.]+)"?`),
+	}
+
+	// undefinedMapperPattern matches a call to a mapping function that has to
+	// be written by hand, e.g. "undefined: NetworkPeering_FromProto"
+	undefinedMapperPattern = regexp.MustCompile(`undefined: (?:\w+\.)?(\w+_(?:To|From)Proto\w*)`)
+	undefinedPattern       = regexp.MustCompile(`undefined: ([\w.]+)`)
+
+	// typeMismatchPattern matches Go assignment errors
+	typeMismatchPattern = regexp.MustCompile(`cannot use |mismatched types|incompatible type|cannot convert`)
+
+	// selectorPattern extracts the field of an assignment such as out.Mtu = in.Mtu,
+	// or of a proto getter such as in.GetMtu()
+	selectorPattern = regexp.MustCompile(`\b(?:out|in|obj|krm|resource)\.(\w+)(\()?`)
+
+	// missingCommentPattern matches the generator's "// MISSING: Field" lines
+	missingCommentPattern = regexp.MustCompile(`^\s*//\s*MISSING:\s*(\w+)`)
+
+	// funcPattern matches the mapping functions of a generated mapper
+	funcPattern = regexp.MustCompile(`^func (\w+?)_(?:To|From)Proto\(`)
+)
+
+// parseMapperOutput turns generator and compiler output into diagnostics.
+// Lines that do not look like problems are ignored.
+func parseMapperOutput(repoPath, output string) []MapperDiagnostic {
+	diagnostics := []MapperDiagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if d, ok := parseMapperLine(repoPath, line); ok {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// parseMapperLine classifies a single line of generator output
+func parseMapperLine(repoPath, line string) (MapperDiagnostic, bool) {
+	d := MapperDiagnostic{Severity: "error", Message: line}
+
+	if m := goErrorPattern.FindStringSubmatch(line); m != nil {
+		d.File = m[1]
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		d.Message = m[4]
+		d.Field, d.ProtoPath = fieldAtLine(repoPath, d.File, d.Line)
+	}
+	msg := d.Message
+
+	// Strip klog/log prefixes such as "E0102 15:04:05.000 main.go:12]"
+	if i := strings.Index(msg, "] "); i >= 0 && strings.Contains(msg[:i], ".go:") {
+		msg = msg[i+2:]
+		d.Message = msg
+	}
+
+	for _, p := range unknownFieldPatterns {
+		if m := p.FindStringSubmatch(msg); m != nil {
+			d.Class = DiagUnknownProtoField
+			d.ProtoPath = m[1]
+			if len(m) > 2 && m[2] != "" && !strings.Contains(m[1], m[2]) {
+				d.ProtoPath = m[2] + "." + m[1]
+			}
+			d.Hint = "Fix the +kcc:proto= annotation: the field must exist in the proto message (snake_case). Check it with kcc_describe_proto"
+			return d, true
+		}
+	}
+	for _, p := range unknownMessagePatterns {
+		if m := p.FindStringSubmatch(msg); m != nil {
+			d.Class = DiagUnknownProtoMessage
+			d.ProtoPath = m[1]
+			d.Hint = "Fix the struct's +kcc:proto= annotation: the message must be vendored under " + protoRoot
+			return d, true
+		}
+	}
+	if m := undefinedMapperPattern.FindStringSubmatch(msg); m != nil {
+		d.Class = DiagMissingManualMapping
+		d.Field = defaultIfEmpty(d.Field, strings.SplitN(m[1], "_", 2)[0])
+		d.Hint = "Write the missing mapping function by hand in the service's mapper.go (the generator skips types it cannot map)"
+		return d, true
+	}
+	if typeMismatchPattern.MatchString(msg) {
+		d.Class = DiagTypeMismatch
+		if d.Field == "" {
+			if m := selectorPattern.FindStringSubmatch(msg); m != nil {
+				d.Field = m[1]
+				if m[2] != "" {
+					d.Field = strings.TrimPrefix(d.Field, "Get")
+				}
+			}
+		}
+		d.Hint = "Make the Go type match the proto field type (e.g. *int32 for int32, *string for enums and Timestamps), or map the field by hand"
+		return d, true
+	}
+	if m := undefinedPattern.FindStringSubmatch(msg); m != nil && d.File != "" {
+		d.Class = DiagUndefined
+		d.Field = defaultIfEmpty(d.Field, m[1])
+		d.Hint = "A referenced type is not declared; add the nested struct (kcc_add_field generates it) or fix the name"
+		return d, true
+	}
+	if d.File != "" {
+		d.Class = DiagCompileError
+		return d, true
+	}
+
+	lower := strings.ToLower(msg)
+	if strings.HasPrefix(lower, "error") || strings.HasPrefix(lower, "fatal") || strings.HasPrefix(lower, "panic:") ||
+		strings.HasPrefix(line, "E") && strings.Contains(line, "] ") || strings.HasPrefix(line, "F") && strings.Contains(line, "] ") {
+		d.Class = DiagGeneratorError
+		return d, true
+	}
+	return d, false
+}
+
+// fieldAtLine returns the Go field declared at a line of a types file and its
+// +kcc:proto annotation, if the line declares one
+func fieldAtLine(repoPath, file string, line int) (string, string) {
+	if !strings.HasSuffix(file, "_types.go") && !strings.HasSuffix(file, "types.generated.go") {
+		return "", ""
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, file)
+	}
+	var found struct{ field, proto string }
+	walkGoStructs(filepath.Dir(path), path, func(s *goStruct) bool {
+		if s.Path != path {
+			return false
+		}
+		for _, f := range s.Type.Fields.List {
+			if len(f.Names) == 0 {
+				continue
+			}
+			start := s.Fset.Position(f.Pos()).Line
+			if f.Doc != nil {
+				start = s.Fset.Position(f.Doc.Pos()).Line
+			}
+			if line >= start && line <= s.Fset.Position(f.End()).Line {
+				found.field = s.Name + "." + f.Names[0].Name
+				found.proto = protoAnnotation(f.Doc)
+				return false
+			}
+		}
+		return true
+	})
+	return found.field, found.proto
+}
+
+// locateProtoPaths fills in the location of diagnostics that only name a
+// proto path, using the +kcc:proto annotations in the resource's types files
+func locateProtoPaths(cat *Catalog, entry *CatalogEntry, diagnostics []MapperDiagnostic) {
+	if entry.TypesFile == "" {
+		return
+	}
+	typesFile := filepath.Join(cat.RepoPath(), filepath.FromSlash(entry.TypesFile))
+	for i := range diagnostics {
+		d := &diagnostics[i]
+		if d.File != "" || d.ProtoPath == "" {
+			continue
+		}
+		walkGoStructs(filepath.Dir(typesFile), typesFile, func(s *goStruct) bool {
+			for _, f := range s.Type.Fields.List {
+				if len(f.Names) == 0 || protoAnnotation(f.Doc) != d.ProtoPath {
+					continue
+				}
+				if rel, err := filepath.Rel(cat.RepoPath(), s.Path); err == nil {
+					d.File = filepath.ToSlash(rel)
+				}
+				d.Line = s.Fset.Position(f.Pos()).Line
+				d.Field = s.Name + "." + f.Names[0].Name
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// unmappedFields reports the "// MISSING: Field" comments the generator
+// leaves in the resource's mapping functions: proto fields with no KRM field
+func unmappedFields(cat *Catalog, entry *CatalogEntry) []MapperDiagnostic {
+	var diagnostics []MapperDiagnostic
+	short := kindWithoutService(entry.Kind, entry.Service)
+
+	file, err := os.Open(filepath.Join(cat.RepoPath(), filepath.FromSlash(entry.MapperFile)))
+	if err != nil {
+		return diagnostics
+	}
+	defer file.Close()
+
+	var current string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if m := funcPattern.FindStringSubmatch(text); m != nil {
+			current = m[1]
+			continue
+		}
+		m := missingCommentPattern.FindStringSubmatch(text)
+		if m == nil || !(strings.HasPrefix(current, entry.Kind) || strings.HasPrefix(current, short)) {
+			continue
+		}
+		diagnostics = append(diagnostics, MapperDiagnostic{
+			Severity: "warning",
+			Class:    DiagUnmappedField,
+			File:     entry.MapperFile,
+			Line:     line,
+			Field:    current + "." + m[1],
+			Message:  "proto field " + m[1] + " has no KRM field in " + current,
+			Hint:     "Add the field with kcc_add_field, or leave it unmapped if KCC should not expose it",
+		})
+	}
+	return diagnostics
+}
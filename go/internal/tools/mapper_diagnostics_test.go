@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mapperTestTypes replaces the RedisCluster types of the fixture; the line
+// numbers in mapperTestOutput refer to it
+const mapperTestTypes = `package v1beta1
+
+// +kcc:proto=google.cloud.redis.cluster.v1.Cluster
+type RedisClusterSpec struct {
+	// +kcc:proto=google.cloud.redis.cluster.v1.Cluster.shard_count
+	ShardCount *int64 ` + "`json:\"shardCount,omitempty\"`" + `
+
+	// +kcc:proto=google.cloud.redis.cluster.v1.Cluster.psc_configs
+	PscConfigs []PscConfig ` + "`json:\"pscConfigs,omitempty\"`" + `
+}
+
+var RedisClusterGVK = GroupVersion.WithKind("RedisCluster")
+`
+
+// writeRepoFile writes a file of a test repository
+func writeRepoFile(t *testing.T, repo, relPath, content string) {
+	t.Helper()
+	path := filepath.Join(repo, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseMapperOutput(t *testing.T) {
+	cat := copyFixture(t)
+	writeRepoFile(t, cat.RepoPath(), "apis/redis/v1beta1/cluster_types.go", mapperTestTypes)
+
+	tests := []struct {
+		name string
+		line string
+		want *MapperDiagnostic // nil if the line must not be reported
+	}{
+		{
+			name: "unknown proto field",
+			line: `E1016 10:12:33.123456  481516 mappergenerator.go:312] field "shard_counts" not found in message "google.cloud.redis.cluster.v1.Cluster"`,
+			want: &MapperDiagnostic{
+				Class:     DiagUnknownProtoField,
+				ProtoPath: "google.cloud.redis.cluster.v1.Cluster.shard_counts",
+				Message:   `field "shard_counts" not found in message "google.cloud.redis.cluster.v1.Cluster"`,
+			},
+		},
+		{
+			name: "unknown proto field with full path",
+			line: `error: unknown proto field google.cloud.redis.cluster.v1.Cluster.zone_distribution`,
+			want: &MapperDiagnostic{
+				Class:     DiagUnknownProtoField,
+				ProtoPath: "google.cloud.redis.cluster.v1.Cluster.zone_distribution",
+				Message:   `error: unknown proto field google.cloud.redis.cluster.v1.Cluster.zone_distribution`,
+			},
+		},
+		{
+			name: "unknown proto message",
+			line: `F1016 10:12:33.123456  481516 main.go:88] message "google.cloud.redis.cluster.v1.PscConfigs" not found`,
+			want: &MapperDiagnostic{
+				Class:     DiagUnknownProtoMessage,
+				ProtoPath: "google.cloud.redis.cluster.v1.PscConfigs",
+				Message:   `message "google.cloud.redis.cluster.v1.PscConfigs" not found`,
+			},
+		},
+		{
+			name: "type mismatch",
+			line: `pkg/controller/direct/redis/mapper.generated.go:41:18: cannot use direct.LazyPtr(in.GetShardCount()) (value of type *int32) as *int64 value in assignment`,
+			want: &MapperDiagnostic{
+				Class:   DiagTypeMismatch,
+				File:    "pkg/controller/direct/redis/mapper.generated.go",
+				Line:    41,
+				Column:  18,
+				Field:   "ShardCount",
+				Message: `cannot use direct.LazyPtr(in.GetShardCount()) (value of type *int32) as *int64 value in assignment`,
+			},
+		},
+		{
+			name: "missing manual mapping",
+			line: `pkg/controller/direct/redis/mapper.generated.go:52:20: undefined: PscConfig_FromProto`,
+			want: &MapperDiagnostic{
+				Class:   DiagMissingManualMapping,
+				File:    "pkg/controller/direct/redis/mapper.generated.go",
+				Line:    52,
+				Column:  20,
+				Field:   "PscConfig",
+				Message: "undefined: PscConfig_FromProto",
+			},
+		},
+		{
+			name: "undefined type in a types file",
+			line: `apis/redis/v1beta1/cluster_types.go:9:15: undefined: PscConfig`,
+			want: &MapperDiagnostic{
+				Class:     DiagUndefined,
+				File:      "apis/redis/v1beta1/cluster_types.go",
+				Line:      9,
+				Column:    15,
+				Field:     "RedisClusterSpec.PscConfigs",
+				ProtoPath: "google.cloud.redis.cluster.v1.Cluster.psc_configs",
+				Message:   "undefined: PscConfig",
+			},
+		},
+		{
+			name: "compile error",
+			line: `pkg/controller/direct/redis/mapper.generated.go:3:2: "fmt" imported and not used`,
+			want: &MapperDiagnostic{
+				Class:   DiagCompileError,
+				File:    "pkg/controller/direct/redis/mapper.generated.go",
+				Line:    3,
+				Column:  2,
+				Message: `"fmt" imported and not used`,
+			},
+		},
+		{
+			name: "generator error",
+			line: `E1016 10:12:33.123456  481516 main.go:54] error loading protos: open apis/redis/v1beta1/generate.sh: no such file or directory`,
+			want: &MapperDiagnostic{
+				Class:   DiagGeneratorError,
+				Message: "error loading protos: open apis/redis/v1beta1/generate.sh: no such file or directory",
+			},
+		},
+		{name: "klog info", line: `I1016 10:12:33.123456  481516 main.go:40] generating mapper for RedisCluster`},
+		{name: "package header", line: `# github.com/GoogleCloudPlatform/k8s-config-connector/pkg/controller/direct/redis`},
+		{name: "go download", line: `go: downloading cloud.google.com/go/redis v1.17.0`},
+		{name: "field list", line: `  processing field shard_count of google.cloud.redis.cluster.v1.Cluster`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMapperOutput(cat.RepoPath(), "\n  "+tt.line+"\n")
+			if tt.want == nil {
+				if len(got) > 0 {
+					t.Errorf("line was reported: %+v", got[0])
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d diagnostics, want 1", len(got))
+			}
+			d := got[0]
+			if d.Severity != "error" {
+				t.Errorf("severity = %s, want error", d.Severity)
+			}
+			d.Severity, d.Hint = "", ""
+			if d != *tt.want {
+				t.Errorf("got  %+v\nwant %+v", d, *tt.want)
+			}
+		})
+	}
+}
+
+func TestLocateProtoPaths(t *testing.T) {
+	cat := copyFixture(t)
+	writeRepoFile(t, cat.RepoPath(), "apis/redis/v1beta1/cluster_types.go", mapperTestTypes)
+	entry, err := cat.Resolve("RedisCluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics := []MapperDiagnostic{
+		{Class: DiagUnknownProtoField, ProtoPath: "google.cloud.redis.cluster.v1.Cluster.shard_count"},
+		{Class: DiagUnknownProtoField, ProtoPath: "google.cloud.redis.cluster.v1.Cluster.not_annotated"},
+	}
+	locateProtoPaths(cat, entry, diagnostics)
+	if d := diagnostics[0]; d.File != "apis/redis/v1beta1/cluster_types.go" || d.Line != 6 || d.Field != "RedisClusterSpec.ShardCount" {
+		t.Errorf("annotated field located at %s:%d (%s)", d.File, d.Line, d.Field)
+	}
+	if d := diagnostics[1]; d.File != "" || d.Field != "" {
+		t.Errorf("unknown proto path located at %s (%s)", d.File, d.Field)
+	}
+}
+
+func TestUnmappedFields(t *testing.T) {
+	cat := copyFixture(t)
+	writeRepoFile(t, cat.RepoPath(), "pkg/controller/direct/redis/mapper.generated.go", `package redis
+
+func Cluster_FromProto(mapCtx *direct.MapContext, in *pb.Cluster) *krm.Cluster {
+	out := &krm.Cluster{}
+	out.ShardCount = direct.LazyPtr(in.GetShardCount())
+	// MISSING: PscConfigs
+	//   MISSING:   Uid
+	return out
+}
+
+func RedisClusterSpec_ToProto(mapCtx *direct.MapContext, in *krm.RedisClusterSpec) *pb.Cluster {
+	out := &pb.Cluster{}
+	// MISSING: CreateTime
+	return out
+}
+
+func InstanceSpec_ToProto(mapCtx *direct.MapContext, in *krm.InstanceSpec) *pb.Instance {
+	out := &pb.Instance{}
+	// MISSING: Tier
+	return out
+}
+`)
+	entry, err := cat.Resolve("RedisCluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := unmappedFields(cat, entry)
+	want := []struct {
+		line  int
+		field string
+	}{
+		{6, "Cluster.PscConfigs"},
+		{7, "Cluster.Uid"},
+		{13, "RedisClusterSpec.CreateTime"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d unmapped fields, want %d: %+v", len(got), len(want), got)
+	}
+	for i, d := range got {
+		if d.Class != DiagUnmappedField || d.Severity != "warning" || d.File != entry.MapperFile || d.Line != want[i].line || d.Field != want[i].field {
+			t.Errorf("got %+v, want %s at line %d", d, want[i].field, want[i].line)
+		}
+	}
+}
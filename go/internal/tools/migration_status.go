@@ -2,71 +2,81 @@ package tools
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
-	"strings"
+	"path"
 )
 
 // PhaseStatus represents the status of a migration phase
 type PhaseStatus struct {
-	Number     int             `json:"number"`
-	Name       string          `json:"name"`
-	Status     string          `json:"status"` // "not_started", "in_progress", "completed"
-	FilesExist map[string]bool `json:"files_exist"`
+	Number      int             `json:"number"`
+	Name        string          `json:"name"`
+	Status      string          `json:"status"` // "not_started", "in_progress", "completed"
+	FilesExist  map[string]bool `json:"files_exist"`
+	UnmetChecks []string        `json:"unmet_checks"`
 }
 
 // MigrationStatus represents the overall migration status
 type MigrationStatus struct {
-	Resource         string        `json:"resource"`
-	OverallProgress  string        `json:"overall_progress"`
-	CurrentPhase     PhaseStatus   `json:"current_phase"`
-	Phases           []PhaseStatus `json:"phases"`
-	NextAction       string        `json:"next_action"`
-	CanAddFields     bool          `json:"can_add_fields"`
+	Resource        string        `json:"resource"`
+	OverallProgress string        `json:"overall_progress"`
+	CurrentPhase    PhaseStatus   `json:"current_phase"`
+	Phases          []PhaseStatus `json:"phases"`
+	NextAction      string        `json:"next_action"`
+	CanAddFields    bool          `json:"can_add_fields"`
+	ControllerType  string        `json:"controller_type"` // default controller, as detected
+	Hybrid          bool          `json:"hybrid"`          // a direct controller exists alongside the default one
+}
+
+// repoFileExists reports whether a repository path, which may be a glob
+// pattern, names an existing file
+func repoFileExists(repoPath, relPath string) bool {
+	matches, err := fs.Glob(os.DirFS(repoPath), relPath)
+	return err == nil && len(matches) > 0
 }
 
 // GetMigrationStatus checks the migration status for a resource
-func GetMigrationStatus(repoPath, resource string) (*MigrationStatus, error) {
-	info, err := DetectControllerType(repoPath, resource)
+func GetMigrationStatus(cat *Catalog, resource string) (*MigrationStatus, error) {
+	info, err := DetectControllerType(cat, resource)
 	if err != nil {
 		return nil, err
 	}
 
-	// If already migrated to direct controller
-	if info.Type == "direct" {
-		return &MigrationStatus{
-			Resource:        resource,
-			OverallProgress: "Migration complete",
-			CurrentPhase: PhaseStatus{
-				Number: 7,
-				Name:   "Complete",
-				Status: "completed",
-			},
-			Phases:       []PhaseStatus{},
-			NextAction:   "Migration complete. Use kcc_add_field to add new fields.",
-			CanAddFields: true,
-		}, nil
-	}
+	return buildMigrationStatus(newPhaseVerifier(cat), resource, info)
+}
 
-	if info.Service == nil || info.Version == nil {
+// buildMigrationStatus derives the migration status from detected controller
+// info. A phase only counts as completed when its files exist and every
+// content check passes. The phases are checked whatever the controller type:
+// a scaffolded controller registers itself, so a direct or hybrid Kind is
+// not necessarily a finished one.
+func buildMigrationStatus(v *phaseVerifier, resource string, info *ControllerTypeInfo) (*MigrationStatus, error) {
+	if info.entry == nil {
 		return nil, fmt.Errorf("could not determine service/version for %s", resource)
 	}
 
-	resourceLower := strings.ToLower(resource)
-	service := *info.Service
-	version := *info.Version
+	repoPath := v.cat.RepoPath()
+	entry := info.entry
+	service := entry.Service
 
-	// Define expected files for each phase
+	// Define expected files and content checks for each phase
 	type phaseDefinition struct {
 		number int
 		name   string
 		files  map[string]string
+		checks func(entry *CatalogEntry) []string
 	}
 
-	protoLocation := fmt.Sprintf("mockgcp/third_party/googleapis/google/cloud/%s/v1/*.proto", service)
-	if info.ProtoLocation != nil {
+	// Phase 1 is about the proto declaring the resource's message; failing
+	// that, any proto of the service, which the catalog may not have indexed
+	// yet, is a start
+	protoLocation := v.protoDeclaring(entry)
+	if protoLocation == "" && info.ProtoLocation != nil {
 		protoLocation = *info.ProtoLocation
 	}
+	if protoLocation == "" {
+		protoLocation = fmt.Sprintf("mockgcp/third_party/googleapis/google/cloud/%s/v1/*.proto", service)
+	}
 
 	phases := []phaseDefinition{
 		{
@@ -75,49 +85,56 @@ func GetMigrationStatus(repoPath, resource string) (*MigrationStatus, error) {
 			files: map[string]string{
 				"proto": protoLocation,
 			},
+			checks: v.checkProto,
 		},
 		{
 			number: 2,
 			name:   "API Types",
 			files: map[string]string{
-				"types": fmt.Sprintf("apis/%s/%s/%s_types.go", service, version, resourceLower),
+				"types": entry.TypesFile,
 			},
+			checks: v.checkTypes,
 		},
 		{
 			number: 3,
 			name:   "Identity Handler",
 			files: map[string]string{
-				"identity": fmt.Sprintf("apis/%s/%s/%s_identity.go", service, version, resourceLower),
+				"identity": entry.IdentityFile,
 			},
+			checks: v.checkIdentity,
 		},
 		{
 			number: 4,
 			name:   "Mapper",
 			files: map[string]string{
-				"mapper": fmt.Sprintf("pkg/controller/direct/%s/mapper.generated.go", service),
+				"mapper": entry.MapperFile,
 			},
+			checks: v.checkMapper,
 		},
 		{
 			number: 5,
 			name:   "Controller",
 			files: map[string]string{
-				"controller": fmt.Sprintf("pkg/controller/direct/%s/%s_controller.go", service, resourceLower),
+				"controller": entry.ControllerFile,
 			},
+			checks: v.checkController,
 		},
 		{
 			number: 6,
 			name:   "MockGCP",
 			files: map[string]string{
-				"mockgcp": fmt.Sprintf("mockgcp/mock%s/%s.go", service, resourceLower),
+				"mockgcp": entry.MockGCPFile,
 			},
+			checks: v.checkMockGCP,
 		},
 		{
 			number: 7,
 			name:   "Test Fixtures",
 			files: map[string]string{
-				"create_yaml": fmt.Sprintf("pkg/test/resourcefixture/testdata/basic/%s/%s/%s/create.yaml", service, version, resourceLower),
-				"update_yaml": fmt.Sprintf("pkg/test/resourcefixture/testdata/basic/%s/%s/%s/update.yaml", service, version, resourceLower),
+				"create_yaml": path.Join(entry.FixturesDir, "create.yaml"),
+				"update_yaml": path.Join(entry.FixturesDir, "update.yaml"),
 			},
+			checks: v.checkFixtures,
 		},
 	}
 
@@ -129,7 +146,7 @@ func GetMigrationStatus(repoPath, resource string) (*MigrationStatus, error) {
 		someExist := false
 
 		for key, filePath := range phase.files {
-			exists := fileExists(filepath.Join(repoPath, filePath))
+			exists := repoFileExists(repoPath, filePath)
 			filesExist[key] = exists
 			if exists {
 				someExist = true
@@ -139,18 +156,24 @@ func GetMigrationStatus(repoPath, resource string) (*MigrationStatus, error) {
 			}
 		}
 
+		unmet := []string{}
+		if someExist {
+			unmet = append(unmet, phase.checks(entry)...)
+		}
+
 		status := "not_started"
-		if allExist {
+		if allExist && len(unmet) == 0 {
 			status = "completed"
 		} else if someExist {
 			status = "in_progress"
 		}
 
 		phasesStatus = append(phasesStatus, PhaseStatus{
-			Number:     phase.number,
-			Name:       phase.name,
-			Status:     status,
-			FilesExist: filesExist,
+			Number:      phase.number,
+			Name:        phase.name,
+			Status:      status,
+			FilesExist:  filesExist,
+			UnmetChecks: unmet,
 		})
 	}
 
@@ -198,21 +221,38 @@ func GetMigrationStatus(repoPath, resource string) (*MigrationStatus, error) {
 		}
 	} else if currentPhase.Status == "in_progress" {
 		nextAction = fmt.Sprintf("Complete phase %d: %s", currentPhase.Number, currentPhase.Name)
+		if len(currentPhase.UnmetChecks) > 0 {
+			nextAction = fmt.Sprintf("%s (%d unmet check(s), first: %s)", nextAction, len(currentPhase.UnmetChecks), currentPhase.UnmetChecks[0])
+		}
 	}
 
-	return &MigrationStatus{
+	status := &MigrationStatus{
 		Resource:        resource,
 		OverallProgress: fmt.Sprintf("%d/%d phases", completed, total),
 		CurrentPhase:    currentPhase,
 		Phases:          phasesStatus,
 		NextAction:      nextAction,
 		CanAddFields:    completed >= 4, // Need types, identity, mapper, controller
-	}, nil
-}
+		ControllerType:  info.Type,
+		Hybrid:          info.Hybrid,
+	}
+	if completed < total {
+		return status, nil
+	}
 
-// fileExists is already defined in find_resource.go
-// but duplicating here to avoid circular import issues if needed
-func fileExistsLocal(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+	// Every phase passed: what is left depends on which controller is the default
+	switch {
+	case info.Type == "direct":
+		status.OverallProgress = "Migration complete"
+		status.CurrentPhase = PhaseStatus{Number: 7, Name: "Complete", Status: "completed"}
+		status.NextAction = "Migration complete. Use kcc_add_field to add new fields."
+	case info.Hybrid:
+		status.OverallProgress = fmt.Sprintf("%d/%d phases, direct controller available (opt-in), default is %s", completed, total, info.DefaultController)
+		status.CurrentPhase = PhaseStatus{Number: 8, Name: "Promote Direct Controller", Status: "in_progress"}
+		status.NextAction = fmt.Sprintf("Once the direct controller reaches parity, make it the default in %s.", staticConfigFile)
+		if info.ReconcilerAnnotation != nil {
+			status.NextAction = fmt.Sprintf("Objects opt into the direct controller with the annotation %s. %s", *info.ReconcilerAnnotation, status.NextAction)
+		}
+	}
+	return status, nil
 }
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrationStatusProtoPhase(t *testing.T) {
+	const redisProto = "mockgcp/third_party/googleapis/google/cloud/redis/v1/cloud_redis.proto"
+	tests := []struct {
+		name       string
+		resource   string
+		proto      string // content of redisProto, if written
+		refresh    bool   // whether the catalog indexes redisProto
+		wantStatus string
+		wantExists bool
+		wantUnmet  []string
+	}{
+		{
+			name:       "proto declaring the message",
+			resource:   "DataprocWorkflowTemplate",
+			wantStatus: "completed",
+			wantExists: true,
+		},
+		{
+			name:       "no proto",
+			resource:   "RedisCluster",
+			wantStatus: "not_started",
+		},
+		{
+			name:       "proto not indexed yet",
+			resource:   "RedisCluster",
+			proto:      "syntax = \"proto3\";\n\npackage google.cloud.redis.v1;\n\nmessage Cluster {\n  string name = 1;\n}\n",
+			wantStatus: "completed",
+			wantExists: true,
+		},
+		{
+			name:       "service proto without the message",
+			resource:   "RedisCluster",
+			proto:      "syntax = \"proto3\";\n\npackage google.cloud.redis.v1;\n\nmessage Instance {\n  string name = 1;\n}\n",
+			refresh:    true,
+			wantStatus: "in_progress",
+			wantExists: true,
+			wantUnmet:  []string{"no proto message named Cluster (case-insensitive) in the 1 redis protos under mockgcp/third_party/googleapis"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := copyFixture(t)
+			if tt.proto != "" {
+				writeRepoFile(t, cat.RepoPath(), redisProto, tt.proto)
+			}
+			if tt.refresh {
+				if _, err := cat.Refresh(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status, err := GetMigrationStatus(cat, tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			phase := status.Phases[0]
+			if phase.Status != tt.wantStatus || phase.FilesExist["proto"] != tt.wantExists {
+				t.Errorf("phase 1 is %s with proto existing = %t, want %s and %t", phase.Status, phase.FilesExist["proto"], tt.wantStatus, tt.wantExists)
+			}
+			if len(phase.UnmetChecks)+len(tt.wantUnmet) > 0 && !reflect.DeepEqual(phase.UnmetChecks, tt.wantUnmet) {
+				t.Errorf("unmet checks = %q, want %q", phase.UnmetChecks, tt.wantUnmet)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// phaseVerifier inspects the contents of migration files. Reads are cached so
+// that checking many resources of one service reads shared files only once.
+type phaseVerifier struct {
+	cat   *Catalog
+	cache map[string]*string
+}
+
+func newPhaseVerifier(cat *Catalog) *phaseVerifier {
+	return &phaseVerifier{cat: cat, cache: make(map[string]*string)}
+}
+
+// read returns the contents of a repository file, or false if it cannot be read
+func (v *phaseVerifier) read(relPath string) (string, bool) {
+	if cached, ok := v.cache[relPath]; ok {
+		if cached == nil {
+			return "", false
+		}
+		return *cached, true
+	}
+
+	data, err := os.ReadFile(filepath.Join(v.cat.RepoPath(), filepath.FromSlash(relPath)))
+	if err != nil {
+		v.cache[relPath] = nil
+		return "", false
+	}
+	content := string(data)
+	v.cache[relPath] = &content
+	return content, true
+}
+
+var (
+	// stubReturnPattern matches scaffolded methods that still return a stub error
+	stubReturnPattern = regexp.MustCompile(`return fmt\.Errorf\("[^"]*not yet implemented"\)`)
+
+	// temporaryReturnPattern matches scaffolded placeholder returns
+	temporaryReturnPattern = regexp.MustCompile(`return [^\n]*// Temporary`)
+
+	// scaffoldTODOPattern matches TODO comments left by the scaffold tools
+	scaffoldTODOPattern = regexp.MustCompile(`// TODO: (Implement|Add|Adjust|Get GCP client|Support URLs|Set project|Build field mask)[^\n]*`)
+
+	// structPattern matches a struct declaration and captures its name and body
+	structPattern = regexp.MustCompile(`(?s)type (\w+) struct \{(.*?)\n\}`)
+)
+
+// countTODOs returns the number of scaffold TODO comments in content
+func countTODOs(content string) int {
+	return len(scaffoldTODOPattern.FindAllString(content, -1))
+}
+
+// hasFields reports whether a struct body declares at least one field, i.e.
+// has a line that is neither blank nor a comment
+func hasFields(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "/*") && !strings.HasPrefix(line, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkProto verifies that a proto message for the resource is vendored
+func (v *phaseVerifier) checkProto(entry *CatalogEntry) []string {
+	protos := v.cat.ProtoFiles(entry.Service)
+	if len(protos) == 0 || v.protoDeclaring(entry) != "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("no proto message named %s (case-insensitive) in the %d %s protos under mockgcp/third_party/googleapis",
+		kindWithoutService(entry.Kind, entry.Service), len(protos), entry.Service)}
+}
+
+// protoDeclaring returns the vendored proto of the resource's service that
+// declares its message, or ""
+func (v *phaseVerifier) protoDeclaring(entry *CatalogEntry) string {
+	name := kindWithoutService(entry.Kind, entry.Service)
+	messagePattern := regexp.MustCompile(`(?im)^\s*message\s+` + regexp.QuoteMeta(name) + `\s*\{`)
+	for _, p := range v.cat.ProtoFiles(entry.Service) {
+		if content, ok := v.read(p); ok && messagePattern.MatchString(content) {
+			return p
+		}
+	}
+	return ""
+}
+
+// checkTypes verifies that the API types have been filled in
+func (v *phaseVerifier) checkTypes(entry *CatalogEntry) []string {
+	content, ok := v.read(entry.TypesFile)
+	if !ok {
+		return nil
+	}
+
+	var unmet []string
+	if !strings.Contains(content, fmt.Sprintf(`GroupVersion.WithKind("%s")`, entry.Kind)) {
+		unmet = append(unmet, fmt.Sprintf("%s does not declare GroupVersion.WithKind(%q)", entry.TypesFile, entry.Kind))
+	}
+	if strings.Contains(content, "TODO: Add fields here") {
+		unmet = append(unmet, "Spec still contains the scaffold placeholder \"TODO: Add fields here\"")
+	}
+	for _, m := range structPattern.FindAllStringSubmatch(content, -1) {
+		if strings.HasSuffix(m[1], "ObservedState") && !hasFields(m[2]) {
+			unmet = append(unmet, fmt.Sprintf("%s has no fields", m[1]))
+		}
+	}
+	return unmet
+}
+
+// checkIdentity verifies that the identity handler has been adjusted
+func (v *phaseVerifier) checkIdentity(entry *CatalogEntry) []string {
+	content, ok := v.read(entry.IdentityFile)
+	if !ok {
+		return nil
+	}
+
+	if n := countTODOs(content); n > 0 {
+		return []string{fmt.Sprintf("%s still has %d scaffold TODO(s)", entry.IdentityFile, n)}
+	}
+	return nil
+}
+
+// checkMapper verifies that mapper functions exist for the Spec and
+// ObservedState types declared in the types file
+func (v *phaseVerifier) checkMapper(entry *CatalogEntry) []string {
+	mapper, ok := v.read(entry.MapperFile)
+	if !ok {
+		return nil
+	}
+	types, ok := v.read(entry.TypesFile)
+	if !ok {
+		return []string{fmt.Sprintf("cannot verify mapper: %s does not exist", entry.TypesFile)}
+	}
+
+	// Hand-written mappers live next to the generated one
+	dir := path.Dir(entry.MapperFile)
+	if others, err := findFiles(v.cat.RepoPath(), dir, func(relPath string) bool {
+		return path.Dir(relPath) == dir && strings.HasSuffix(relPath, "mapper.go")
+	}); err == nil {
+		for _, other := range others {
+			if content, ok := v.read(other); ok {
+				mapper += content
+			}
+		}
+	}
+
+	var unmet []string
+	for _, m := range structPattern.FindAllStringSubmatch(types, -1) {
+		name := m[1]
+		if !strings.HasSuffix(name, "Spec") && !strings.HasSuffix(name, "ObservedState") {
+			continue
+		}
+		for _, fn := range []string{name + "_ToProto", name + "_FromProto"} {
+			if !strings.Contains(mapper, "func "+fn+"(") {
+				unmet = append(unmet, fmt.Sprintf("mapper function %s is missing from %s", fn, dir))
+			}
+		}
+	}
+	return unmet
+}
+
+// checkController verifies that the controller is registered and implemented
+func (v *phaseVerifier) checkController(entry *CatalogEntry) []string {
+	content, ok := v.read(entry.ControllerFile)
+	if !ok {
+		return nil
+	}
+
+	var unmet []string
+	if !regexp.MustCompile(`registry\.RegisterModel\(\s*(?:\w+\.)?` + regexp.QuoteMeta(entry.Kind) + `GVK\b`).MatchString(content) {
+		unmet = append(unmet, fmt.Sprintf("%s does not call registry.RegisterModel(krm.%sGVK, ...)", entry.ControllerFile, entry.Kind))
+	}
+	for _, m := range stubReturnPattern.FindAllString(content, -1) {
+		unmet = append(unmet, fmt.Sprintf("stub return remains: %s", m))
+	}
+	if n := len(temporaryReturnPattern.FindAllString(content, -1)); n > 0 {
+		unmet = append(unmet, fmt.Sprintf("%d placeholder return(s) marked \"// Temporary\" remain", n))
+	}
+	if n := countTODOs(content); n > 0 {
+		unmet = append(unmet, fmt.Sprintf("%s still has %d scaffold TODO(s)", entry.ControllerFile, n))
+	}
+	return unmet
+}
+
+// checkMockGCP verifies that the mock is implemented and its server registered
+func (v *phaseVerifier) checkMockGCP(entry *CatalogEntry) []string {
+	content, ok := v.read(entry.MockGCPFile)
+	if !ok {
+		return nil
+	}
+
+	var unmet []string
+	if n := countTODOs(content); n > 0 {
+		unmet = append(unmet, fmt.Sprintf("%s still has %d scaffold TODO(s)", entry.MockGCPFile, n))
+	}
+
+	serviceFile := path.Join(path.Dir(entry.MockGCPFile), "service.go")
+	serverName := fmt.Sprintf("%sServer", ServiceTitle(entry.Service))
+	service, ok := v.read(serviceFile)
+	switch {
+	case !ok:
+		unmet = append(unmet, fmt.Sprintf("%s does not exist; the mock service is not registered", serviceFile))
+	case !strings.Contains(service, serverName+"{"):
+		unmet = append(unmet, fmt.Sprintf("%s does not register %s", serviceFile, serverName))
+	}
+	return unmet
+}
+
+// checkFixtures verifies that the fixtures have recorded golden output
+func (v *phaseVerifier) checkFixtures(entry *CatalogEntry) []string {
+	if !fileExists(filepath.Join(v.cat.RepoPath(), entry.FixturesDir)) {
+		return nil
+	}
+
+	httpLog := path.Join(entry.FixturesDir, "_http.log")
+	if !fileExists(filepath.Join(v.cat.RepoPath(), httpLog)) {
+		return []string{fmt.Sprintf("%s golden file has not been recorded", httpLog)}
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package tools
 
 import (
 	"fmt"
-	"strings"
 )
 
 // MigrationPhase represents a single phase in the migration
@@ -24,43 +23,47 @@ type ProtoInfo struct {
 
 // MigrationPlan represents the complete migration plan
 type MigrationPlan struct {
-	Resource        string                 `json:"resource"`
-	CurrentType     string                 `json:"current_type"`
-	NeedsMigration  bool                   `json:"needs_migration"`
-	Phases          []MigrationPhase       `json:"phases"`
-	TargetFiles     map[string]string      `json:"target_files"`
-	ProtoInfo       *ProtoInfo             `json:"proto_info"`
-	NextAction      string                 `json:"next_action"`
+	Resource       string            `json:"resource"`
+	CurrentType    string            `json:"current_type"`
+	NeedsMigration bool              `json:"needs_migration"`
+	Phases         []MigrationPhase  `json:"phases"`
+	TargetFiles    map[string]string `json:"target_files"`
+	ProtoInfo      *ProtoInfo        `json:"proto_info"`
+	NextAction     string            `json:"next_action"`
 }
 
 // PlanMigration creates a detailed migration plan for a resource
-func PlanMigration(repoPath, resource string) (*MigrationPlan, error) {
-	info, err := DetectControllerType(repoPath, resource)
+func PlanMigration(cat *Catalog, resource string) (*MigrationPlan, error) {
+	info, err := DetectControllerType(cat, resource)
 	if err != nil {
 		return nil, err
 	}
 
-	if !info.MigrationNeeded {
-		return nil, fmt.Errorf("%s is already a direct controller at %s.\nNo migration needed. Use kcc_add_field to add fields.",
-			resource, *info.Location)
+	if info.entry == nil {
+		return nil, fmt.Errorf("could not determine service/version for %s.\nNo types file declares a matching Kind.",
+			resource)
 	}
 
-	if info.Service == nil || info.Version == nil {
-		return nil, fmt.Errorf("could not determine service/version for %s.\nFound at: %s",
+	if !info.MigrationNeeded && info.Hybrid && info.DefaultController != "direct" {
+		return nil, fmt.Errorf("%s already has a direct controller, but the default is %s.\n"+
+			"Objects opt in with the annotation %s.\nTo finish the migration make direct the default in %s.",
+			resource, info.DefaultController, *info.ReconcilerAnnotation, staticConfigFile)
+	}
+
+	if !info.MigrationNeeded {
+		return nil, fmt.Errorf("%s is already a direct controller at %s.\nNo migration needed. Use kcc_add_field to add fields.",
 			resource, *info.Location)
 	}
 
-	resourceLower := strings.ToLower(resource)
-	service := *info.Service
-	version := *info.Version
+	entry := info.entry
 
 	targetFiles := map[string]string{
-		"types_file":        fmt.Sprintf("apis/%s/%s/%s_types.go", service, version, resourceLower),
-		"identity_file":     fmt.Sprintf("apis/%s/%s/%s_identity.go", service, version, resourceLower),
-		"controller_file":   fmt.Sprintf("pkg/controller/direct/%s/%s_controller.go", service, resourceLower),
-		"mapper_file":       fmt.Sprintf("pkg/controller/direct/%s/mapper.generated.go", service),
-		"mockgcp_file":      fmt.Sprintf("mockgcp/mock%s/%s.go", service, resourceLower),
-		"test_fixtures_dir": fmt.Sprintf("pkg/test/resourcefixture/testdata/basic/%s/%s/%s", service, version, resourceLower),
+		"types_file":        entry.TypesFile,
+		"identity_file":     entry.IdentityFile,
+		"controller_file":   entry.ControllerFile,
+		"mapper_file":       entry.MapperFile,
+		"mockgcp_file":      entry.MockGCPFile,
+		"test_fixtures_dir": entry.FixturesDir,
 	}
 
 	protoTask := "⚠️  Check if proto exists in mockgcp/third_party/googleapis"
@@ -87,6 +90,7 @@ func PlanMigration(repoPath, resource string) (*MigrationPlan, error) {
 			Tasks: []string{
 				fmt.Sprintf("Create %s", targetFiles["types_file"]),
 				"Define Spec struct with all fields",
+				"Run kcc_field_parity to list existing fields and keep renamed or missing ones backwards compatible",
 				"Add +kcc:proto= annotations for each field",
 				"Define nested types if needed",
 				"Follow naming conventions (PascalCase)",
@@ -110,7 +114,7 @@ func PlanMigration(repoPath, resource string) (*MigrationPlan, error) {
 			Name:        "Mapper Generation",
 			Description: "Generate KRM ↔ Proto conversion functions",
 			Tasks: []string{
-				fmt.Sprintf("Run ./dev/tasks/generate-mapper %s", resource),
+				fmt.Sprintf("Run ./dev/tasks/generate-mapper %s", entry.Kind),
 				fmt.Sprintf("Verify %s updated", targetFiles["mapper_file"]),
 				"Check for any mapper errors",
 			},
@@ -172,7 +176,7 @@ func PlanMigration(repoPath, resource string) (*MigrationPlan, error) {
 			Service:      *info.Service,
 			Version:      protoVersion,
 			ProtoPackage: fmt.Sprintf("google.cloud.%s.v1", *info.Service),
-			ProtoMessage: resource,
+			ProtoMessage: kindWithoutService(entry.Kind, entry.Service),
 		}
 	}
 
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanMigration(t *testing.T) {
+	cat := newFixtureCatalog(t)
+
+	tests := []struct {
+		resource    string
+		currentType string
+		targets     map[string]string
+		protoInfo   ProtoInfo
+		nextAction  string
+	}{
+		{
+			resource:    "ComputeURLMap",
+			currentType: "terraform",
+			targets: map[string]string{
+				"types_file":        "apis/compute/v1beta1/urlmap_types.go",
+				"identity_file":     "apis/compute/v1beta1/urlmap_identity.go",
+				"controller_file":   "pkg/controller/direct/compute/urlmap_controller.go",
+				"mapper_file":       "pkg/controller/direct/compute/mapper.generated.go",
+				"mockgcp_file":      "mockgcp/mockcompute/urlmap.go",
+				"test_fixtures_dir": "pkg/test/resourcefixture/testdata/basic/compute/v1beta1/urlmap",
+			},
+			protoInfo:  ProtoInfo{Service: "compute", Version: "v1", ProtoPackage: "google.cloud.compute.v1", ProtoMessage: "URLMap"},
+			nextAction: "Check Phase 1: Verify proto definitions exist",
+		},
+		{
+			resource:    "dataprocworkflowtemplate",
+			currentType: "dcl",
+			targets: map[string]string{
+				"types_file":        "apis/dataproc/v1beta1/workflowtemplate_types.go",
+				"identity_file":     "apis/dataproc/v1beta1/workflowtemplate_identity.go",
+				"controller_file":   "pkg/controller/direct/dataproc/workflowtemplate_controller.go",
+				"mapper_file":       "pkg/controller/direct/dataproc/mapper.generated.go",
+				"mockgcp_file":      "mockgcp/mockdataproc/workflowtemplate.go",
+				"test_fixtures_dir": "pkg/test/resourcefixture/testdata/basic/dataproc/v1beta1/workflowtemplate",
+			},
+			protoInfo:  ProtoInfo{Service: "dataproc", Version: "v1", ProtoPackage: "google.cloud.dataproc.v1", ProtoMessage: "WorkflowTemplate"},
+			nextAction: "Start with Phase 2: Create API types using kcc_scaffold_types",
+		},
+		{
+			resource:    "PubSubLiteReservation",
+			currentType: "terraform",
+			targets: map[string]string{
+				"types_file":        "apis/pubsublite/v1beta1/reservation_types.go",
+				"identity_file":     "apis/pubsublite/v1beta1/reservation_identity.go",
+				"controller_file":   "pkg/controller/direct/pubsublite/reservation_controller.go",
+				"mapper_file":       "pkg/controller/direct/pubsublite/mapper.generated.go",
+				"mockgcp_file":      "mockgcp/mockpubsublite/reservation.go",
+				"test_fixtures_dir": "pkg/test/resourcefixture/testdata/basic/pubsublite/v1beta1/reservation",
+			},
+			protoInfo:  ProtoInfo{Service: "pubsublite", Version: "v1", ProtoPackage: "google.cloud.pubsublite.v1", ProtoMessage: "Reservation"},
+			nextAction: "Check Phase 1: Verify proto definitions exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			plan, err := PlanMigration(cat, tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !plan.NeedsMigration || plan.CurrentType != tt.currentType {
+				t.Errorf("needs migration = %t from %s, want true from %s", plan.NeedsMigration, plan.CurrentType, tt.currentType)
+			}
+			for key, want := range tt.targets {
+				if got := plan.TargetFiles[key]; got != want {
+					t.Errorf("%s = %s, want %s", key, got, want)
+				}
+			}
+			if plan.ProtoInfo == nil || *plan.ProtoInfo != tt.protoInfo {
+				t.Errorf("proto info = %+v, want %+v", plan.ProtoInfo, tt.protoInfo)
+			}
+			if plan.NextAction != tt.nextAction {
+				t.Errorf("next action = %q, want %q", plan.NextAction, tt.nextAction)
+			}
+			if len(plan.Phases) != 7 {
+				t.Errorf("plan has %d phases, want 7", len(plan.Phases))
+			}
+		})
+	}
+}
+
+func TestPlanMigrationNotNeeded(t *testing.T) {
+	cat := newFixtureCatalog(t)
+	for resource, want := range map[string]string{
+		"ComputeForwardingRule": "already has a direct controller, but the default is terraform",
+		"RedisCluster":          "is already a direct controller at apis/redis/v1beta1/cluster_types.go",
+		"SpannerInstance":       "could not determine service/version for SpannerInstance",
+	} {
+		if _, err := PlanMigration(cat, resource); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("PlanMigration(%s) = %v, want an error containing %q", resource, err, want)
+		}
+	}
+}
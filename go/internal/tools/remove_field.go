@@ -0,0 +1,517 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
+)
+
+// FieldRef identifies an existing field of a resource's types, either by Go
+// name or by its +kcc:proto= path
+type FieldRef struct {
+	Resource   string `json:"resource"`
+	TypesFile  string `json:"types_file,omitempty"`  // defaults to the resource's direct types file
+	ParentType string `json:"parent_type,omitempty"` // struct holding the field; searched package-wide when empty
+	FieldName  string `json:"field_name,omitempty"`  // Go field name, e.g. "Mtu"
+	ProtoPath  string `json:"proto_path,omitempty"`  // alternatively, e.g. google.cloud.compute.v1.Network.mtu
+}
+
+// RemoveFieldParams contains parameters for removing a field
+type RemoveFieldParams struct {
+	FieldRef
+	RegenerateMapper bool `json:"regenerate_mapper,omitempty"`
+	DryRun           bool `json:"dry_run,omitempty"` // return the diff without writing
+}
+
+// DeprecateFieldParams contains parameters for deprecating a field
+type DeprecateFieldParams struct {
+	FieldRef
+	Reason           string `json:"reason,omitempty"`      // why the field is deprecated, e.g. "The API no longer supports it"
+	Replacement      string `json:"replacement,omitempty"` // field to use instead, e.g. "spec.networkRef"
+	RegenerateMapper bool   `json:"regenerate_mapper,omitempty"`
+	DryRun           bool   `json:"dry_run,omitempty"` // return the diff without writing
+}
+
+// FieldReference is a place outside the types file that uses a field
+type FieldReference struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Kind string `json:"kind"` // "mapper", "controller", "mockgcp", "fixture" or "golden"
+	Text string `json:"text"`
+}
+
+// FieldChangeResult is the result of removing or deprecating a field
+type FieldChangeResult struct {
+	Action        string           `json:"action"` // "removed" or "deprecated"
+	DryRun        bool             `json:"dry_run,omitempty"`
+	File          string           `json:"file"`
+	Struct        string           `json:"struct"`
+	Field         string           `json:"field"`
+	JSONName      string           `json:"json_name,omitempty"`
+	ProtoPath     string           `json:"proto_path,omitempty"`
+	References    []FieldReference `json:"references"`
+	OrphanedTypes []string         `json:"orphaned_types,omitempty"` // nested structs no other field uses any more
+	Preview       *ChangePreview   `json:"preview,omitempty"`        // the diff, for a dry run
+	Mapper        *MapperResult    `json:"mapper,omitempty"`         // diagnostics of the mapper run, if regenerated
+	MapperOutput  string           `json:"mapper_output,omitempty"`
+	MapperError   string           `json:"mapper_error,omitempty"`
+	Operation     string           `json:"operation,omitempty"` // operation log ID, for kcc_undo
+}
+
+// deprecatedPrefix starts the doc comment of deprecated fields, following
+// KCC's convention for deprecated CRD fields
+const deprecatedPrefix = "DEPRECATED:"
+
+// RemoveField deletes a field, with its doc comment, from a types file and
+// reports the code, fixtures and golden files that still refer to it
+func RemoveField(ctx context.Context, cat *Catalog, params RemoveFieldParams, changes *ChangeSet, mapperOpts runner.Options) (*FieldChangeResult, error) {
+	target, field, name, err := findExistingField(cat, params.FieldRef)
+	if err != nil {
+		return nil, err
+	}
+	result := newFieldChangeResult(cat, "removed", target, field, name)
+	result.DryRun = changes.DryRun()
+
+	// A name sharing its declaration is dropped from the name list; the
+	// remaining names still use the type, so nothing is orphaned
+	if len(field.Names) > 1 {
+		if err := writeGoFile(changes, target.Path, removeFieldName(target, field, name)); err != nil {
+			return nil, err
+		}
+		finishFieldChange(ctx, cat, params.Resource, params.RegenerateMapper, changes, mapperOpts, result)
+		return result, nil
+	}
+
+	// Remove the declaration together with the blank line that follows it
+	start := target.fieldStart(field)
+	end := target.offset(field.End())
+	if nl := bytes.IndexByte(target.Src[end:], '\n'); nl >= 0 {
+		end += nl + 1
+	}
+	if strings.TrimSpace(lineAt(target.Src, end)) == "" && end < target.offset(target.Type.Fields.Closing) {
+		end += len(lineAt(target.Src, end)) + 1
+	}
+	content := append(append([]byte(nil), target.Src[:start]...), target.Src[end:]...)
+
+	if err := writeGoFile(changes, target.Path, content); err != nil {
+		return nil, err
+	}
+
+	result.OrphanedTypes = orphanedTypes(changes, target, field)
+	finishFieldChange(ctx, cat, params.Resource, params.RegenerateMapper, changes, mapperOpts, result)
+	return result, nil
+}
+
+// DeprecateField marks a field deprecated in its doc comment and reports the
+// code, fixtures and golden files that refer to it
+func DeprecateField(ctx context.Context, cat *Catalog, params DeprecateFieldParams, changes *ChangeSet, mapperOpts runner.Options) (*FieldChangeResult, error) {
+	target, field, name, err := findExistingField(cat, params.FieldRef)
+	if err != nil {
+		return nil, err
+	}
+	if field.Doc != nil && strings.Contains(field.Doc.Text(), deprecatedPrefix) {
+		return nil, fmt.Errorf("%s.%s is already deprecated", target.Name, name.Name)
+	}
+	result := newFieldChangeResult(cat, "deprecated", target, field, name)
+	result.DryRun = changes.DryRun()
+
+	notice := deprecatedPrefix
+	if params.Reason != "" {
+		notice += " " + strings.TrimSuffix(strings.TrimSpace(params.Reason), ".") + "."
+	} else {
+		notice += " This field is deprecated and will be removed in a future version."
+	}
+	if params.Replacement != "" {
+		notice += fmt.Sprintf(" Use %s instead.", params.Replacement)
+	}
+
+	// The notice leads the doc comment so that it starts the CRD description
+	offset := target.fieldStart(field)
+	indent := lineAt(target.Src, offset)
+	indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+	content := applyEdits(target.Src, []edit{{offset: offset, text: indent + "// " + notice + "\n"}})
+	if len(field.Names) > 1 {
+		// Only this name is deprecated, so it gets a declaration of its own
+		// ahead of the shared one, keeping the shared doc comment
+		decl := indent + "// " + notice + "\n" +
+			string(target.Src[offset:target.lineStart(target.offset(field.Pos()))]) +
+			indent + name.Name + " " + string(target.Src[target.offset(field.Type.Pos()):target.offset(field.End())]) + "\n\n"
+		content = applyEdits(removeFieldName(target, field, name), []edit{{offset: offset, text: decl}})
+	}
+
+	if err := writeGoFile(changes, target.Path, content); err != nil {
+		return nil, err
+	}
+
+	finishFieldChange(ctx, cat, params.Resource, params.RegenerateMapper, changes, mapperOpts, result)
+	return result, nil
+}
+
+// findExistingField locates the field described by ref. A declaration may
+// name several fields (A, B string); name is the one that matched.
+func findExistingField(cat *Catalog, ref FieldRef) (*goStruct, *ast.Field, *ast.Ident, error) {
+	if ref.FieldName == "" && ref.ProtoPath == "" {
+		return nil, nil, nil, fmt.Errorf("either field_name or proto_path is required")
+	}
+	typesFile, err := resolveTypesFile(cat, ref.TypesFile, ref.Resource)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	filePath := filepath.Join(cat.RepoPath(), typesFile)
+
+	type match struct {
+		s    *goStruct
+		f    *ast.Field
+		name *ast.Ident
+	}
+	var matches []match
+	err = walkGoStructs(filepath.Dir(filePath), filePath, func(s *goStruct) bool {
+		if ref.ParentType != "" && s.Name != ref.ParentType {
+			return true
+		}
+		for _, f := range s.Type.Fields.List {
+			if ref.ProtoPath != "" && protoAnnotation(f.Doc) != ref.ProtoPath {
+				continue
+			}
+			for _, name := range f.Names {
+				if ref.FieldName != "" && name.Name != ref.FieldName {
+					continue
+				}
+				matches = append(matches, match{s, f, name})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	selector := ref.FieldName
+	if ref.ProtoPath != "" {
+		selector = strings.TrimSpace(selector + " +kcc:proto=" + ref.ProtoPath)
+	}
+	switch len(matches) {
+	case 0:
+		scope := "package " + filepath.Dir(typesFile)
+		if ref.ParentType != "" {
+			scope = ref.ParentType
+		}
+		return nil, nil, nil, fmt.Errorf("no field %s in %s", selector, scope)
+	case 1:
+		return matches[0].s, matches[0].f, matches[0].name, nil
+	}
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.s.Name+"."+m.name.Name)
+	}
+	return nil, nil, nil, fmt.Errorf("field %s is ambiguous: %s\n\nPass parent_type to pick one", selector, strings.Join(names, ", "))
+}
+
+// newFieldChangeResult describes the field before it is changed
+func newFieldChangeResult(cat *Catalog, action string, s *goStruct, f *ast.Field, name *ast.Ident) *FieldChangeResult {
+	relPath, _ := filepath.Rel(cat.RepoPath(), s.Path)
+	return &FieldChangeResult{
+		Action:     action,
+		File:       relPath,
+		Struct:     s.Name,
+		Field:      name.Name,
+		JSONName:   jsonTagName(f),
+		ProtoPath:  protoAnnotation(f.Doc),
+		References: []FieldReference{},
+	}
+}
+
+// finishFieldChange collects the references to the field and regenerates the
+// mapper if requested. Mapper failures are reported, not returned, since the
+// types file has already been changed. A dry run never regenerates: the
+// generator reads the types from disk.
+func finishFieldChange(ctx context.Context, cat *Catalog, resource string, regenerate bool, changes *ChangeSet, mapperOpts runner.Options, result *FieldChangeResult) {
+	result.References = findFieldReferences(cat, resource, result)
+	if result.DryRun {
+		result.Preview = changes.Preview()
+	}
+	defer func() { result.Operation = changes.OperationID() }()
+	if !regenerate || result.DryRun {
+		return
+	}
+	mapper, err := GenerateMapper(ctx, cat, resource, changes, mapperOpts)
+	if err != nil {
+		result.MapperError = err.Error()
+		return
+	}
+	result.Mapper = mapper
+	if !mapper.Success {
+		result.MapperError = FormatMapperResult(mapper)
+		return
+	}
+	result.MapperOutput = FormatMapperResult(mapper)
+}
+
+// removeFieldName returns the struct's file with name dropped from the name
+// list of a declaration that has several
+func removeFieldName(s *goStruct, f *ast.Field, name *ast.Ident) []byte {
+	start, end := s.offset(name.Pos()), s.offset(name.End())
+	for i, n := range f.Names {
+		if n != name {
+			continue
+		}
+		if i+1 < len(f.Names) {
+			end = s.offset(f.Names[i+1].Pos())
+		} else {
+			start = s.offset(f.Names[i-1].End())
+		}
+	}
+	return append(append([]byte(nil), s.Src[:start]...), s.Src[end:]...)
+}
+
+// writeGoFile formats content and writes it to path through changes
+func writeGoFile(changes *ChangeSet, path string, content []byte) error {
+	formatted, err := format.Source(content)
+	if err != nil {
+		return fmt.Errorf("change would leave %s unparsable: %w", path, err)
+	}
+	if err := changes.Write(path, formatted); err != nil {
+		return err
+	}
+	return changes.Apply()
+}
+
+// lineAt returns the line starting at offset, without its newline
+func lineAt(src []byte, offset int) string {
+	line := src[offset:]
+	if nl := bytes.IndexByte(line, '\n'); nl >= 0 {
+		line = line[:nl]
+	}
+	return string(line)
+}
+
+// orphanedTypes returns the package structs that the removed field referred
+// to and that no other field of the package uses. The package is read
+// through changes, so a dry run sees the field already removed.
+func orphanedTypes(changes *ChangeSet, s *goStruct, removed *ast.Field) []string {
+	candidates := make(map[string]bool)
+	ast.Inspect(removed.Type, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			candidates[id.Name] = true
+		}
+		return true
+	})
+
+	declared := make(map[string]bool)
+	used := make(map[string]bool)
+	walkGoStructSources(filepath.Dir(s.Path), s.Path, changes.Content, func(other *goStruct) bool {
+		declared[other.Name] = true
+		for _, f := range other.Type.Fields.List {
+			ast.Inspect(f.Type, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					used[id.Name] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+
+	var orphans []string
+	for name := range candidates {
+		if declared[name] && !used[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// findFieldReferences searches the resource's mapper and controller
+// packages for the Go field and its proto getter, its MockGCP service for the
+// proto field, and its fixtures and golden files for the JSON name
+func findFieldReferences(cat *Catalog, resource string, result *FieldChangeResult) []FieldReference {
+	refs := []FieldReference{}
+	entry, err := cat.Resolve(resource)
+	if err != nil || entry == nil {
+		return refs
+	}
+	goSource := func(kind func(relPath string) string) func(string) string {
+		return func(relPath string) string {
+			if !strings.HasSuffix(relPath, ".go") || strings.HasSuffix(relPath, "_test.go") {
+				return ""
+			}
+			return kind(relPath)
+		}
+	}
+
+	goPatterns := []*regexp.Regexp{regexp.MustCompile(`\.` + regexp.QuoteMeta(result.Field) + `\b`)}
+	var protoField string
+	if i := strings.LastIndex(result.ProtoPath, "."); i >= 0 {
+		protoField = result.ProtoPath[i+1:]
+		getter := "Get" + protoGoName(protoField)
+		goPatterns = append(goPatterns, regexp.MustCompile(`\b`+regexp.QuoteMeta(getter)+`\(`))
+	}
+
+	// The mapper and controller live side by side in the service package
+	if entry.ControllerFile != "" {
+		refs = append(refs, grepFiles(cat.RepoPath(), path.Dir(entry.ControllerFile), goPatterns, goSource(func(relPath string) string {
+			if strings.Contains(filepath.Base(relPath), "mapper") {
+				return "mapper"
+			}
+			return "controller"
+		}))...)
+	}
+
+	// The mock works on the proto message: its Go field, getter and, in
+	// field masks, its proto name
+	if protoField != "" && entry.MockGCPFile != "" {
+		mockPatterns := []*regexp.Regexp{
+			regexp.MustCompile(`\.` + regexp.QuoteMeta(protoGoName(protoField)) + `\b`),
+			goPatterns[1],
+			regexp.MustCompile(`"` + regexp.QuoteMeta(protoField) + `"`),
+		}
+		refs = append(refs, grepFiles(cat.RepoPath(), path.Dir(entry.MockGCPFile), mockPatterns, goSource(func(string) string {
+			return "mockgcp"
+		}))...)
+	}
+
+	// Fixtures and generated objects are YAML or JSON; the recorded HTTP
+	// logs hold the JSON bodies, whose keys follow the proto field
+	var names []string
+	if result.JSONName != "" {
+		names = append(names, result.JSONName)
+	}
+	if protoField != "" {
+		if name := protoJSONName(protoField); name != result.JSONName {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return refs
+	}
+	var keyPatterns []*regexp.Regexp
+	for _, name := range names {
+		keyPatterns = append(keyPatterns,
+			regexp.MustCompile(`^\s*(- )?`+regexp.QuoteMeta(name)+`:`),
+			regexp.MustCompile(`"`+regexp.QuoteMeta(name)+`"\s*:`))
+	}
+	for _, dir := range entry.FixtureDirs {
+		refs = append(refs, grepFiles(cat.RepoPath(), dir, keyPatterns, func(relPath string) string {
+			base := filepath.Base(relPath)
+			switch {
+			case strings.Contains(base, ".golden") || strings.HasSuffix(base, "_http.log"):
+				return "golden"
+			case strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".json"):
+				return "fixture"
+			}
+			return ""
+		})...)
+	}
+	return refs
+}
+
+// grepFiles returns the lines of the files under dir that match any of
+// patterns. classify returns the reference kind of a file, or "" to skip it.
+func grepFiles(repoPath, dir string, patterns []*regexp.Regexp, classify func(relPath string) string) []FieldReference {
+	var refs []FieldReference
+	root := filepath.Join(repoPath, filepath.FromSlash(dir))
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		relPath, _ := filepath.Rel(repoPath, path)
+		relPath = filepath.ToSlash(relPath)
+		kind := classify(relPath)
+		if kind == "" {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			for _, p := range patterns {
+				if p.MatchString(text) {
+					refs = append(refs, FieldReference{File: relPath, Line: line, Kind: kind, Text: strings.TrimSpace(text)})
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return refs
+}
+
+// protoGoName returns the name protoc-gen-go gives a proto field
+// (self_link -> SelfLink)
+func protoGoName(protoName string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(protoName, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// fieldActionTitles capitalises FieldChangeResult.Action for messages
+var fieldActionTitles = map[string]string{
+	"removed":    "Removed",
+	"deprecated": "Deprecated",
+}
+
+// FormatFieldChange renders the result of removing or deprecating a field
+func FormatFieldChange(result *FieldChangeResult) string {
+	var b strings.Builder
+	if result.DryRun {
+		fmt.Fprintf(&b, "Would %s %s.%s in %s\n", strings.TrimSuffix(result.Action, "d"), result.Struct, result.Field, result.File)
+	} else {
+		fmt.Fprintf(&b, "✅ %s %s.%s in %s\n", fieldActionTitles[result.Action], result.Struct, result.Field, result.File)
+	}
+	if result.ProtoPath != "" {
+		fmt.Fprintf(&b, "   +kcc:proto=%s\n", result.ProtoPath)
+	}
+
+	if len(result.References) == 0 {
+		b.WriteString("\nNo references found in the mapper, controller, fixtures or golden files\n")
+	} else {
+		fmt.Fprintf(&b, "\n%d reference(s) to update:\n", len(result.References))
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tLOCATION\tLINE")
+		for _, r := range result.References {
+			fmt.Fprintf(w, "%s\t%s:%d\t%s\n", r.Kind, r.File, r.Line, r.Text)
+		}
+		w.Flush()
+	}
+
+	if len(result.OrphanedTypes) > 0 {
+		fmt.Fprintf(&b, "\nNo longer used (remove if not needed): %s\n", strings.Join(result.OrphanedTypes, ", "))
+	}
+	switch {
+	case result.MapperError != "":
+		fmt.Fprintf(&b, "\n⚠️  Mapper regeneration failed:\n%s\n", result.MapperError)
+	case result.MapperOutput != "":
+		fmt.Fprintf(&b, "\n%s\n", result.MapperOutput)
+	case result.DryRun && result.Preview != nil:
+		fmt.Fprintf(&b, "\n🔍 Dry run: no files were written\n\n%s", result.Preview.Diff)
+	default:
+		b.WriteString("\nNext: run kcc_generate_mapper to regenerate the mapper\n")
+	}
+	if result.Operation != "" && result.Mapper == nil {
+		fmt.Fprintf(&b, "\nRecorded as operation %s (revert with kcc_undo)\n", result.Operation)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/runner"
+)
+
+func TestRemoveFieldOrphanedTypes(t *testing.T) {
+	before := readTestdata(t, "nested_message.go.golden")
+	params := RemoveFieldParams{FieldRef: FieldRef{
+		Resource:   "WidgetsWidget",
+		TypesFile:  testTypesFile,
+		ParentType: "WidgetSpec",
+		FieldName:  "Config",
+	}}
+
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry run %t", dryRun), func(t *testing.T) {
+			cat := newTestRepo(t, before)
+			changes := NewChangeSet(cat.RepoPath(), dryRun)
+			result, err := RemoveField(context.Background(), cat, params, changes, runner.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.OrphanedTypes; len(got) != 1 || got[0] != "Widget_Config" {
+				t.Errorf("orphaned types = %v, want [Widget_Config]", got)
+			}
+
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed := !strings.Contains(string(got), "Config *Widget_Config"); removed == dryRun {
+				t.Errorf("field removed from disk = %t in a dry run = %t", removed, dryRun)
+			}
+
+			out := FormatFieldChange(result)
+			want := "✅ Removed WidgetSpec.Config"
+			if dryRun {
+				want = "Would remove WidgetSpec.Config"
+			}
+			if !strings.HasPrefix(out, want) || !strings.Contains(out, "No longer used (remove if not needed): Widget_Config") {
+				t.Errorf("unexpected output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestDeprecateField(t *testing.T) {
+	base := readTestdata(t, "nested_message.go.golden")
+	multiName := strings.Replace(base, "Depth *int32 `json:\"depth,omitempty\"`", "Depth, Width *int32", 1)
+
+	tests := []struct {
+		name    string
+		src     string
+		params  DeprecateFieldParams
+		want    []string // snippets of the types file afterwards
+		notWant []string
+		wantErr string
+	}{
+		{
+			name: "reason and replacement",
+			src:  base,
+			params: DeprecateFieldParams{
+				FieldRef:    FieldRef{Resource: "WidgetsWidget", TypesFile: testTypesFile, FieldName: "SizeGb"},
+				Reason:      "Sizes are chosen by the API.",
+				Replacement: "spec.config",
+			},
+			want: []string{"\t// DEPRECATED: Sizes are chosen by the API. Use spec.config instead.\n\t// The size in GB.\n"},
+		},
+		{
+			name:   "by proto path",
+			src:    base,
+			params: DeprecateFieldParams{FieldRef: FieldRef{Resource: "WidgetsWidget", TypesFile: testTypesFile, ProtoPath: "google.cloud.widgets.v1.Widget.Config.enabled"}},
+			want:   []string{"\t// DEPRECATED: This field is deprecated and will be removed in a future version.\n\t// Whether the widget is enabled.\n"},
+		},
+		{
+			name:   "second name of a declaration",
+			src:    multiName,
+			params: DeprecateFieldParams{FieldRef: FieldRef{Resource: "WidgetsWidget", TypesFile: testTypesFile, FieldName: "Width"}},
+			want: []string{
+				"\t// DEPRECATED: This field is deprecated and will be removed in a future version.\n\t// The nesting depth.\n\t// +kcc:proto=google.cloud.widgets.v1.Widget.Config.depth\n\tWidth *int32\n",
+				"\tDepth *int32\n",
+			},
+			notWant: []string{"Depth, Width"},
+		},
+		{
+			name:    "already deprecated",
+			src:     strings.Replace(base, "\t// The size in GB.\n", "\t// DEPRECATED: Use config.\n\t// The size in GB.\n", 1),
+			params:  DeprecateFieldParams{FieldRef: FieldRef{Resource: "WidgetsWidget", TypesFile: testTypesFile, FieldName: "SizeGb"}},
+			wantErr: "WidgetSpec.SizeGb is already deprecated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cat := newTestRepo(t, tt.src)
+			changes := NewChangeSet(cat.RepoPath(), false)
+			_, err := DeprecateField(context.Background(), cat, tt.params, changes, runner.Options{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("types file does not contain %q:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(got), notWant) {
+					t.Errorf("types file still contains %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveFieldSharedDeclaration(t *testing.T) {
+	src := strings.Replace(readTestdata(t, "nested_message.go.golden"), "Depth *int32 `json:\"depth,omitempty\"`", "Depth, Width, Height *int32", 1)
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "Depth", want: "\tWidth, Height *int32\n"},
+		{field: "Width", want: "\tDepth, Height *int32\n"},
+		{field: "Height", want: "\tDepth, Width *int32\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			cat := newTestRepo(t, src)
+			params := RemoveFieldParams{FieldRef: FieldRef{Resource: "WidgetsWidget", TypesFile: testTypesFile, FieldName: tt.field}}
+			result, err := RemoveField(context.Background(), cat, params, NewChangeSet(cat.RepoPath(), false), runner.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Struct != "Widget_Config" || result.Field != tt.field || len(result.OrphanedTypes) != 0 {
+				t.Errorf("result = %s.%s, orphaned %v", result.Struct, result.Field, result.OrphanedTypes)
+			}
+			got, err := os.ReadFile(filepath.Join(cat.RepoPath(), testTypesFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), tt.want) || !strings.Contains(string(got), "// The nesting depth.") {
+				t.Errorf("types file does not contain %q:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFindFieldReferences(t *testing.T) {
+	cat := copyFixture(t)
+	const fixtureDir = "pkg/test/resourcefixture/testdata/basic/redis/v1beta1/rediscluster/"
+	files := map[string]string{
+		"pkg/controller/direct/redis/mapper.generated.go":         "package redis\n\nfunc f() {\n\tout.ShardCount = direct.LazyPtr(in.GetShardCount())\n}\n",
+		"pkg/controller/direct/redis/cluster_diff.go":             "package redis\n\nfunc g() {\n\tif desired.ShardCount != nil {\n\t}\n}\n",
+		"pkg/controller/direct/redis/cluster_controller_test.go":  "package redis\n\nvar _ = spec.ShardCount\n",
+		"mockgcp/mockredis/cluster.go":                            "package mockredis\n\nfunc h() {\n\tobj.ShardCount = proto.Int32(3)\n\tswitch path {\n\tcase \"shard_count\":\n\t}\n\tobj.ShardCountMax = 0\n}\n",
+		fixtureDir + "create.yaml":                                "kind: RedisCluster\nspec:\n  shardCount: 3\n  location: us-central1\n",
+		fixtureDir + "update.json":                                "{\n  \"spec\": {\n    \"shardCount\" : 4\n  }\n}\n",
+		fixtureDir + "_generated_object_rediscluster.golden.yaml": "spec:\n  shardCount: 3\n",
+		fixtureDir + "_http.log":                                  "POST https://redis.googleapis.com/v1/projects/p/locations/l/clusters\n\n{\n  \"shardCount\": 3\n}\n",
+		fixtureDir + "README.md":                                  "shardCount: not a fixture\n",
+	}
+	for relPath, content := range files {
+		writeRepoFile(t, cat.RepoPath(), relPath, content)
+	}
+
+	result := &FieldChangeResult{
+		Field:     "ShardCount",
+		JSONName:  "shardCount",
+		ProtoPath: "google.cloud.redis.cluster.v1.Cluster.shard_count",
+	}
+	got := findFieldReferences(cat, "RedisCluster", result)
+	want := []FieldReference{
+		{File: "pkg/controller/direct/redis/cluster_diff.go", Line: 4, Kind: "controller", Text: "if desired.ShardCount != nil {"},
+		{File: "pkg/controller/direct/redis/mapper.generated.go", Line: 4, Kind: "mapper", Text: "out.ShardCount = direct.LazyPtr(in.GetShardCount())"},
+		{File: "mockgcp/mockredis/cluster.go", Line: 4, Kind: "mockgcp", Text: "obj.ShardCount = proto.Int32(3)"},
+		{File: "mockgcp/mockredis/cluster.go", Line: 6, Kind: "mockgcp", Text: `case "shard_count":`},
+		{File: fixtureDir + "_generated_object_rediscluster.golden.yaml", Line: 2, Kind: "golden", Text: "shardCount: 3"},
+		{File: fixtureDir + "_http.log", Line: 4, Kind: "golden", Text: `"shardCount": 3`},
+		{File: fixtureDir + "create.yaml", Line: 3, Kind: "fixture", Text: "shardCount: 3"},
+		{File: fixtureDir + "update.json", Line: 3, Kind: "fixture", Text: `"shardCount" : 4`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("references:\ngot  %+v\nwant %+v", got, want)
+	}
+}
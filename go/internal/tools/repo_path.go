@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Path violation codes reported in PathError
+const (
+	PathOutsideRepository = "outside_repository"
+	PathWriteNotAllowed   = "write_not_allowed"
+	PathInvalid           = "invalid_path"
+)
+
+// writableRoots are the KCC subtrees tools may write to, relative to the
+// repository
+var writableRoots = []string{
+	"apis",
+	"pkg/controller/direct",
+	"mockgcp",
+	"pkg/test/resourcefixture",
+}
+
+// PathError reports a file path a tool refused to use
+type PathError struct {
+	Code     string   `json:"code"` // outside_repository, write_not_allowed or invalid_path
+	Param    string   `json:"param,omitempty"`
+	Path     string   `json:"path"`
+	Resolved string   `json:"resolved,omitempty"` // canonical path, symlinks resolved
+	Reason   string   `json:"reason"`
+	Allowed  []string `json:"allowed,omitempty"` // writable subtrees, for write_not_allowed
+}
+
+func (e *PathError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "❌ %s: %s", e.Code, e.Reason)
+	if e.Param != "" {
+		fmt.Fprintf(&b, "\nParameter: %s", e.Param)
+	}
+	fmt.Fprintf(&b, "\nPath: %s", e.Path)
+	if e.Resolved != "" && e.Resolved != e.Path {
+		fmt.Fprintf(&b, "\nResolves to: %s", e.Resolved)
+	}
+	if len(e.Allowed) > 0 {
+		fmt.Fprintf(&b, "\n\nTools may only write under: %s", strings.Join(e.Allowed, ", "))
+	}
+	return b.String()
+}
+
+// resolveRepoPath canonicalises path, relative to the repository or
+// absolute, resolving symlinks, and rejects anything outside the repository
+func resolveRepoPath(repoPath, path string) (string, error) {
+	root, err := canonicalPath(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve repository path %s: %w", repoPath, err)
+	}
+
+	full := filepath.FromSlash(path)
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(root, full)
+	}
+	resolved, err := canonicalPath(full)
+	if err != nil {
+		return "", &PathError{Code: PathInvalid, Path: path, Reason: err.Error()}
+	}
+	if !within(root, resolved) {
+		return "", &PathError{
+			Code:     PathOutsideRepository,
+			Path:     path,
+			Resolved: resolved,
+			Reason:   "path resolves outside kcc_repo_path " + root,
+		}
+	}
+	return resolved, nil
+}
+
+// ResolveWritePath is resolveRepoPath for a file a tool or an undo is about
+// to write: it must also lie in one of the writable KCC subtrees
+func ResolveWritePath(repoPath, path string) (string, error) {
+	resolved, err := resolveRepoPath(repoPath, path)
+	if err != nil {
+		return "", err
+	}
+	root, _ := canonicalPath(repoPath)
+	for _, dir := range writableRoots {
+		if within(filepath.Join(root, filepath.FromSlash(dir)), resolved) {
+			return resolved, nil
+		}
+	}
+	rel, _ := filepath.Rel(root, resolved)
+	return "", &PathError{
+		Code:     PathWriteNotAllowed,
+		Path:     path,
+		Resolved: resolved,
+		Reason:   fmt.Sprintf("%s is not in a subtree tools may write to", filepath.ToSlash(rel)),
+		Allowed:  writableRoots,
+	}
+}
+
+// repoRelPath returns a resolved path relative to the repository, with
+// forward slashes
+func repoRelPath(repoPath, resolved string) string {
+	root, err := canonicalPath(repoPath)
+	if err != nil {
+		root = repoPath
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return filepath.ToSlash(resolved)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// checkPathElements rejects parameters that are joined into paths but are
+// not single, plain path elements, e.g. a service of "../../etc"
+func checkPathElements(params map[string]string) error {
+	names := make([]string, 0, len(params))
+	for param := range params {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+	for _, param := range names {
+		value := params[param]
+		if value == "" || value == "." || value == ".." || strings.ContainsAny(value, `/\`) || strings.ContainsRune(value, 0) {
+			return &PathError{
+				Code:   PathInvalid,
+				Param:  param,
+				Path:   value,
+				Reason: param + " must be a single path element without separators or ..",
+			}
+		}
+	}
+	return nil
+}
+
+// withParam records which tool parameter a path error came from
+func withParam(err error, param string) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) && pathErr.Param == "" {
+		pathErr.Param = param
+	}
+	return err
+}
+
+// maxSymlinks bounds the links canonicalPath follows, as the kernel does
+const maxSymlinks = 40
+
+// canonicalPath makes path absolute and resolves symlinks in the longest
+// part of it that exists, so paths of files not created yet can be checked.
+// Dangling symlinks are followed too: writing through one would create its
+// target.
+func canonicalPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for links := 0; ; {
+		existing, rest := abs, ""
+		for {
+			resolved, err := filepath.EvalSymlinks(existing)
+			if err == nil {
+				return filepath.Join(resolved, rest), nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+			if info, err := os.Lstat(existing); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				break
+			}
+			parent := filepath.Dir(existing)
+			if parent == existing {
+				return abs, nil
+			}
+			rest = filepath.Join(filepath.Base(existing), rest)
+			existing = parent
+		}
+
+		// existing is a dangling symlink: continue from its target
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+		}
+		target, err := os.Readlink(existing)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(existing), target)
+		}
+		abs = filepath.Join(target, rest)
+	}
+}
+
+// within reports whether path is dir or inside it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newPathTestRepo returns a repository with the writable subtrees and a
+// directory outside it
+func newPathTestRepo(t *testing.T) (repo, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	repo, outside = filepath.Join(base, "repo"), filepath.Join(base, "outside")
+	for _, dir := range []string{"apis/compute/v1beta1", "mockgcp", "pkg/clients/generated", "pkg/controller/direct"} {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return repo, outside
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveWritePath(t *testing.T) {
+	repo, outside := newPathTestRepo(t)
+	symlink(t, outside, filepath.Join(repo, "apis", "evil"))
+	symlink(t, "../../../outside/new.go", filepath.Join(repo, "mockgcp", "dangling.go"))
+	symlink(t, "compute/v1beta1/new_types.go", filepath.Join(repo, "apis", "inside.go"))
+	symlink(t, "loop.go", filepath.Join(repo, "apis", "loop.go"))
+	symlink(t, "../pkg/clients/generated", filepath.Join(repo, "apis", "generated"))
+
+	tests := []struct {
+		name string
+		path string
+		code string // "" if the write is allowed
+		want string // resolved path relative to the repository, if allowed
+	}{
+		{name: "relative", path: "apis/compute/v1beta1/network_types.go", want: "apis/compute/v1beta1/network_types.go"},
+		{name: "absolute inside", path: filepath.Join(repo, "mockgcp/mock.go"), want: "mockgcp/mock.go"},
+		{name: "new directory", path: "pkg/controller/direct/widgets/controller.go", want: "pkg/controller/direct/widgets/controller.go"},
+		{name: "dot dot staying inside", path: "apis/compute/../compute/v1beta1/x.go", want: "apis/compute/v1beta1/x.go"},
+		{name: "dangling symlink inside", path: "apis/inside.go", want: "apis/compute/v1beta1/new_types.go"},
+		{name: "parent traversal", path: "../../etc/passwd", code: PathOutsideRepository},
+		{name: "traversal from a writable subtree", path: "apis/../../outside/x.go", code: PathOutsideRepository},
+		{name: "absolute outside", path: "/etc/passwd", code: PathOutsideRepository},
+		{name: "symlinked directory", path: "apis/evil/x.go", code: PathOutsideRepository},
+		{name: "dangling symlink outside", path: "mockgcp/dangling.go", code: PathOutsideRepository},
+		{name: "generated clients", path: "pkg/clients/generated/apis/x.go", code: PathWriteNotAllowed},
+		{name: "symlink into generated clients", path: "apis/generated/x.go", code: PathWriteNotAllowed},
+		{name: "repository root", path: ".", code: PathWriteNotAllowed},
+		{name: "go.mod", path: "go.mod", code: PathWriteNotAllowed},
+		{name: "symlink loop", path: "apis/loop.go", code: PathInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveWritePath(repo, tt.path)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := filepath.Join(canonical(t, repo), filepath.FromSlash(tt.want)); got != want {
+					t.Errorf("resolved to %s, want %s", got, want)
+				}
+				return
+			}
+			var pathErr *PathError
+			if !errors.As(err, &pathErr) {
+				t.Fatalf("got %q, %v; want a %s PathError", got, err, tt.code)
+			}
+			if pathErr.Code != tt.code || pathErr.Path != tt.path {
+				t.Errorf("got code %s for path %s, want %s", pathErr.Code, pathErr.Path, tt.code)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "new.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("resolving a dangling symlink created its target: %v", err)
+	}
+}
+
+func TestResolveWritePathSymlinkedRepository(t *testing.T) {
+	repo, _ := newPathTestRepo(t)
+	link := filepath.Join(t.TempDir(), "repo-link")
+	symlink(t, repo, link)
+
+	got, err := ResolveWritePath(link, "apis/compute/v1beta1/x.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(canonical(t, repo), "apis/compute/v1beta1/x.go"); got != want {
+		t.Errorf("resolved to %s, want %s", got, want)
+	}
+	if rel := repoRelPath(link, got); rel != "apis/compute/v1beta1/x.go" {
+		t.Errorf("repoRelPath = %s", rel)
+	}
+}
+
+func TestResolveRepoPathAllowsReadsOutsideWritableSubtrees(t *testing.T) {
+	repo, _ := newPathTestRepo(t)
+	if _, err := resolveRepoPath(repo, "pkg/clients/generated/apis/x.go"); err != nil {
+		t.Errorf("reading generated clients: %v", err)
+	}
+	var pathErr *PathError
+	if _, err := resolveRepoPath(repo, "../outside"); !errors.As(err, &pathErr) || pathErr.Code != PathOutsideRepository {
+		t.Errorf("reading outside the repository: %v", err)
+	}
+}
+
+func TestCheckPathElements(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		param  string // parameter reported, "" if valid
+	}{
+		{name: "valid", params: map[string]string{"service": "compute", "version": "v1beta1", "resource": "Network"}},
+		{name: "dot dot service", params: map[string]string{"service": "..", "resource": "Network"}, param: "service"},
+		{name: "traversal in resource", params: map[string]string{"service": "compute", "resource": "../../etc"}, param: "resource"},
+		{name: "dot", params: map[string]string{"version": "."}, param: "version"},
+		{name: "empty", params: map[string]string{"service": ""}, param: "service"},
+		{name: "separator", params: map[string]string{"service": "compute/v1"}, param: "service"},
+		{name: "backslash", params: map[string]string{"resource": `..\evil`}, param: "resource"},
+		{name: "NUL", params: map[string]string{"resource": "a\x00b"}, param: "resource"},
+		{name: "first invalid parameter by name", params: map[string]string{"version": "..", "resource": ".."}, param: "resource"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPathElements(tt.params)
+			if tt.param == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var pathErr *PathError
+			if !errors.As(err, &pathErr) || pathErr.Code != PathInvalid || pathErr.Param != tt.param {
+				t.Errorf("got %v, want an invalid_path error for %s", err, tt.param)
+			}
+		})
+	}
+}
+
+func TestChangeSetWriteConfined(t *testing.T) {
+	repo, outside := newPathTestRepo(t)
+	symlink(t, outside, filepath.Join(repo, "apis", "evil"))
+
+	changes := NewChangeSet(repo, false)
+	for path, code := range map[string]string{
+		"pkg/clients/generated/apis/x.go": PathWriteNotAllowed,
+		"apis/evil/x.go":                  PathOutsideRepository,
+		"../outside/x.go":                 PathOutsideRepository,
+	} {
+		var pathErr *PathError
+		if err := changes.Write(path, []byte("x")); !errors.As(err, &pathErr) || pathErr.Code != code {
+			t.Errorf("Write(%s) = %v, want a %s PathError", path, err, code)
+		}
+	}
+	if err := changes.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("files were written outside the repository: %v", entries)
+	}
+}
+
+func TestScaffoldsRejectPathTraversal(t *testing.T) {
+	repo, outside := newPathTestRepo(t)
+	evil := "../../../" + filepath.Base(outside)
+
+	scaffolds := map[string]func(*ChangeSet) error{
+		"controller service": func(c *ChangeSet) error {
+			_, err := ScaffoldController(repo, ScaffoldControllerParams{Resource: "Network", Service: evil, Version: "v1beta1"}, c)
+			return err
+		},
+		"identity version": func(c *ChangeSet) error {
+			_, err := ScaffoldIdentity(repo, ScaffoldIdentityParams{Resource: "Network", Service: "compute", Version: ".."}, c)
+			return err
+		},
+		"mockgcp resource": func(c *ChangeSet) error {
+			_, err := ScaffoldMockGCP(repo, ScaffoldMockGCPParams{Resource: evil, Service: "compute"}, c)
+			return err
+		},
+	}
+	for name, scaffold := range scaffolds {
+		t.Run(name, func(t *testing.T) {
+			changes := NewChangeSet(repo, false)
+			var pathErr *PathError
+			if err := scaffold(changes); !errors.As(err, &pathErr) || pathErr.Code != PathInvalid {
+				t.Errorf("got %v, want an invalid_path error", err)
+			}
+			if len(changes.Files()) > 0 {
+				t.Errorf("files were staged: %v", changes.Files())
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("files were written outside the repository: %v", entries)
+	}
+}
+
+// canonical resolves the symlinks of an existing path, e.g. a temporary
+// directory under a symlinked /tmp
+func canonical(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// resourceNamePattern matches the resource names accepted from MCP clients.
+// KCC kinds and file stems are plain identifiers, so anything else (path
+// separators, globs, quotes, shell metacharacters) is rejected up front.
+var resourceNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,127}$`)
+
+// validateResourceName checks that a client-supplied resource name is safe to
+// use for repository lookups
+func validateResourceName(resource string) error {
+	if !resourceNamePattern.MatchString(resource) {
+		return fmt.Errorf(`invalid resource name: %q
+
+Resource names must start with a letter and contain only letters, digits
+and underscores (e.g. "ComputeURLMap" or "urlmap").`, resource)
+	}
+	return nil
+}
+
+// walkFiles walks root (relative to repoPath) and calls fn with the
+// slash-separated, repository-relative path of every regular file accepted by
+// match. A missing root is not an error.
+func walkFiles(repoPath, root string, match func(relPath string) bool, fn func(relPath string, d fs.DirEntry) error) error {
+	walkRoot := filepath.Join(repoPath, filepath.FromSlash(root))
+	err := filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == walkRoot && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(repoPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if match(rel) {
+			return fn(rel, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return nil
+}
+
+// findFiles returns the sorted, repository-relative paths of every regular
+// file under root accepted by match
+func findFiles(repoPath, root string, match func(relPath string) bool) ([]string, error) {
+	var results []string
+	err := walkFiles(repoPath, root, match, func(relPath string, _ fs.DirEntry) error {
+		results = append(results, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(results)
+	return results, nil
+}
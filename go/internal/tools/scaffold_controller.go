@@ -2,9 +2,9 @@ package tools
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 )
 
@@ -15,10 +15,14 @@ type ScaffoldControllerParams struct {
 	Version      string `json:"version"`
 	ProtoPackage string `json:"proto_package"`
 	ProtoMessage string `json:"proto_message"`
+	DryRun       bool   `json:"dry_run,omitempty"` // return the diff without writing
 }
 
 // ScaffoldController generates controller file
-func ScaffoldController(repoPath string, params ScaffoldControllerParams) (string, error) {
+func ScaffoldController(repoPath string, params ScaffoldControllerParams, changes *ChangeSet) (string, error) {
+	if err := checkPathElements(map[string]string{"service": params.Service, "resource": params.Resource}); err != nil {
+		return "", err
+	}
 	resourceLower := strings.ToLower(params.Resource)
 	targetPath := filepath.Join(repoPath, "pkg", "controller", "direct", params.Service, fmt.Sprintf("%s_controller.go", resourceLower))
 
@@ -26,14 +30,15 @@ func ScaffoldController(repoPath string, params ScaffoldControllerParams) (strin
 		return "", fmt.Errorf("controller file already exists: %s", targetPath)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	content, err := generateControllerTemplate(params)
+	if err != nil {
+		return "", err
 	}
-
-	content := generateControllerTemplate(params)
-	if err := os.WriteFile(targetPath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	if err := changes.Write(targetPath, []byte(content)); err != nil {
+		return "", err
+	}
+	if err := changes.Apply(); err != nil {
+		return "", err
 	}
 
 	return fmt.Sprintf("✅ Created controller file: pkg/controller/direct/%s/%s_controller.go\n\n"+
@@ -45,14 +50,38 @@ func ScaffoldController(repoPath string, params ScaffoldControllerParams) (strin
 		params.Service, resourceLower), nil
 }
 
-func generateControllerTemplate(params ScaffoldControllerParams) string {
-	year := time.Now().Year()
-	resourceTitle := params.Resource
-	gvk := fmt.Sprintf("%s%s", strings.Title(params.Service), resourceTitle)
-	model := fmt.Sprintf("%sModel", resourceTitle)
-	adapter := fmt.Sprintf("%sAdapter", resourceTitle)
+func generateControllerTemplate(params ScaffoldControllerParams) (string, error) {
+	var buf strings.Builder
+	err := controllerTemplate.Execute(&buf, controllerTemplateData{
+		Year:         time.Now().Year(),
+		Service:      params.Service,
+		Version:      params.Version,
+		GVK:          ServiceTitle(params.Service) + params.Resource,
+		Model:        params.Resource + "Model",
+		Adapter:      params.Resource + "Adapter",
+		Resource:     params.Resource,
+		ProtoMessage: params.ProtoMessage,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render controller template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// controllerTemplateData holds the values substituted into controllerTemplate
+type controllerTemplateData struct {
+	Year         int
+	Service      string // Go package name of the service, e.g. compute
+	Version      string // KRM API version, e.g. v1beta1
+	GVK          string // Kind the GVK variable is named after, e.g. ComputeNetwork
+	Model        string
+	Adapter      string
+	Resource     string
+	ProtoMessage string
+}
 
-	return fmt.Sprintf(`// Copyright %d Google LLC
+// controllerTemplate is the skeleton of a direct controller
+var controllerTemplate = template.Must(template.New("controller").Parse(`// Copyright {{.Year}} Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -66,20 +95,20 @@ func generateControllerTemplate(params ScaffoldControllerParams) string {
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package %s
+package {{.Service}}
 
 import (
 	"context"
 	"fmt"
 
-	krm "github.com/GoogleCloudPlatform/k8s-config-connector/apis/%s/%s"
+	krm "github.com/GoogleCloudPlatform/k8s-config-connector/apis/{{.Service}}/{{.Version}}"
 	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/config"
 	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/controller/direct"
 	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/controller/direct/directbase"
 	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/controller/direct/registry"
 
-	gcp "cloud.google.com/go/%s/apiv1"
-	pb "cloud.google.com/go/%s/apiv1/%spb"
+	gcp "cloud.google.com/go/{{.Service}}/apiv1"
+	pb "cloud.google.com/go/{{.Service}}/apiv1/{{.Service}}pb"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
@@ -87,26 +116,26 @@ import (
 )
 
 func init() {
-	registry.RegisterModel(krm.%sGVK, New%s)
+	registry.RegisterModel(krm.{{.GVK}}GVK, New{{.Model}})
 }
 
-func New%s(ctx context.Context, config *config.ControllerConfig) (directbase.Model, error) {
-	return &%s{config: *config}, nil
+func New{{.Model}}(ctx context.Context, config *config.ControllerConfig) (directbase.Model, error) {
+	return &{{.Model}}{config: *config}, nil
 }
 
-var _ directbase.Model = &%s{}
+var _ directbase.Model = &{{.Model}}{}
 
-type %s struct {
+type {{.Model}} struct {
 	config config.ControllerConfig
 }
 
-func (m *%s) AdapterForObject(ctx context.Context, reader client.Reader, u *unstructured.Unstructured) (directbase.Adapter, error) {
-	obj := &krm.%s{}
+func (m *{{.Model}}) AdapterForObject(ctx context.Context, reader client.Reader, u *unstructured.Unstructured) (directbase.Adapter, error) {
+	obj := &krm.{{.GVK}}{}
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &obj); err != nil {
-		return nil, fmt.Errorf("error converting to %%T: %%w", obj, err)
+		return nil, fmt.Errorf("error converting to %T: %w", obj, err)
 	}
 
-	id, err := krm.New%sIdentity(ctx, reader, obj)
+	id, err := krm.New{{.Resource}}Identity(ctx, reader, obj)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +146,7 @@ func (m *%s) AdapterForObject(ctx context.Context, reader client.Reader, u *unst
 	// 	return nil, err
 	// }
 
-	return &%s{
+	return &{{.Adapter}}{
 		// gcpClient: gcpClient,
 		id:      id,
 		desired: obj,
@@ -125,34 +154,34 @@ func (m *%s) AdapterForObject(ctx context.Context, reader client.Reader, u *unst
 	}, nil
 }
 
-func (m *%s) AdapterForURL(ctx context.Context, url string) (directbase.Adapter, error) {
+func (m *{{.Model}}) AdapterForURL(ctx context.Context, url string) (directbase.Adapter, error) {
 	// TODO: Support URLs
 	return nil, nil
 }
 
-type %s struct {
+type {{.Adapter}} struct {
 	// gcpClient *gcp.Client
-	id      *krm.%sIdentity
-	desired *krm.%s
-	actual  *pb.%s
+	id      *krm.{{.Resource}}Identity
+	desired *krm.{{.GVK}}
+	actual  *pb.{{.ProtoMessage}}
 	reader  client.Reader
 }
 
-var _ directbase.Adapter = &%s{}
+var _ directbase.Adapter = &{{.Adapter}}{}
 
 // Find retrieves the GCP resource.
-func (a *%s) Find(ctx context.Context) (bool, error) {
+func (a *{{.Adapter}}) Find(ctx context.Context) (bool, error) {
 	log := klog.FromContext(ctx)
-	log.V(2).Info("getting %s", "name", a.id)
+	log.V(2).Info("getting {{.Resource}}", "name", a.id)
 
 	// TODO: Implement Find using GCP client
-	// req := &pb.Get%sRequest{Name: a.id.String()}
-	// obj, err := a.gcpClient.Get%s(ctx, req)
+	// req := &pb.Get{{.ProtoMessage}}Request{Name: a.id.String()}
+	// obj, err := a.gcpClient.Get{{.ProtoMessage}}(ctx, req)
 	// if err != nil {
 	// 	if direct.IsNotFound(err) {
 	// 		return false, nil
 	// 	}
-	// 	return false, fmt.Errorf("getting %s %%q: %%w", a.id, err)
+	// 	return false, fmt.Errorf("getting {{.Resource}} %q: %w", a.id, err)
 	// }
 	// a.actual = obj
 	// return true, nil
@@ -160,15 +189,15 @@ func (a *%s) Find(ctx context.Context) (bool, error) {
 	return false, nil // Temporary
 }
 
-func (a *%s) resolveReferences(ctx context.Context) error {
+func (a *{{.Adapter}}) resolveReferences(ctx context.Context) error {
 	// TODO: Implement reference resolution if needed
 	return nil
 }
 
 // Create creates the resource in GCP.
-func (a *%s) Create(ctx context.Context, createOp *directbase.CreateOperation) error {
+func (a *{{.Adapter}}) Create(ctx context.Context, createOp *directbase.CreateOperation) error {
 	log := klog.FromContext(ctx)
-	log.V(2).Info("creating %s", "name", a.id)
+	log.V(2).Info("creating {{.Resource}}", "name", a.id)
 
 	if err := a.resolveReferences(ctx); err != nil {
 		return err
@@ -176,29 +205,29 @@ func (a *%s) Create(ctx context.Context, createOp *directbase.CreateOperation) e
 
 	mapCtx := &direct.MapContext{}
 	desired := a.desired.DeepCopy()
-	resource := %sSpec_ToProto(mapCtx, &desired.Spec)
+	resource := {{.Resource}}Spec_ToProto(mapCtx, &desired.Spec)
 	if mapCtx.Err() != nil {
 		return mapCtx.Err()
 	}
 
 	// TODO: Implement Create using GCP client
-	// req := &pb.Create%sRequest{
+	// req := &pb.Create{{.ProtoMessage}}Request{
 	// 	Parent:   a.id.Parent().String(),
-	// 	%sId: a.id.ID(),
-	// 	%s:   resource,
+	// 	{{.ProtoMessage}}Id: a.id.ID(),
+	// 	{{.ProtoMessage}}:   resource,
 	// }
-	// op, err := a.gcpClient.Create%s(ctx, req)
+	// op, err := a.gcpClient.Create{{.ProtoMessage}}(ctx, req)
 	// if err != nil {
-	// 	return fmt.Errorf("creating %s %%s: %%w", a.id, err)
+	// 	return fmt.Errorf("creating {{.Resource}} %s: %w", a.id, err)
 	// }
 	// created, err := op.Wait(ctx)
 	// if err != nil {
-	// 	return fmt.Errorf("%s %%s waiting creation: %%w", a.id, err)
+	// 	return fmt.Errorf("{{.Resource}} %s waiting creation: %w", a.id, err)
 	// }
-	// log.V(2).Info("successfully created %s", "name", a.id)
+	// log.V(2).Info("successfully created {{.Resource}}", "name", a.id)
 
-	// status := &krm.%sStatus{}
-	// status.ObservedState = %sObservedState_FromProto(mapCtx, created)
+	// status := &krm.{{.Resource}}Status{}
+	// status.ObservedState = {{.Resource}}ObservedState_FromProto(mapCtx, created)
 	// if mapCtx.Err() != nil {
 	// 	return mapCtx.Err()
 	// }
@@ -206,13 +235,13 @@ func (a *%s) Create(ctx context.Context, createOp *directbase.CreateOperation) e
 	// return createOp.UpdateStatus(ctx, status, nil)
 
 	_ = resource // Temporary
-	return fmt.Errorf("%s Create not yet implemented")
+	return fmt.Errorf("{{.Resource}} Create not yet implemented")
 }
 
 // Update updates the resource in GCP.
-func (a *%s) Update(ctx context.Context, updateOp *directbase.UpdateOperation) error {
+func (a *{{.Adapter}}) Update(ctx context.Context, updateOp *directbase.UpdateOperation) error {
 	log := klog.FromContext(ctx)
-	log.V(2).Info("updating %s", "name", a.id)
+	log.V(2).Info("updating {{.Resource}}", "name", a.id)
 
 	if err := a.resolveReferences(ctx); err != nil {
 		return err
@@ -220,7 +249,7 @@ func (a *%s) Update(ctx context.Context, updateOp *directbase.UpdateOperation) e
 
 	mapCtx := &direct.MapContext{}
 	desired := a.desired.DeepCopy()
-	resource := %sSpec_ToProto(mapCtx, &desired.Spec)
+	resource := {{.Resource}}Spec_ToProto(mapCtx, &desired.Spec)
 	if mapCtx.Err() != nil {
 		return mapCtx.Err()
 	}
@@ -228,22 +257,22 @@ func (a *%s) Update(ctx context.Context, updateOp *directbase.UpdateOperation) e
 	// TODO: Implement Update using GCP client
 	// TODO: Build field mask for changed fields
 	// resource.Name = a.id.String()
-	// req := &pb.Update%sRequest{
-	// 	%s: resource,
+	// req := &pb.Update{{.ProtoMessage}}Request{
+	// 	{{.ProtoMessage}}: resource,
 	// 	UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
 	// }
-	// op, err := a.gcpClient.Update%s(ctx, req)
+	// op, err := a.gcpClient.Update{{.ProtoMessage}}(ctx, req)
 	// if err != nil {
-	// 	return fmt.Errorf("updating %s %%s: %%w", a.id, err)
+	// 	return fmt.Errorf("updating {{.Resource}} %s: %w", a.id, err)
 	// }
 	// updated, err := op.Wait(ctx)
 	// if err != nil {
-	// 	return fmt.Errorf("%s %%s waiting update: %%w", a.id, err)
+	// 	return fmt.Errorf("{{.Resource}} %s waiting update: %w", a.id, err)
 	// }
-	// log.V(2).Info("successfully updated %s", "name", a.id)
+	// log.V(2).Info("successfully updated {{.Resource}}", "name", a.id)
 
-	// status := &krm.%sStatus{}
-	// status.ObservedState = %sObservedState_FromProto(mapCtx, updated)
+	// status := &krm.{{.Resource}}Status{}
+	// status.ObservedState = {{.Resource}}ObservedState_FromProto(mapCtx, updated)
 	// if mapCtx.Err() != nil {
 	// 	return mapCtx.Err()
 	// }
@@ -251,19 +280,19 @@ func (a *%s) Update(ctx context.Context, updateOp *directbase.UpdateOperation) e
 	// return updateOp.UpdateStatus(ctx, status, nil)
 
 	_ = resource // Temporary
-	return fmt.Errorf("%s Update not yet implemented")
+	return fmt.Errorf("{{.Resource}} Update not yet implemented")
 }
 
 // Export maps the GCP object to a Config Connector resource spec.
-func (a *%s) Export(ctx context.Context) (*unstructured.Unstructured, error) {
+func (a *{{.Adapter}}) Export(ctx context.Context) (*unstructured.Unstructured, error) {
 	if a.actual == nil {
 		return nil, fmt.Errorf("Find() not called")
 	}
 
 	u := &unstructured.Unstructured{}
-	obj := &krm.%s{}
+	obj := &krm.{{.GVK}}{}
 	mapCtx := &direct.MapContext{}
-	obj.Spec = direct.ValueOf(%sSpec_FromProto(mapCtx, a.actual))
+	obj.Spec = direct.ValueOf({{.Resource}}Spec_FromProto(mapCtx, a.actual))
 	if mapCtx.Err() != nil {
 		return nil, mapCtx.Err()
 	}
@@ -278,44 +307,33 @@ func (a *%s) Export(ctx context.Context) (*unstructured.Unstructured, error) {
 	}
 
 	u.SetName(a.id.ID())
-	u.SetGroupVersionKind(krm.%sGVK)
+	u.SetGroupVersionKind(krm.{{.GVK}}GVK)
 	u.Object = uObj
 
 	return u, nil
 }
 
 // Delete deletes the resource from GCP.
-func (a *%s) Delete(ctx context.Context, deleteOp *directbase.DeleteOperation) (bool, error) {
+func (a *{{.Adapter}}) Delete(ctx context.Context, deleteOp *directbase.DeleteOperation) (bool, error) {
 	log := klog.FromContext(ctx)
-	log.V(2).Info("deleting %s", "name", a.id)
+	log.V(2).Info("deleting {{.Resource}}", "name", a.id)
 
 	// TODO: Implement Delete using GCP client
-	// req := &pb.Delete%sRequest{Name: a.id.String()}
-	// op, err := a.gcpClient.Delete%s(ctx, req)
+	// req := &pb.Delete{{.ProtoMessage}}Request{Name: a.id.String()}
+	// op, err := a.gcpClient.Delete{{.ProtoMessage}}(ctx, req)
 	// if err != nil {
 	// 	if direct.IsNotFound(err) {
 	// 		return true, nil
 	// 	}
-	// 	return false, fmt.Errorf("deleting %s %%s: %%w", a.id, err)
+	// 	return false, fmt.Errorf("deleting {{.Resource}} %s: %w", a.id, err)
 	// }
-	// log.V(2).Info("successfully deleted %s", "name", a.id)
+	// log.V(2).Info("successfully deleted {{.Resource}}", "name", a.id)
 	// err = op.Wait(ctx)
 	// if err != nil {
-	// 	return false, fmt.Errorf("waiting delete %s %%s: %%w", a.id, err)
+	// 	return false, fmt.Errorf("waiting delete {{.Resource}} %s: %w", a.id, err)
 	// }
 	// return true, nil
 
 	return true, nil // Temporary
 }
-`, year, params.Service, params.Service, params.Version, params.Service, params.Service, params.Service,
-		gvk, model, model, model, model, model, model, gvk, resourceTitle, adapter,
-		model, adapter, resourceTitle, gvk, params.ProtoMessage, adapter,
-		adapter, resourceTitle, params.ProtoMessage, params.ProtoMessage, resourceTitle,
-		adapter, adapter, resourceTitle, gvk, params.ProtoMessage,
-		params.ProtoMessage, params.ProtoMessage, resourceTitle, resourceTitle, resourceTitle,
-		gvk, gvk, resourceTitle, adapter, resourceTitle, gvk,
-		params.ProtoMessage, params.ProtoMessage, resourceTitle, resourceTitle,
-		resourceTitle, gvk, gvk, resourceTitle, adapter, gvk, gvk,
-		gvk, adapter, resourceTitle, params.ProtoMessage, params.ProtoMessage,
-		resourceTitle, resourceTitle, resourceTitle)
-}
+`))
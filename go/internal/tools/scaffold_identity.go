@@ -2,7 +2,6 @@ package tools
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -14,10 +13,14 @@ type ScaffoldIdentityParams struct {
 	Service            string `json:"service"`
 	Version            string `json:"version"`
 	ResourceNameFormat string `json:"resource_name_format"` // e.g., "projects/{project}/locations/{location}/urlMaps/{urlMap}"
+	DryRun             bool   `json:"dry_run,omitempty"`    // return the diff without writing
 }
 
 // ScaffoldIdentity generates identity handler file
-func ScaffoldIdentity(repoPath string, params ScaffoldIdentityParams) (string, error) {
+func ScaffoldIdentity(repoPath string, params ScaffoldIdentityParams, changes *ChangeSet) (string, error) {
+	if err := checkPathElements(map[string]string{"service": params.Service, "version": params.Version, "resource": params.Resource}); err != nil {
+		return "", err
+	}
 	resourceLower := strings.ToLower(params.Resource)
 	targetPath := filepath.Join(repoPath, "apis", params.Service, params.Version, fmt.Sprintf("%s_identity.go", resourceLower))
 
@@ -25,14 +28,12 @@ func ScaffoldIdentity(repoPath string, params ScaffoldIdentityParams) (string, e
 		return "", fmt.Errorf("identity file already exists: %s", targetPath)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
 	content := generateIdentityTemplate(params)
-	if err := os.WriteFile(targetPath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	if err := changes.Write(targetPath, []byte(content)); err != nil {
+		return "", err
+	}
+	if err := changes.Apply(); err != nil {
+		return "", err
 	}
 
 	return fmt.Sprintf("✅ Created identity file: apis/%s/%s/%s_identity.go\n\n"+
@@ -48,7 +49,7 @@ func generateIdentityTemplate(params ScaffoldIdentityParams) string {
 	resourceTitle := params.Resource
 	resourceLower := strings.ToLower(params.Resource)
 	identityType := fmt.Sprintf("%sIdentity", resourceTitle)
-	gvk := fmt.Sprintf("%s%s", strings.Title(params.Service), resourceTitle)
+	gvk := fmt.Sprintf("%s%s", ServiceTitle(params.Service), resourceTitle)
 
 	// Parse the resource name format to extract components
 	parts := strings.Split(params.ResourceNameFormat, "/")
@@ -2,9 +2,9 @@ package tools
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 )
 
@@ -15,10 +15,14 @@ type ScaffoldMockGCPParams struct {
 	ProtoPackage       string `json:"proto_package"`
 	ProtoMessage       string `json:"proto_message"`
 	ResourceNameFormat string `json:"resource_name_format"`
+	DryRun             bool   `json:"dry_run,omitempty"` // return the diff without writing
 }
 
 // ScaffoldMockGCP generates MockGCP implementation file
-func ScaffoldMockGCP(repoPath string, params ScaffoldMockGCPParams) (string, error) {
+func ScaffoldMockGCP(repoPath string, params ScaffoldMockGCPParams, changes *ChangeSet) (string, error) {
+	if err := checkPathElements(map[string]string{"service": params.Service, "resource": params.Resource}); err != nil {
+		return "", err
+	}
 	resourceLower := strings.ToLower(params.Resource)
 	targetPath := filepath.Join(repoPath, "mockgcp", fmt.Sprintf("mock%s", params.Service), fmt.Sprintf("%s.go", resourceLower))
 
@@ -26,14 +30,15 @@ func ScaffoldMockGCP(repoPath string, params ScaffoldMockGCPParams) (string, err
 		return "", fmt.Errorf("MockGCP file already exists: %s", targetPath)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	content, err := generateMockGCPTemplate(params)
+	if err != nil {
+		return "", err
 	}
-
-	content := generateMockGCPTemplate(params)
-	if err := os.WriteFile(targetPath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	if err := changes.Write(targetPath, []byte(content)); err != nil {
+		return "", err
+	}
+	if err := changes.Apply(); err != nil {
+		return "", err
 	}
 
 	return fmt.Sprintf("✅ Created MockGCP file: mockgcp/mock%s/%s.go\n\n"+
@@ -44,11 +49,8 @@ func ScaffoldMockGCP(repoPath string, params ScaffoldMockGCPParams) (string, err
 		params.Service, resourceLower, params.Service), nil
 }
 
-func generateMockGCPTemplate(params ScaffoldMockGCPParams) string {
-	year := time.Now().Year()
-	resourceTitle := params.Resource
+func generateMockGCPTemplate(params ScaffoldMockGCPParams) (string, error) {
 	resourceLower := strings.ToLower(params.Resource)
-	serverName := fmt.Sprintf("%sServer", strings.Title(params.Service))
 
 	// Build format string for resource name
 	formatStr := params.ResourceNameFormat
@@ -56,7 +58,37 @@ func generateMockGCPTemplate(params ScaffoldMockGCPParams) string {
 	formatStr = strings.ReplaceAll(formatStr, "{location}", "%s")
 	formatStr = strings.ReplaceAll(formatStr, fmt.Sprintf("{%s}", resourceLower), "%s")
 
-	return fmt.Sprintf(`// Copyright %d Google LLC
+	var buf strings.Builder
+	err := mockGCPTemplate.Execute(&buf, mockGCPTemplateData{
+		Year:               time.Now().Year(),
+		Service:            params.Service,
+		ProtoPackage:       params.ProtoPackage,
+		Server:             ServiceTitle(params.Service) + "Server",
+		Resource:           params.Resource,
+		ResourceLower:      resourceLower,
+		ResourceNameFormat: params.ResourceNameFormat,
+		NameFormatString:   formatStr,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render mockgcp template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// mockGCPTemplateData holds the values substituted into mockGCPTemplate
+type mockGCPTemplateData struct {
+	Year               int
+	Service            string // mockgcp package suffix, e.g. compute for mockcompute
+	ProtoPackage       string
+	Server             string // type of the service's mock server
+	Resource           string
+	ResourceLower      string
+	ResourceNameFormat string // e.g. projects/{project}/locations/{location}/networks/{network}
+	NameFormatString   string // ResourceNameFormat as a fmt format string
+}
+
+// mockGCPTemplate is the skeleton of a MockGCP resource implementation
+var mockGCPTemplate = template.Must(template.New("mockgcp").Parse(`// Copyright {{.Year}} Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -70,7 +102,7 @@ func generateMockGCPTemplate(params ScaffoldMockGCPParams) string {
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package mock%s
+package mock{{.Service}}
 
 import (
 	"context"
@@ -85,23 +117,23 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/GoogleCloudPlatform/k8s-config-connector/mockgcp/common/projects"
-	pb "%spb"
+	pb "{{.ProtoPackage}}pb"
 	"github.com/GoogleCloudPlatform/k8s-config-connector/mockgcp/pkg/storage"
 	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
 )
 
-func (s *%s) Get%s(ctx context.Context, req *pb.Get%sRequest) (*pb.%s, error) {
-	name, err := s.parse%sName(req.Name)
+func (s *{{.Server}}) Get{{.Resource}}(ctx context.Context, req *pb.Get{{.Resource}}Request) (*pb.{{.Resource}}, error) {
+	name, err := s.parse{{.Resource}}Name(req.Name)
 	if err != nil {
 		return nil, err
 	}
 
 	fqn := name.String()
 
-	obj := &pb.%s{}
+	obj := &pb.{{.Resource}}{}
 	if err := s.storage.Get(ctx, fqn, obj); err != nil {
 		if status.Code(err) == codes.NotFound {
-			return nil, status.Errorf(codes.NotFound, "Resource '%%s' was not found", fqn)
+			return nil, status.Errorf(codes.NotFound, "Resource '%s' was not found", fqn)
 		}
 		return nil, err
 	}
@@ -109,15 +141,15 @@ func (s *%s) Get%s(ctx context.Context, req *pb.Get%sRequest) (*pb.%s, error) {
 	return obj, nil
 }
 
-func (s *%s) List%ss(ctx context.Context, req *pb.List%ssRequest) (*pb.List%ssResponse, error) {
-	response := &pb.List%ssResponse{}
+func (s *{{.Server}}) List{{.Resource}}s(ctx context.Context, req *pb.List{{.Resource}}sRequest) (*pb.List{{.Resource}}sResponse, error) {
+	response := &pb.List{{.Resource}}sResponse{}
 
-	findKind := (&pb.%s{}).ProtoReflect().Descriptor()
+	findKind := (&pb.{{.Resource}}{}).ProtoReflect().Descriptor()
 	if err := s.storage.List(ctx, findKind, storage.ListOptions{
-		Prefix: req.Parent + "/%ss/",
+		Prefix: req.Parent + "/{{.ResourceLower}}s/",
 	}, func(obj proto.Message) error {
-		item := obj.(*pb.%s)
-		response.%ss = append(response.%ss, item)
+		item := obj.(*pb.{{.Resource}})
+		response.{{.Resource}}s = append(response.{{.Resource}}s, item)
 		return nil
 	}); err != nil {
 		return nil, err
@@ -126,9 +158,9 @@ func (s *%s) List%ss(ctx context.Context, req *pb.List%ssRequest) (*pb.List%ssRe
 	return response, nil
 }
 
-func (s *%s) Create%s(ctx context.Context, req *pb.Create%sRequest) (*longrunningpb.Operation, error) {
-	reqName := req.Parent + "/%ss/" + req.%sId
-	name, err := s.parse%sName(reqName)
+func (s *{{.Server}}) Create{{.Resource}}(ctx context.Context, req *pb.Create{{.Resource}}Request) (*longrunningpb.Operation, error) {
+	reqName := req.Parent + "/{{.ResourceLower}}s/" + req.{{.Resource}}Id
+	name, err := s.parse{{.Resource}}Name(reqName)
 	if err != nil {
 		return nil, err
 	}
@@ -136,14 +168,14 @@ func (s *%s) Create%s(ctx context.Context, req *pb.Create%sRequest) (*longrunnin
 	fqn := name.String()
 	now := time.Now()
 
-	obj := proto.Clone(req.%s).(*pb.%s)
+	obj := proto.Clone(req.{{.Resource}}).(*pb.{{.Resource}})
 	obj.Name = fqn
 
 	if err := s.storage.Create(ctx, fqn, obj); err != nil {
 		return nil, err
 	}
 
-	lroPrefix := fmt.Sprintf("projects/%%s/locations/%%s", name.Project.ID, name.Location)
+	lroPrefix := fmt.Sprintf("projects/%s/locations/%s", name.Project.ID, name.Location)
 	lroMetadata := &pb.OperationMetadata{
 		CreateTime: timestamppb.New(now),
 		EndTime:    timestamppb.New(now),
@@ -153,34 +185,34 @@ func (s *%s) Create%s(ctx context.Context, req *pb.Create%sRequest) (*longrunnin
 	}
 
 	return s.operations.StartLRO(ctx, lroPrefix, lroMetadata, func() (proto.Message, error) {
-		result := proto.Clone(obj).(*pb.%s)
+		result := proto.Clone(obj).(*pb.{{.Resource}})
 		return result, nil
 	})
 }
 
-func (s *%s) Update%s(ctx context.Context, req *pb.Update%sRequest) (*longrunningpb.Operation, error) {
-	name, err := s.parse%sName(req.%s.Name)
+func (s *{{.Server}}) Update{{.Resource}}(ctx context.Context, req *pb.Update{{.Resource}}Request) (*longrunningpb.Operation, error) {
+	name, err := s.parse{{.Resource}}Name(req.{{.Resource}}.Name)
 	if err != nil {
 		return nil, err
 	}
 
 	fqn := name.String()
 
-	existing := &pb.%s{}
+	existing := &pb.{{.Resource}}{}
 	if err := s.storage.Get(ctx, fqn, existing); err != nil {
 		return nil, err
 	}
 
 	now := time.Now()
 
-	updated := proto.Clone(req.%s).(*pb.%s)
+	updated := proto.Clone(req.{{.Resource}}).(*pb.{{.Resource}})
 	updated.Name = fqn
 
 	if err := s.storage.Update(ctx, fqn, updated); err != nil {
 		return nil, err
 	}
 
-	lroPrefix := fmt.Sprintf("projects/%%s/locations/%%s", name.Project.ID, name.Location)
+	lroPrefix := fmt.Sprintf("projects/%s/locations/%s", name.Project.ID, name.Location)
 	lroMetadata := &pb.OperationMetadata{
 		CreateTime: timestamppb.New(now),
 		EndTime:    timestamppb.New(now),
@@ -190,20 +222,20 @@ func (s *%s) Update%s(ctx context.Context, req *pb.Update%sRequest) (*longrunnin
 	}
 
 	return s.operations.StartLRO(ctx, lroPrefix, lroMetadata, func() (proto.Message, error) {
-		result := proto.Clone(updated).(*pb.%s)
+		result := proto.Clone(updated).(*pb.{{.Resource}})
 		return result, nil
 	})
 }
 
-func (s *%s) Delete%s(ctx context.Context, req *pb.Delete%sRequest) (*longrunningpb.Operation, error) {
-	name, err := s.parse%sName(req.Name)
+func (s *{{.Server}}) Delete{{.Resource}}(ctx context.Context, req *pb.Delete{{.Resource}}Request) (*longrunningpb.Operation, error) {
+	name, err := s.parse{{.Resource}}Name(req.Name)
 	if err != nil {
 		return nil, err
 	}
 
 	fqn := name.String()
 
-	deleted := &pb.%s{}
+	deleted := &pb.{{.Resource}}{}
 	if err := s.storage.Delete(ctx, fqn, deleted); err != nil {
 		return nil, err
 	}
@@ -217,48 +249,40 @@ func (s *%s) Delete%s(ctx context.Context, req *pb.Delete%sRequest) (*longrunnin
 		ApiVersion: "v1",
 	}
 
-	lroPrefix := fmt.Sprintf("projects/%%s/locations/%%s", name.Project.ID, name.Location)
+	lroPrefix := fmt.Sprintf("projects/%s/locations/%s", name.Project.ID, name.Location)
 	return s.operations.DoneLRO(ctx, lroPrefix, lroMetadata, &emptypb.Empty{})
 }
 
-type %sName struct {
+type {{.ResourceLower}}Name struct {
 	Project  *projects.ProjectData
 	Location string
-	%sName string
+	{{.Resource}}Name string
 }
 
-func (n *%sName) String() string {
-	// Format: %s
-	return fmt.Sprintf("%s", n.Project.ID, n.Location, n.%sName)
+func (n *{{.ResourceLower}}Name) String() string {
+	// Format: {{.ResourceNameFormat}}
+	return fmt.Sprintf("{{.NameFormatString}}", n.Project.ID, n.Location, n.{{.Resource}}Name)
 }
 
-// parse%sName parses a string into a %sName.
-// Expected form: %s
-func (s *%s) parse%sName(name string) (*%sName, error) {
+// parse{{.Resource}}Name parses a string into a {{.ResourceLower}}Name.
+// Expected form: {{.ResourceNameFormat}}
+func (s *{{.Server}}) parse{{.Resource}}Name(name string) (*{{.ResourceLower}}Name, error) {
 	tokens := strings.Split(name, "/")
 
 	// TODO: Adjust parsing based on actual resource name format
-	if len(tokens) == 6 && tokens[0] == "projects" && tokens[2] == "locations" && tokens[4] == "%ss" {
+	if len(tokens) == 6 && tokens[0] == "projects" && tokens[2] == "locations" && tokens[4] == "{{.ResourceLower}}s" {
 		project, err := s.Projects.GetProjectByID(tokens[1])
 		if err != nil {
 			return nil, err
 		}
 
-		return &%sName{
+		return &{{.ResourceLower}}Name{
 			Project:  project,
 			Location: tokens[3],
-			%sName: tokens[5],
+			{{.Resource}}Name: tokens[5],
 		}, nil
 	}
 
-	return nil, status.Errorf(codes.InvalidArgument, "name %%q is not valid", name)
-}
-`, year, params.Service, params.ProtoPackage, serverName, resourceTitle, resourceTitle, resourceTitle, resourceTitle, resourceTitle,
-		serverName, resourceTitle, resourceTitle, resourceTitle, resourceTitle, resourceTitle, resourceLower, resourceTitle,
-		resourceTitle, resourceTitle, serverName, resourceTitle, resourceTitle, resourceLower, resourceTitle, resourceTitle,
-		resourceTitle, resourceTitle, resourceTitle, serverName, resourceTitle, resourceTitle, resourceTitle, resourceTitle,
-		resourceTitle, resourceTitle, resourceTitle, resourceTitle, serverName, resourceTitle, resourceTitle,
-		resourceTitle, resourceTitle, resourceLower, resourceTitle, resourceLower, params.ResourceNameFormat,
-		formatStr, resourceTitle, resourceTitle, resourceLower, params.ResourceNameFormat, serverName,
-		resourceTitle, resourceLower, resourceLower, resourceLower, resourceTitle, resourceTitle)
+	return nil, status.Errorf(codes.InvalidArgument, "name %q is not valid", name)
 }
+`))
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldTemplates(t *testing.T) {
+	controller, err := generateControllerTemplate(ScaffoldControllerParams{
+		Resource:     "Widget",
+		Service:      "widgets",
+		Version:      "v1alpha1",
+		ProtoMessage: "WidgetMessage",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock, err := generateMockGCPTemplate(ScaffoldMockGCPParams{
+		Resource:           "Widget",
+		Service:            "widgets",
+		ProtoPackage:       "cloud.google.com/go/widgets/apiv1/widgets",
+		ProtoMessage:       "WidgetMessage",
+		ResourceNameFormat: "projects/{project}/locations/{location}/widgets/{widget}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "controller",
+			src:  controller,
+			want: []string{
+				"package widgets\n",
+				`krm "github.com/GoogleCloudPlatform/k8s-config-connector/apis/widgets/v1alpha1"`,
+				"registry.RegisterModel(krm.WidgetsWidgetGVK, NewWidgetModel)",
+				"func (a *WidgetAdapter) Find(ctx context.Context) (bool, error) {",
+				"actual  *pb.WidgetMessage",
+				"id      *krm.WidgetIdentity",
+				`return fmt.Errorf("Widget Create not yet implemented")`,
+				`return nil, fmt.Errorf("error converting to %T: %w", obj, err)`,
+			},
+		},
+		{
+			name: "mockgcp",
+			src:  mock,
+			want: []string{
+				"package mockwidgets\n",
+				`pb "cloud.google.com/go/widgets/apiv1/widgetspb"`,
+				"func (s *WidgetsServer) GetWidget(ctx context.Context, req *pb.GetWidgetRequest) (*pb.Widget, error) {",
+				`Prefix: req.Parent + "/widgets/",`,
+				`return fmt.Sprintf("projects/%s/locations/%s/widgets/%s", n.Project.ID, n.Location, n.WidgetName)`,
+				"// Expected form: projects/{project}/locations/{location}/widgets/{widget}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parser.ParseFile(token.NewFileSet(), tt.name+".go", tt.src, 0); err != nil {
+				t.Errorf("generated file does not parse: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(tt.src, want) {
+					t.Errorf("generated file does not contain %q", want)
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package tools
 
 import (
 	"fmt"
-	"os"
+	"go/format"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
 )
 
 // ScaffoldTypesParams contains parameters for scaffolding types
@@ -16,25 +18,68 @@ type ScaffoldTypesParams struct {
 	ProtoPackage string `json:"proto_package"`
 	ProtoMessage string `json:"proto_message"`
 	Description  string `json:"description,omitempty"`
+	FromProto    bool   `json:"from_proto,omitempty"` // populate Spec and ObservedState from the proto message
+	DryRun       bool   `json:"dry_run,omitempty"`    // return the diff without writing
+}
+
+// identityProtoFields are proto fields already covered by the scaffolded identity
+// fields (ProjectRef, Location, ResourceID)
+var identityProtoFields = map[string]bool{
+	"name": true, "project": true, "location": true, "region": true, "zone": true, "parent": true,
+}
+
+// protoTypes holds the parts of a types file generated from a proto message
+type protoTypes struct {
+	spec     string
+	observed string
+	nested   []string
+	imports  []string
+	// counts for the result summary
+	specFields     int
+	observedFields int
 }
 
 // ScaffoldTypes generates API types file
-func ScaffoldTypes(repoPath string, params ScaffoldTypesParams) (string, error) {
+func ScaffoldTypes(cat *Catalog, params ScaffoldTypesParams, changes *ChangeSet) (string, error) {
+	if err := checkPathElements(map[string]string{"service": params.Service, "version": params.Version, "resource": params.Resource}); err != nil {
+		return "", err
+	}
 	resourceLower := strings.ToLower(params.Resource)
-	targetPath := filepath.Join(repoPath, "apis", params.Service, params.Version, fmt.Sprintf("%s_types.go", resourceLower))
+	targetPath := filepath.Join(cat.RepoPath(), "apis", params.Service, params.Version, fmt.Sprintf("%s_types.go", resourceLower))
 
 	if fileExists(targetPath) {
 		return "", fmt.Errorf("types file already exists: %s\nUse kcc_add_field to add fields to existing types", targetPath)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	var generated *protoTypes
+	if params.FromProto {
+		var err error
+		if generated, err = generateProtoTypes(cat, params); err != nil {
+			return "", err
+		}
+	}
+
+	content, err := format.Source([]byte(generateTypesTemplate(params, generated)))
+	if err != nil {
+		return "", fmt.Errorf("generated types file is not valid Go: %w", err)
 	}
 
-	content := generateTypesTemplate(params)
-	if err := os.WriteFile(targetPath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
+	if err := changes.Write(targetPath, content); err != nil {
+		return "", err
+	}
+	if err := changes.Apply(); err != nil {
+		return "", err
+	}
+
+	if generated != nil {
+		return fmt.Sprintf("✅ Created types file: apis/%s/%s/%s_types.go\n"+
+			"Generated from %s.%s: %d spec fields, %d observed state fields, %d nested types\n\n"+
+			"Next steps:\n"+
+			"1. Review the generated fields; drop any that KCC should not expose\n"+
+			"2. Run: ./dev/tasks/generate-mapper %s\n"+
+			"3. Use: kcc_scaffold_identity to create identity handler",
+			params.Service, params.Version, resourceLower, params.ProtoPackage, params.ProtoMessage,
+			generated.specFields, generated.observedFields, len(generated.nested), params.Resource), nil
 	}
 
 	return fmt.Sprintf("✅ Created types file: apis/%s/%s/%s_types.go\n\n"+
@@ -46,18 +91,81 @@ func ScaffoldTypes(repoPath string, params ScaffoldTypesParams) (string, error)
 		params.Service, params.Version, resourceLower, params.Resource), nil
 }
 
-func generateTypesTemplate(params ScaffoldTypesParams) string {
+// generateProtoTypes renders the Spec and ObservedState fields of a resource
+// from its proto message. Output-only fields go to the ObservedState, the rest
+// to the Spec, skipping the identity fields the template already declares.
+func generateProtoTypes(cat *Catalog, params ScaffoldTypesParams) (*protoTypes, error) {
+	index := cat.ProtoIndex(params.Service)
+	fullName := params.ProtoPackage + "." + params.ProtoMessage
+	msg := index.Message(fullName)
+	if msg == nil {
+		return nil, fmt.Errorf(`proto message %s not found in the vendored %s protos
+
+Check the names with kcc_describe_proto, or scaffold without from_proto and
+add fields with kcc_add_field`, fullName, params.Service)
+	}
+
+	var specFields, observedFields []*protoparser.Field
+	for _, f := range msg.Fields {
+		switch {
+		case identityProtoFields[f.Name]:
+		case isOutputOnly(f):
+			observedFields = append(observedFields, f)
+		default:
+			specFields = append(specFields, f)
+		}
+	}
+
+	gen := newTypeGenerator(index)
+	gen.skip[msg.FullName] = true
+	result := &protoTypes{
+		spec:           gen.structBody(msg, specFields, false),
+		observed:       gen.structBody(msg, observedFields, true),
+		specFields:     len(specFields),
+		observedFields: len(observedFields),
+	}
+	result.nested = gen.structs
+	result.imports = gen.importLines()
+	return result, nil
+}
+
+func generateTypesTemplate(params ScaffoldTypesParams, generated *protoTypes) string {
 	year := time.Now().Year()
 	resourceTitle := params.Resource
 	resourceSpec := fmt.Sprintf("%sSpec", resourceTitle)
 	resourceStatus := fmt.Sprintf("%sStatus", resourceTitle)
 	resourceObservedState := fmt.Sprintf("%sObservedState", resourceTitle)
-	gvk := fmt.Sprintf("%s%s", strings.Title(params.Service), resourceTitle)
+	gvk := fmt.Sprintf("%s%s", ServiceTitle(params.Service), resourceTitle)
 	description := params.Description
 	if description == "" {
 		description = fmt.Sprintf("%s resource", resourceTitle)
 	}
 
+	specFields := fmt.Sprintf(`// TODO: Add fields here with proper +kcc:proto= annotations
+	// Example:
+	// // Description of the resource
+	// // +kcc:proto=%s.%s.description
+	// Description *string `+"`json:\"description,omitempty\"`"+`
+
+`, params.ProtoPackage, params.ProtoMessage)
+	observedFields := `// TODO: Add observed state fields here
+	// These are typically output-only fields from the GCP API`
+	var extraImports, nestedTypes string
+	if generated != nil {
+		specFields = ""
+		observedFields = generated.observed
+		for _, imp := range generated.imports {
+			extraImports += "\t" + imp + "\n"
+		}
+		for _, nested := range generated.nested {
+			nestedTypes += "\n" + nested + "\n"
+		}
+	}
+	specExtra := ""
+	if generated != nil && generated.spec != "" {
+		specExtra = "\n" + generated.spec + "\n"
+	}
+
 	return fmt.Sprintf(`// Copyright %d Google LLC
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
@@ -75,7 +183,7 @@ func generateTypesTemplate(params ScaffoldTypesParams) string {
 package %s
 
 import (
-	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/apis/k8s/v1alpha1"
+%s	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/apis/k8s/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -84,13 +192,7 @@ var %sGVK = GroupVersion.WithKind("%s")
 // %s defines the desired state of %s
 // +kcc:proto=%s.%s
 type %s struct {
-	// TODO: Add fields here with proper +kcc:proto= annotations
-	// Example:
-	// // Description of the resource
-	// // +kcc:proto=%s.%s.description
-	// Description *string `+"`json:\"description,omitempty\"`"+`
-
-	// REQUIRED: Immutable. The Project that this resource belongs to.
+	%s// REQUIRED: Immutable. The Project that this resource belongs to.
 	ProjectRef *v1alpha1.ProjectRef `+"`json:\"projectRef\"`"+`
 
 	// REQUIRED: Immutable. The location for the resource
@@ -99,7 +201,7 @@ type %s struct {
 	// REQUIRED: The %s name. If not given, the metadata.name will be used.
 	// + optional
 	ResourceID *string `+"`json:\"resourceID,omitempty\"`"+`
-}
+%s}
 
 // %s defines the config connector machine state of %s
 type %s struct {
@@ -120,10 +222,9 @@ type %s struct {
 // %s is the state of the %s resource as most recently observed in GCP.
 // +kcc:proto=%s.%s
 type %s struct {
-	// TODO: Add observed state fields here
-	// These are typically output-only fields from the GCP API
+	%s
 }
-
+%s
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:resource:categories=gcp
@@ -156,12 +257,13 @@ type %sList struct {
 func init() {
 	SchemeBuilder.Register(&%s{}, &%sList{})
 }
-`, year, params.Version, gvk, gvk, resourceSpec, resourceTitle,
+`, year, params.Version, extraImports, gvk, gvk, resourceSpec, resourceTitle,
 		params.ProtoPackage, params.ProtoMessage, resourceSpec,
-		params.ProtoPackage, params.ProtoMessage, resourceTitle,
+		specFields, resourceTitle, specExtra,
 		resourceStatus, resourceTitle, resourceStatus, resourceTitle,
 		resourceObservedState, resourceObservedState, resourceTitle,
 		params.ProtoPackage, params.ProtoMessage, resourceObservedState,
+		observedFields, nestedTypes,
 		gvk, params.Service, resourceTitle, gvk, resourceSpec, resourceStatus,
 		gvk, gvk, gvk, gvk, gvk, gvk)
 }
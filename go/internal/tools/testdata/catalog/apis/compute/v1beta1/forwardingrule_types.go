@@ -0,0 +1,65 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"github.com/GoogleCloudPlatform/k8s-config-connector/apis/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var ComputeForwardingRuleGVK = GroupVersion.WithKind("ComputeForwardingRule")
+
+// ComputeForwardingRuleSpec defines the desired state of ComputeForwardingRule
+// +kcc:proto=google.cloud.compute.v1.ForwardingRule
+type ComputeForwardingRuleSpec struct {
+	// The ComputeForwardingRule name. If not given, the metadata.name will be used.
+	ResourceID *string `json:"resourceID,omitempty"`
+}
+
+// ComputeForwardingRuleStatus defines the config connector machine state of ComputeForwardingRule
+type ComputeForwardingRuleStatus struct {
+	/* Conditions represent the latest available observations of the
+	   object's current state. */
+	Conditions []v1alpha1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the resource that was most recently observed by the Config Connector controller. If this is equal to metadata.generation, then that means that the current reported status reflects the most recent desired state of the resource.
+	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+
+// ComputeForwardingRule is the Schema for the ComputeForwardingRule API
+// +k8s:openapi-gen=true
+type ComputeForwardingRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComputeForwardingRuleSpec   `json:"spec,omitempty"`
+	Status ComputeForwardingRuleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// ComputeForwardingRuleList contains a list of ComputeForwardingRule
+type ComputeForwardingRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComputeForwardingRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComputeForwardingRule{}, &ComputeForwardingRuleList{})
+}
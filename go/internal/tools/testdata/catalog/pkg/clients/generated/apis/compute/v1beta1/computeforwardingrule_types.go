@@ -0,0 +1,76 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ----------------------------------------------------------------------------
+//
+//     ***     AUTO GENERATED CODE    ***    Type: MMv1     ***
+//
+// ----------------------------------------------------------------------------
+//
+//     This file is automatically generated by Config Connector and manual
+//     changes will be clobbered when the file is regenerated.
+//
+// ----------------------------------------------------------------------------
+
+// *** DISCLAIMER ***
+// Config Connector's go-client for CRDs is currently in ALPHA, which means
+// that future versions of the go-client may include breaking changes.
+// Please try it out and give us feedback!
+
+package v1beta1
+
+import (
+	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/clients/generated/apis/k8s/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ComputeForwardingRuleSpec struct {
+	/* An optional description of this resource. */
+	// +optional
+	Description *string `json:"description,omitempty"`
+}
+
+type ComputeForwardingRuleStatus struct {
+	/* Conditions represent the latest available observations of the
+	   ComputeForwardingRule's current state. */
+	Conditions []v1alpha1.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=gcp,shortName=gcpcomputeforwardingrule;gcpcomputeforwardingrules
+// +kubebuilder:subresource:status
+
+// ComputeForwardingRule is the Schema for the compute API
+// +k8s:openapi-gen=true
+type ComputeForwardingRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComputeForwardingRuleSpec   `json:"spec,omitempty"`
+	Status ComputeForwardingRuleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ComputeForwardingRuleList contains a list of ComputeForwardingRule
+type ComputeForwardingRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComputeForwardingRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComputeForwardingRule{}, &ComputeForwardingRuleList{})
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourceconfig
+
+import (
+	"github.com/GoogleCloudPlatform/k8s-config-connector/pkg/k8s"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ControllerConfigStatic is the static configuration of the controllers
+// that reconcile each resource.
+var ControllerConfigStatic = map[schema.GroupKind]ControllerConfig{
+	{Group: "compute.cnrm.cloud.google.com", Kind: "ComputeForwardingRule"}: {
+		DefaultController:    k8s.ReconcilerTypeTerraform,
+		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeTerraform, k8s.ReconcilerTypeDirect},
+	},
+	{Group: "compute.cnrm.cloud.google.com", Kind: "ComputeURLMap"}: {
+		DefaultController:    k8s.ReconcilerTypeTerraform,
+		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeTerraform},
+	},
+	{Group: "redis.cnrm.cloud.google.com", Kind: "RedisCluster"}: {
+		DefaultController:    k8s.ReconcilerTypeDirect,
+		SupportedControllers: []k8s.ReconcilerType{k8s.ReconcilerTypeDirect},
+	},
+}
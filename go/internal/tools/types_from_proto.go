@@ -0,0 +1,416 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fkc1e100/kcc-mcp-server/go/internal/protoparser"
+)
+
+// Go import paths used by generated types
+const (
+	refsImport           = `refs "github.com/GoogleCloudPlatform/k8s-config-connector/apis/refs/v1beta1"`
+	apiextensionsImport  = `apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"`
+	outputOnlyBehavior   = "OUTPUT_ONLY"
+	outputOnlyCommentTag = "[Output Only]"
+)
+
+// goInitialisms are spelled in upper case in Go field names
+var goInitialisms = map[string]string{
+	"Id": "ID", "Ids": "IDs", "Url": "URL", "Urls": "URLs", "Uri": "URI", "Uris": "URIs",
+	"Ip": "IP", "Ipv4": "IPV4", "Ipv6": "IPV6", "Kms": "KMS", "Dns": "DNS", "Http": "HTTP", "Https": "HTTPS",
+	"Ssl": "SSL", "Tls": "TLS", "Api": "API", "Cpu": "CPU", "Gpu": "GPU",
+}
+
+// wellKnownGoTypes maps well-known proto types onto the Go types KCC uses
+var wellKnownGoTypes = map[string]string{
+	"google.protobuf.Timestamp":   "*string",
+	"google.protobuf.Duration":    "*string",
+	"google.protobuf.FieldMask":   "*string",
+	"google.protobuf.Struct":      "*apiextensionsv1.JSON",
+	"google.protobuf.Value":       "*apiextensionsv1.JSON",
+	"google.protobuf.ListValue":   "*apiextensionsv1.JSON",
+	"google.protobuf.Any":         "*apiextensionsv1.JSON",
+	"google.protobuf.StringValue": "*string",
+	"google.protobuf.BytesValue":  "[]byte",
+	"google.protobuf.BoolValue":   "*bool",
+	"google.protobuf.Int32Value":  "*int32",
+	"google.protobuf.Int64Value":  "*int64",
+	"google.protobuf.UInt32Value": "*uint32",
+	"google.protobuf.UInt64Value": "*uint64",
+	"google.protobuf.FloatValue":  "*float32",
+	"google.protobuf.DoubleValue": "*float64",
+}
+
+// knownRefTypes maps google.api.resource types whose KCC reference type does
+// not follow the {Service}{Kind}Ref convention
+var knownRefTypes = map[string]string{
+	"cloudresourcemanager.googleapis.com/Project":      "ProjectRef",
+	"cloudresourcemanager.googleapis.com/Folder":       "FolderRef",
+	"cloudresourcemanager.googleapis.com/Organization": "OrganizationRef",
+	"cloudkms.googleapis.com/CryptoKey":                "KMSCryptoKeyRef",
+	"cloudkms.googleapis.com/KeyRing":                  "KMSKeyRingRef",
+	"iam.googleapis.com/ServiceAccount":                "IAMServiceAccountRef",
+	"pubsub.googleapis.com/Topic":                      "PubSubTopicRef",
+	"pubsub.googleapis.com/Subscription":               "PubSubSubscriptionRef",
+	"secretmanager.googleapis.com/Secret":              "SecretManagerSecretRef",
+	"secretmanager.googleapis.com/SecretVersion":       "SecretManagerSecretVersionRef",
+	"bigquery.googleapis.com/Dataset":                  "BigQueryDatasetRef",
+	"bigquery.googleapis.com/Table":                    "BigQueryTableRef",
+}
+
+// typeGenerator renders Go structs for proto messages, following the
+// conventions of KCC's direct types: pointer scalars, +kcc:proto annotations,
+// nested messages as named structs and resource references as refs types
+type typeGenerator struct {
+	index   *protoparser.Index
+	imports map[string]bool
+
+	// structs are the nested struct declarations generated so far, in order
+	structs []string
+	// named maps message full names onto their Go struct names; observed
+	// state structs are keyed with an "ObservedState" suffix
+	named map[string]string
+	// skip holds message full names that must not get a nested struct, such
+	// as the resource message itself
+	skip map[string]bool
+}
+
+func newTypeGenerator(index *protoparser.Index) *typeGenerator {
+	return &typeGenerator{
+		index:   index,
+		imports: make(map[string]bool),
+		named:   make(map[string]string),
+		skip:    make(map[string]bool),
+	}
+}
+
+// isOutputOnly reports whether a field is output only, either by its
+// field_behavior or, for the Compute discovery-based protos, by the
+// "[Output Only]" comment convention
+func isOutputOnly(f *protoparser.Field) bool {
+	return f.HasBehavior(outputOnlyBehavior) || strings.HasPrefix(strings.TrimSpace(f.Comment), outputOnlyCommentTag)
+}
+
+// structBody renders the fields of msg as a struct body. When observed is
+// false OUTPUT_ONLY fields are left out.
+func (g *typeGenerator) structBody(msg *protoparser.Message, fields []*protoparser.Field, observed bool) string {
+	var blocks []string
+	for _, f := range fields {
+		if !observed && isOutputOnly(f) {
+			continue
+		}
+		blocks = append(blocks, g.fieldDeclaration(msg, f, observed))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// fieldDeclaration renders one field with its doc comment and annotation
+func (g *typeGenerator) fieldDeclaration(msg *protoparser.Message, f *protoparser.Field, observed bool) string {
+	name, goType, jsonName := g.goField(msg, f, observed)
+
+	comment, markers := f.Comment, []string(nil)
+	if !observed {
+		var prefix string
+		prefix, markers = behaviorAnnotations(f, jsonName)
+		comment = prefixComment(comment, prefix)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+		if line = strings.TrimRight(line, " "); line != "" {
+			lines = append(lines, "\t// "+line)
+		} else if len(lines) > 0 {
+			lines = append(lines, "\t//")
+		}
+	}
+	for _, marker := range markers {
+		lines = append(lines, "\t// "+marker)
+	}
+	if values := g.enumValues(msg, f); len(values) > 0 {
+		lines = append(lines, "\t// +kubebuilder:validation:Enum="+strings.Join(values, ";"))
+	}
+	lines = append(lines, fmt.Sprintf("\t// +kcc:proto=%s.%s", msg.FullName, f.Name))
+	lines = append(lines, fmt.Sprintf("\t%s %s `json:\"%s,omitempty\"`", name, goType, jsonName))
+	return strings.Join(lines, "\n")
+}
+
+// behaviorAnnotations returns the doc comment prefix and kubebuilder markers
+// KCC uses for a spec field's google.api.field_behavior: REQUIRED fields are
+// "Required." and +required, OPTIONAL fields +optional, and IMMUTABLE fields
+// "Immutable." with a CEL rule rejecting updates
+func behaviorAnnotations(f *protoparser.Field, jsonName string) (string, []string) {
+	var prefix []string
+	var markers []string
+	switch {
+	case f.HasBehavior("REQUIRED"):
+		prefix = append(prefix, "Required.")
+		markers = append(markers, "+required")
+	case f.HasBehavior("OPTIONAL"):
+		markers = append(markers, "+optional")
+	}
+	if f.HasBehavior("IMMUTABLE") {
+		prefix = append(prefix, "Immutable.")
+		markers = append(markers, fmt.Sprintf(`+kubebuilder:validation:XValidation:rule="self == oldSelf",message="%s field is immutable"`, jsonName))
+	}
+	return strings.Join(prefix, " "), markers
+}
+
+// prefixComment puts prefix ("Required. Immutable.") in front of a doc
+// comment, leaving out the words the comment already starts with
+func prefixComment(comment, prefix string) string {
+	comment = strings.TrimSpace(comment)
+	var words []string
+	for _, word := range strings.Fields(prefix) {
+		bare := strings.TrimSuffix(word, ".")
+		if !strings.HasPrefix(strings.ToLower(comment), strings.ToLower(bare)) &&
+			!strings.Contains(strings.ToLower(comment), strings.ToLower(bare)+". ") {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return comment
+	}
+	if comment == "" {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words, " ") + " " + comment
+}
+
+// enumValues returns the values accepted by a singular enum field, leaving
+// out the zero "unspecified" value
+func (g *typeGenerator) enumValues(msg *protoparser.Message, f *protoparser.Field) []string {
+	if f.IsMap() || f.IsRepeated() {
+		return nil
+	}
+	kind, fullName := g.index.FieldType(msg, f)
+	e := g.index.Enum(fullName)
+	if kind != protoparser.KindEnum || e == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range e.Values {
+		if v.Number == 0 && (strings.HasSuffix(v.Name, "_UNSPECIFIED") || strings.HasPrefix(v.Name, "UNDEFINED_")) {
+			continue
+		}
+		values = append(values, v.Name)
+	}
+	return values
+}
+
+// goField returns the Go field name, Go type and JSON name for a proto field
+func (g *typeGenerator) goField(msg *protoparser.Message, f *protoparser.Field, observed bool) (string, string, string) {
+	if refType := g.refType(f); refType != "" {
+		base := refBaseName(f.Name, f.IsRepeated())
+		if f.IsRepeated() {
+			return goFieldName(base) + "Refs", "[]" + refType, protoJSONName(base) + "Refs"
+		}
+		return goFieldName(base) + "Ref", "*" + refType, protoJSONName(base) + "Ref"
+	}
+	return goFieldName(f.Name), g.goType(msg, f, observed), protoJSONName(f.Name)
+}
+
+// refType returns the KCC reference type of a field annotated with
+// google.api.resource_reference, or ""
+func (g *typeGenerator) refType(f *protoparser.Field) string {
+	if f.ResourceReference == nil || f.IsMap() || f.Type != "string" {
+		return ""
+	}
+	resourceType := f.ResourceReference.Type
+	if resourceType == "" {
+		resourceType = f.ResourceReference.ChildType
+	}
+	name := refTypeName(resourceType)
+	if name == "" {
+		return ""
+	}
+	g.imports[refsImport] = true
+	return "refs." + name
+}
+
+// refTypeName derives the KCC reference type for a resource type such as
+// compute.googleapis.com/Network (ComputeNetworkRef)
+func refTypeName(resourceType string) string {
+	if name, ok := knownRefTypes[resourceType]; ok {
+		return name
+	}
+	host, kind, ok := strings.Cut(resourceType, "/")
+	if !ok || kind == "*" || kind == "" {
+		return ""
+	}
+	service := strings.TrimSuffix(host, ".googleapis.com")
+	return goFieldName(strings.ReplaceAll(service, ".", "_")) + kind + "Ref"
+}
+
+// refBaseName strips the suffixes KCC drops when a field becomes a
+// reference: kms_key_name -> kms_key, networks -> network
+func refBaseName(name string, repeated bool) string {
+	for _, suffix := range []string{"_name", "_id", "_uri", "_url", "_email"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name {
+			name = trimmed
+			break
+		}
+	}
+	if repeated {
+		name = strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// goType maps a proto field onto a Go type, generating nested structs for
+// message types
+func (g *typeGenerator) goType(msg *protoparser.Message, f *protoparser.Field, observed bool) string {
+	if f.IsMap() {
+		key := scalarGoType(f.MapKey)
+		kind, fullName := g.index.ResolveType(msg.FullName, f.MapValue)
+		value := strings.TrimPrefix(g.elementType(kind, fullName, observed), "*")
+		return fmt.Sprintf("map[%s]%s", strings.TrimPrefix(key, "*"), value)
+	}
+
+	kind, fullName := g.index.FieldType(msg, f)
+	elem := g.elementType(kind, fullName, observed)
+	if f.IsRepeated() {
+		return "[]" + strings.TrimPrefix(elem, "*")
+	}
+	return elem
+}
+
+// elementType maps a single (non-repeated) proto type onto a Go type
+func (g *typeGenerator) elementType(kind protoparser.TypeKind, fullName string, observed bool) string {
+	switch kind {
+	case protoparser.KindScalar:
+		return scalarGoType(fullName)
+	case protoparser.KindEnum:
+		return "*string"
+	}
+
+	if goType, ok := wellKnownGoTypes[fullName]; ok {
+		if strings.Contains(goType, "apiextensionsv1.") {
+			g.imports[apiextensionsImport] = true
+		}
+		return goType
+	}
+
+	nested := g.index.Message(fullName)
+	if nested == nil || g.skip[nested.FullName] {
+		// Not vendored: keep the value opaque rather than guessing its shape
+		g.imports[apiextensionsImport] = true
+		return "*apiextensionsv1.JSON"
+	}
+	return "*" + g.nestedStruct(nested, observed)
+}
+
+// nestedStruct returns the Go name of the struct for msg, generating it on
+// first use. Nested message names are joined with underscores, following
+// KCC's generated types (Network_RoutingConfig).
+func (g *typeGenerator) nestedStruct(msg *protoparser.Message, observed bool) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(msg.FullName, msg.File.Package+"."), ".", "_")
+	if observed {
+		name += "ObservedState"
+	}
+	return g.declareStruct(msg, name, observed)
+}
+
+// declareStruct generates the struct for msg under the given name, unless
+// the message already has a struct, whose name is returned instead
+func (g *typeGenerator) declareStruct(msg *protoparser.Message, name string, observed bool) string {
+	key := msg.FullName
+	if observed {
+		key += "ObservedState"
+	}
+	if existing, ok := g.named[key]; ok {
+		return existing
+	}
+	g.named[key] = name
+
+	var b strings.Builder
+	if comment := strings.TrimSpace(msg.Comment); comment != "" {
+		for _, line := range strings.Split(comment, "\n") {
+			fmt.Fprintf(&b, "// %s\n", strings.TrimRight(line, " "))
+		}
+	}
+	fmt.Fprintf(&b, "// +kcc:proto=%s\n", msg.FullName)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	// Reserve the slot so that structs appear in the order they are first used
+	slot := len(g.structs)
+	g.structs = append(g.structs, "")
+	if body := g.structBody(msg, msg.Fields, observed); body != "" {
+		b.WriteString(body + "\n")
+	}
+	b.WriteString("}")
+	g.structs[slot] = b.String()
+	return name
+}
+
+// importLines returns the extra imports needed by the generated code
+func (g *typeGenerator) importLines() []string {
+	lines := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		lines = append(lines, imp)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// scalarGoType maps a proto scalar type onto a pointer Go type
+func scalarGoType(typ string) string {
+	switch typ {
+	case "string":
+		return "*string"
+	case "bytes":
+		return "[]byte"
+	case "bool":
+		return "*bool"
+	case "int32", "sint32", "sfixed32":
+		return "*int32"
+	case "uint32", "fixed32":
+		return "*uint32"
+	case "int64", "sint64", "sfixed64":
+		return "*int64"
+	case "uint64", "fixed64":
+		return "*uint64"
+	case "float":
+		return "*float32"
+	case "double":
+		return "*float64"
+	}
+	return "*string"
+}
+
+// goFieldName converts a proto field name to an exported Go name,
+// upper-casing common initialisms (kms_key_name -> KMSKeyName)
+func goFieldName(protoName string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(protoName, "_") {
+		if part == "" {
+			continue
+		}
+		part = strings.ToUpper(part[:1]) + part[1:]
+		if initialism, ok := goInitialisms[part]; ok {
+			part = initialism
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// protoJSONName converts a proto field name to its JSON name
+// (self_link -> selfLink), as protojson does
+func protoJSONName(protoName string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range protoName {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}